@@ -1,5 +1,7 @@
 package client
 
+import "encoding/json"
+
 // Get sends a get command to the API.
 func (c *Client) Get(key string, handleFunc func(*Message)) *Operation {
 	op := c.NewOperation(handleFunc)
@@ -14,6 +16,13 @@ func (c *Client) Query(query string, handleFunc func(*Message)) *Operation {
 	return op
 }
 
+// QueryWithStats sends a querystats command to the API. The done message carries the query execution statistics as JSON in RawValue.
+func (c *Client) QueryWithStats(query string, handleFunc func(*Message)) *Operation {
+	op := c.NewOperation(handleFunc)
+	op.Send(msgRequestQueryStats, query, nil)
+	return op
+}
+
 // Sub sends a sub command to the API.
 func (c *Client) Sub(query string, handleFunc func(*Message)) *Operation {
 	op := c.NewOperation(handleFunc)
@@ -28,6 +37,35 @@ func (c *Client) Qsub(query string, handleFunc func(*Message)) *Operation {
 	return op
 }
 
+// QueryWithParams sends a query command with parameters bound to the placeholders ($1, $2, ...) of the query to the API.
+func (c *Client) QueryWithParams(query string, params []interface{}, handleFunc func(*Message)) (*Operation, error) {
+	return c.sendWithParams(msgRequestQuery, query, params, handleFunc)
+}
+
+// SubWithParams sends a sub command with parameters bound to the placeholders ($1, $2, ...) of the query to the API.
+func (c *Client) SubWithParams(query string, params []interface{}, handleFunc func(*Message)) (*Operation, error) {
+	return c.sendWithParams(msgRequestSub, query, params, handleFunc)
+}
+
+// QsubWithParams sends a qsub command with parameters bound to the placeholders ($1, $2, ...) of the query to the API.
+func (c *Client) QsubWithParams(query string, params []interface{}, handleFunc func(*Message)) (*Operation, error) {
+	return c.sendWithParams(msgRequestQsub, query, params, handleFunc)
+}
+
+func (c *Client) sendWithParams(command, query string, params []interface{}, handleFunc func(*Message)) (*Operation, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	op := c.NewOperation(handleFunc)
+	op.Send(command, string(encodedParams)+apiSeperator+query, nil)
+	return op, nil
+}
+
 // Create sends a create command to the API.
 func (c *Client) Create(key string, value interface{}, handleFunc func(*Message)) *Operation {
 	op := c.NewOperation(handleFunc)
@@ -49,6 +87,27 @@ func (c *Client) Insert(key string, value interface{}, handleFunc func(*Message)
 	return op
 }
 
+// Patch sends a patch command to the API. The patch may be a JSON Patch (RFC 6902) or a JSON Merge Patch (RFC 7396), either as raw JSON in a []byte or string, or as a value that is encoded to JSON.
+func (c *Client) Patch(key string, patch interface{}, handleFunc func(*Message)) (*Operation, error) {
+	var raw []byte
+	switch v := patch.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		var err error
+		raw, err = json.Marshal(patch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	op := c.NewOperation(handleFunc)
+	op.sendRaw(msgRequestPatch, key, raw)
+	return op, nil
+}
+
 // Delete sends a delete command to the API.
 func (c *Client) Delete(key string, handleFunc func(*Message)) *Operation {
 	op := c.NewOperation(handleFunc)
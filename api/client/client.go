@@ -217,6 +217,19 @@ func (op *Operation) Send(command, text string, data interface{}) {
 	op.client.send <- op.request
 }
 
+// sendRaw sends a command with data that is sent as is.
+func (op *Operation) sendRaw(command, text string, data []byte) {
+	op.request = &Message{
+		OpID:     op.ID,
+		Type:     command,
+		Key:      text,
+		RawValue: data,
+		sent:     abool.NewBool(false),
+	}
+	log.Tracef("client: [%s] sending %s msg: %s", op.request.OpID, op.request.Type, op.request.Key)
+	op.client.send <- op.request
+}
+
 // EnableResuscitation will resend the request after reconnecting to the API.
 func (op *Operation) EnableResuscitation() {
 	op.resuscitationEnabled.Set()
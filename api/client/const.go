@@ -2,14 +2,16 @@
 
 // message types
 const (
-	msgRequestGet    = "get"
-	msgRequestQuery  = "query"
-	msgRequestSub    = "sub"
-	msgRequestQsub   = "qsub"
-	msgRequestCreate = "create"
-	msgRequestUpdate = "update"
-	msgRequestInsert = "insert"
-	msgRequestDelete = "delete"
+	msgRequestGet        = "get"
+	msgRequestQuery      = "query"
+	msgRequestQueryStats = "querystats"
+	msgRequestSub        = "sub"
+	msgRequestQsub       = "qsub"
+	msgRequestCreate     = "create"
+	msgRequestUpdate     = "update"
+	msgRequestInsert     = "insert"
+	msgRequestDelete     = "delete"
+	msgRequestPatch      = "patch"
 
 	MsgOk      = "ok"
 	MsgError   = "error"
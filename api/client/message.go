@@ -63,9 +63,13 @@ func ParseMessage(data []byte) (*Message, error) {
 		}
 		m.Key = string(parts[2])
 	case MsgDone, MsgSuccess:
-		// nothing more to do
+		// nothing more to do, except for query stats
 		//    127|success
 		//    127|done
+		//    127|done|<stats>
+		if m.Type == MsgDone && len(parts) == 3 {
+			m.RawValue = parts[2]
+		}
 	}
 
 	return m, nil
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/safing/portbase/database"
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/log"
+)
+
+const (
+	maxRecordSize = 10485760 // 10MB
+)
+
+func init() {
+	// GET    /api/database/v1/record/<key> -> <data>
+	// PUT    /api/database/v1/record/<key> <- <data> // update
+	// POST   /api/database/v1/record/<key> <- <data> // create
+	// DELETE /api/database/v1/record/<key>
+	RegisterHandleFunc("/api/database/v1/record/{key:.+}", handleRecordRequest).Methods("GET", "PUT", "POST", "DELETE")
+
+	// GET /api/database/v1/query?q=<query>[&params=<params>]
+	//    {"key":"<key>","data":<data>}\n
+	//    {"error":"<message>"}\n
+	RegisterHandleFunc("/api/database/v1/query", handleQueryRequest).Methods("GET")
+
+	// GET /api/database/v1/subscribe?q=<query>[&params=<params>]
+	//    event: new|upd
+	//    data: {"key":"<key>","data":<data>}
+	//
+	//    event: del
+	//    data: {"key":"<key>"}
+	RegisterHandleFunc("/api/database/v1/subscribe", handleSubscribeRequest).Methods("GET")
+}
+
+// streamedRecord is a single record in a streamed response.
+type streamedRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+func handleRecordRequest(w http.ResponseWriter, r *http.Request) {
+	key := GetMuxVars(r)["key"]
+	if key == "" {
+		http.Error(w, "missing record key", http.StatusBadRequest)
+		return
+	}
+
+	// same permissions as the websocket database API
+	db := database.NewInterface(nil)
+
+	switch r.Method {
+	case http.MethodGet:
+		rec, err := db.Get(key)
+		if err != nil {
+			httpDatabaseError(w, err)
+			return
+		}
+		data, err := marshalJSONRecord(rec)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+
+	case http.MethodPut, http.MethodPost:
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRecordSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read body: %s", err), http.StatusBadRequest)
+			return
+		}
+		if !json.Valid(data) {
+			http.Error(w, "body must be valid JSON", http.StatusBadRequest)
+			return
+		}
+
+		rec, err := record.NewWrapper(key, nil, record.JSON, data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPost {
+			err = db.PutNew(rec)
+		} else {
+			err = db.Put(rec)
+		}
+		if err != nil {
+			httpDatabaseError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		err := db.Delete(key)
+		if err != nil {
+			httpDatabaseError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleQueryRequest(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQueryRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	it, err := database.NewInterface(nil).Query(q)
+	if err != nil {
+		httpDatabaseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// client went away
+			it.Cancel()
+			return
+		case rec := <-it.Next:
+			if rec == nil {
+				// query ended
+				if it.Err() != nil {
+					_ = encoder.Encode(&streamedRecord{Error: it.Err().Error()})
+				}
+				return
+			}
+
+			line := &streamedRecord{Key: rec.Key()}
+			line.Data, err = marshalJSONRecord(rec)
+			if err != nil {
+				line.Error = err.Error()
+			}
+			err = encoder.Encode(line)
+			if err != nil {
+				it.Cancel()
+				return
+			}
+			if flusher != nil && len(it.Next) == 0 {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func handleSubscribeRequest(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQueryRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, err := database.NewInterface(nil).Subscribe(q)
+	if err != nil {
+		httpDatabaseError(w, err)
+		return
+	}
+	defer sub.Cancel() //nolint:errcheck
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// client went away
+			return
+		case rec := <-sub.Feed:
+			if rec == nil {
+				// sub feed ended
+				return
+			}
+
+			rec.Lock()
+			isDeleted := rec.Meta().IsDeleted()
+			isNew := rec.Meta().Created == rec.Meta().Modified
+			rec.Unlock()
+
+			event := &streamedRecord{Key: rec.Key()}
+			var eventType string
+			switch {
+			case isDeleted:
+				eventType = dbMsgTypeDel
+			case isNew:
+				eventType = dbMsgTypeNew
+			default:
+				eventType = dbMsgTypeUpd
+			}
+			if !isDeleted {
+				event.Data, err = marshalJSONRecord(rec)
+				if err != nil {
+					log.Warningf("api: failed to marshal record %s for subscription: %s", rec.Key(), err)
+					continue
+				}
+			}
+
+			eventData, err := json.Marshal(event)
+			if err != nil {
+				log.Warningf("api: failed to marshal subscription event: %s", err)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, eventData)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// parseQueryRequest parses the query and its optional parameters from the request URL.
+func parseQueryRequest(r *http.Request) (*query.Query, error) {
+	queryText := r.URL.Query().Get("q")
+	if queryText == "" {
+		return nil, errors.New("missing query parameter \"q\"")
+	}
+
+	paramsText := r.URL.Query().Get("params")
+	if paramsText == "" {
+		return query.ParseQuery(queryText)
+	}
+
+	var params []interface{}
+	decoder := json.NewDecoder(strings.NewReader(paramsText))
+	decoder.UseNumber()
+	err := decoder.Decode(&params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query parameters: %s", err)
+	}
+	return query.ParseQuery(queryText, params...)
+}
+
+// marshalJSONRecord returns the plain JSON representation of a record.
+func marshalJSONRecord(r record.Record) ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	data, err := r.Marshal(r, record.JSON)
+	if err != nil {
+		return nil, err
+	}
+	// strip format identifier
+	if len(data) < 1 || data[0] != record.JSON {
+		return nil, fmt.Errorf("unexpected format of record %s", r.Key())
+	}
+	return data[1:], nil
+}
+
+func httpDatabaseError(w http.ResponseWriter, err error) {
+	switch err {
+	case database.ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case database.ErrPermissionDenied, database.ErrReadOnly:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case database.ErrShuttingDown, database.ErrDatabaseUnloaded:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/safing/portbase/database"
+	"github.com/safing/portbase/database/record"
+	_ "github.com/safing/portbase/database/storage/hashmap"
+)
+
+const restTestDB = "rest-test"
+
+func TestMain(m *testing.M) {
+	testDir, err := ioutil.TempDir("", "testing-")
+	if err != nil {
+		panic(err)
+	}
+	err = database.Initialize(testDir, nil)
+	if err != nil {
+		panic(err)
+	}
+	_, err = database.Register(&database.Database{
+		Name:        restTestDB,
+		Description: "REST API Test Database",
+		StorageType: "hashmap",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(testDir)
+	os.Exit(code)
+}
+
+func putTestRecord(t *testing.T, key, data string, secret bool) {
+	r, err := record.NewWrapper(restTestDB+":"+key, nil, record.JSON, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.UpdateMeta()
+	if secret {
+		r.Meta().MakeSecret()
+	}
+	err = database.NewInterface(&database.Options{Internal: true, Local: true}).Put(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func doRequest(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	mainMux.ServeHTTP(w, req)
+	return w
+}
+
+func TestRecordRequest(t *testing.T) {
+	recordPath := "/api/database/v1/record/" + restTestDB + ":record"
+
+	w := doRequest(http.MethodGet, recordPath, "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected %d for missing record, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = doRequest(http.MethodPost, recordPath, `{"Name":"Herbert"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d for create, got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+
+	w = doRequest(http.MethodPut, recordPath, `{"Name":"Fritz"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d for update, got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	w = doRequest(http.MethodPut, recordPath, `{"Name":`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid JSON, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = doRequest(http.MethodGet, recordPath, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	var data struct{ Name string }
+	err := json.Unmarshal(w.Body.Bytes(), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Name != "Fritz" {
+		t.Errorf("unexpected record data: %s", w.Body)
+	}
+
+	w = doRequest(http.MethodDelete, recordPath, "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d for delete, got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	w = doRequest(http.MethodGet, recordPath, "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected %d for deleted record, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRecordRequestPermissionDenied(t *testing.T) {
+	putTestRecord(t, "secret", `{"Name":"Secret"}`, true)
+	recordPath := "/api/database/v1/record/" + restTestDB + ":secret"
+
+	w := doRequest(http.MethodGet, recordPath, "")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected %d for secret record, got %d", http.StatusForbidden, w.Code)
+	}
+	w = doRequest(http.MethodPut, recordPath, `{"Name":"Public"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected %d for overwriting secret record, got %d", http.StatusForbidden, w.Code)
+	}
+	w = doRequest(http.MethodDelete, recordPath, "")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected %d for deleting secret record, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestQueryRequest(t *testing.T) {
+	putTestRecord(t, "query/a", `{"Name":"A"}`, false)
+	putTestRecord(t, "query/b", `{"Name":"B"}`, false)
+	putTestRecord(t, "query/secret", `{"Name":"Secret"}`, true)
+
+	w := doRequest(http.MethodGet, "/api/database/v1/query", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d for missing query, got %d", http.StatusBadRequest, w.Code)
+	}
+	w = doRequest(http.MethodGet, "/api/database/v1/query?q="+url.QueryEscape("query "+restTestDB+":query/ where Name =="), "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid query, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = doRequest(http.MethodGet, "/api/database/v1/query?q="+url.QueryEscape("query "+restTestDB+":query/"), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	// one JSON object per line
+	found := make(map[string]string)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line struct {
+			Key   string
+			Data  struct{ Name string }
+			Error string
+		}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			t.Fatalf("invalid line %q: %s", scanner.Text(), err)
+		}
+		if line.Error != "" {
+			t.Fatalf("query failed: %s", line.Error)
+		}
+		found[line.Key] = line.Data.Name
+	}
+	if len(found) != 2 || found[restTestDB+":query/a"] != "A" || found[restTestDB+":query/b"] != "B" {
+		t.Errorf("unexpected query result: %v", found)
+	}
+}
+
+func TestSubscribeRequest(t *testing.T) {
+	handlerDone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handlerDone)
+		mainMux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/database/v1/subscribe?q="+url.QueryEscape("query "+restTestDB+":sub/"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	events := make(chan [2]string, 10)
+	go func() {
+		var eventType string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				eventType = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				events <- [2]string{eventType, strings.TrimPrefix(line, "data: ")}
+			}
+		}
+		close(events)
+	}()
+	nextEvent := func() (eventType string, event streamedRecord) {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("event stream ended")
+			}
+			err := json.Unmarshal([]byte(e[1]), &event)
+			if err != nil {
+				t.Fatalf("invalid event data %q: %s", e[1], err)
+			}
+			return e[0], event
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return "", event
+	}
+
+	// records written by the API are delivered as events
+	w := doRequest(http.MethodPost, "/api/database/v1/record/"+restTestDB+":sub/a", `{"Name":"A"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d for create, got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	eventType, event := nextEvent()
+	if eventType != dbMsgTypeNew || event.Key != restTestDB+":sub/a" || !strings.Contains(string(event.Data), `"A"`) {
+		t.Errorf("unexpected event %s: %+v", eventType, event)
+	}
+
+	time.Sleep(time.Second) // ensure a different modification time
+	err = database.NewInterface(nil).InsertValue(restTestDB+":sub/a", "Name", "B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventType, event = nextEvent()
+	if eventType != dbMsgTypeUpd || !strings.Contains(string(event.Data), `"B"`) {
+		t.Errorf("unexpected event %s: %+v", eventType, event)
+	}
+
+	w = doRequest(http.MethodDelete, "/api/database/v1/record/"+restTestDB+":sub/a", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d for delete, got %d: %s", http.StatusNoContent, w.Code, w.Body)
+	}
+	eventType, event = nextEvent()
+	if eventType != dbMsgTypeDel || event.Key != restTestDB+":sub/a" || event.Data != nil {
+		t.Errorf("unexpected event %s: %+v", eventType, event)
+	}
+
+	// the handler returns when the client goes away
+	cancel()
+	select {
+	case <-handlerDone:
+	case <-time.After(time.Second):
+		t.Fatal("subscription handler did not return after the client went away")
+	}
+}
@@ -2,9 +2,11 @@
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/websocket"
 	"github.com/tevino/abool"
@@ -14,6 +16,7 @@
 	"github.com/safing/portbase/database"
 	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/formats/dsd"
 	"github.com/safing/portbase/log"
 )
 
@@ -90,16 +93,24 @@ func (api *DatabaseAPI) handler() {
 	//    123|ok|<key>|<data>
 	//    123|error|<message>
 	// 124|query|<query>
+	// 124|query|<params>|<query> // params: JSON array bound to the placeholders $1, $2, ... of the query
 	//    124|ok|<key>|<data>
 	//    124|done
 	//    124|error|<message>
 	//    124|warning|<message> // error with single record, operation continues
+	// 124|querystats|<query>
+	//    124|ok|<key>|<data>
+	//    124|done|<stats>
+	//    124|error|<message>
+	//    124|warning|<message> // error with single record, operation continues
 	// 125|sub|<query>
+	// 125|sub|<params>|<query>
 	//    125|upd|<key>|<data>
 	//    125|new|<key>|<data>
 	//    127|del|<key>
 	//    125|warning|<message> // error with single record, operation continues
 	// 127|qsub|<query>
+	// 127|qsub|<params>|<query>
 	//    127|ok|<key>|<data>
 	//    127|done
 	//    127|error|<message>
@@ -120,6 +131,9 @@ func (api *DatabaseAPI) handler() {
 	// 131|delete|<key>
 	//    131|success
 	//    131|error|<message>
+	// 132|patch|<key>|<patch>
+	//    132|success
+	//    132|error|<message>
 
 	for {
 
@@ -146,14 +160,17 @@ func (api *DatabaseAPI) handler() {
 			go api.handleGet(parts[0], string(parts[2]))
 		case "query":
 			// 124|query|<query>
-			go api.handleQuery(parts[0], string(parts[2]))
+			go api.handleQuery(parts[0], string(parts[2]), false)
+		case "querystats":
+			// 124|querystats|<query>
+			go api.handleQuery(parts[0], string(parts[2]), true)
 		case "sub":
 			// 125|sub|<query>
 			go api.handleSub(parts[0], string(parts[2]))
 		case "qsub":
 			// 127|qsub|<query>
 			go api.handleQsub(parts[0], string(parts[2]))
-		case "create", "update", "insert":
+		case "create", "update", "insert", "patch":
 			// split key and payload
 			dataParts := bytes.SplitN(parts[2], []byte("|"), 2)
 			if len(dataParts) != 2 {
@@ -171,6 +188,9 @@ func (api *DatabaseAPI) handler() {
 			case "insert":
 				// 130|insert|<key>|<data>
 				go api.handleInsert(parts[0], string(dataParts[0]), dataParts[1])
+			case "patch":
+				// 132|patch|<key>|<patch>
+				go api.handlePatch(parts[0], string(dataParts[0]), dataParts[1])
 			}
 		case "delete":
 			// 131|delete|<key>
@@ -250,7 +270,7 @@ func (api *DatabaseAPI) handleGet(opID []byte, key string) {
 	api.send(opID, dbMsgTypeOk, r.Key(), data)
 }
 
-func (api *DatabaseAPI) handleQuery(opID []byte, queryText string) {
+func (api *DatabaseAPI) handleQuery(opID []byte, queryText string, withStats bool) {
 	// 124|query|<query>
 	//    124|ok|<key>|<data>
 	//    124|done
@@ -258,18 +278,24 @@ func (api *DatabaseAPI) handleQuery(opID []byte, queryText string) {
 	//    124|error|<message>
 	//    124|warning|<message> // error with single record, operation continues
 
+	// 124|querystats|<query>
+	//    124|ok|<key>|<data>
+	//    124|done|<stats>
+	//    124|error|<message>
+	//    124|warning|<message> // error with single record, operation continues
+
 	var err error
 
-	q, err := query.ParseQuery(queryText)
+	q, err := parseQueryMessage(queryText)
 	if err != nil {
 		api.send(opID, dbMsgTypeError, err.Error(), nil)
 		return
 	}
 
-	api.processQuery(opID, q)
+	api.processQuery(opID, q, withStats)
 }
 
-func (api *DatabaseAPI) processQuery(opID []byte, q *query.Query) (ok bool) {
+func (api *DatabaseAPI) processQuery(opID []byte, q *query.Query, withStats bool) (ok bool) {
 	it, err := api.db.Query(q)
 	if err != nil {
 		api.send(opID, dbMsgTypeError, err.Error(), nil)
@@ -313,6 +339,15 @@ func (api *DatabaseAPI) processQuery(opID []byte, q *query.Query) (ok bool) {
 					api.send(opID, dbMsgTypeError, it.Err().Error(), nil)
 					return false
 				}
+				if withStats {
+					stats, err := dsd.Dump(it.Stats(), dsd.JSON)
+					if err != nil {
+						api.send(opID, dbMsgTypeError, err.Error(), nil)
+						return false
+					}
+					api.send(opID, dbMsgTypeDone, emptyString, stats)
+					return true
+				}
 				api.send(opID, dbMsgTypeDone, emptyString, nil)
 				return true
 			}
@@ -322,6 +357,27 @@ func (api *DatabaseAPI) processQuery(opID []byte, q *query.Query) (ok bool) {
 
 // func (api *DatabaseAPI) runQuery()
 
+// parseQueryMessage parses a query that is optionally prefixed by a JSON array of parameters and a separator: [<params>|]<query>
+func parseQueryMessage(payload string) (*query.Query, error) {
+	if !strings.HasPrefix(payload, "[") {
+		return query.ParseQuery(payload)
+	}
+
+	var params []interface{}
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	err := decoder.Decode(&params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query parameters: %s", err)
+	}
+	rest := payload[decoder.InputOffset():]
+	if !strings.HasPrefix(rest, dbAPISeperator) {
+		return nil, errors.New("bad request: malformed message")
+	}
+
+	return query.ParseQuery(rest[len(dbAPISeperator):], params...)
+}
+
 func (api *DatabaseAPI) handleSub(opID []byte, queryText string) {
 	// 125|sub|<query>
 	//    125|upd|<key>|<data>
@@ -330,7 +386,7 @@ func (api *DatabaseAPI) handleSub(opID []byte, queryText string) {
 	//    125|warning|<message> // error with single record, operation continues
 	var err error
 
-	q, err := query.ParseQuery(queryText)
+	q, err := parseQueryMessage(queryText)
 	if err != nil {
 		api.send(opID, dbMsgTypeError, err.Error(), nil)
 		return
@@ -404,7 +460,7 @@ func (api *DatabaseAPI) handleQsub(opID []byte, queryText string) {
 
 	var err error
 
-	q, err := query.ParseQuery(queryText)
+	q, err := parseQueryMessage(queryText)
 	if err != nil {
 		api.send(opID, dbMsgTypeError, err.Error(), nil)
 		return
@@ -414,7 +470,7 @@ func (api *DatabaseAPI) handleQsub(opID []byte, queryText string) {
 	if !ok {
 		return
 	}
-	ok = api.processQuery(opID, q)
+	ok = api.processQuery(opID, q, false)
 	if !ok {
 		return
 	}
@@ -513,6 +569,19 @@ func (api *DatabaseAPI) handleInsert(opID []byte, key string, data []byte) {
 	api.send(opID, dbMsgTypeSuccess, emptyString, nil)
 }
 
+func (api *DatabaseAPI) handlePatch(opID []byte, key string, patch []byte) {
+	// 132|patch|<key>|<patch>
+	//    132|success
+	//    132|error|<message>
+
+	err := api.db.Patch(key, patch)
+	if err != nil {
+		api.send(opID, dbMsgTypeError, err.Error(), nil)
+		return
+	}
+	api.send(opID, dbMsgTypeSuccess, emptyString, nil)
+}
+
 func (api *DatabaseAPI) handleDelete(opID []byte, key string) {
 	// 131|delete|<key>
 	//    131|success
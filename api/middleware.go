@@ -12,7 +12,6 @@ type mwHandler struct {
 
 func (mwh *mwHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	handlerLock.RLock()
-	defer handlerLock.RUnlock()
 
 	// final handler
 	handler := mwh.final
@@ -22,6 +21,9 @@ func (mwh *mwHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		handler = mw(handler)
 	}
 
+	// release lock before serving, as streaming handlers may run for a long time
+	handlerLock.RUnlock()
+
 	// start
 	handler.ServeHTTP(w, r)
 }
@@ -2,41 +2,55 @@
 
 import (
 	"errors"
-	"sort"
 	"strings"
 
 	"github.com/safing/portbase/database"
-	"github.com/safing/portbase/database/iterator"
-	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
-	"github.com/safing/portbase/database/storage"
 	"github.com/safing/portbase/log"
 )
 
 var (
-	dbController *database.Controller
+	configDB *database.InjectedDatabase
 )
 
-// StorageInterface provices a storage.Interface to the configuration manager.
-type StorageInterface struct {
-	storage.InjectBase
+func registerAsDatabase() (err error) {
+	configDB, err = database.NewInjectedDatabase("config", "Configuration Manager", getOptionRecord, setOptionRecord, listOptionRecords)
+	return err
 }
 
-// Get returns a database record.
-func (s *StorageInterface) Get(key string) (record.Record, error) {
+// getOptionRecord returns the config option with the given key as a record.
+func getOptionRecord(key string) (record.Record, error) {
 	optionsLock.Lock()
 	defer optionsLock.Unlock()
 
 	opt, ok := options[key]
 	if !ok {
-		return nil, storage.ErrNotFound
+		return nil, database.ErrNotFound
 	}
 
 	return opt.Export()
 }
 
-// Put stores a record in the database.
-func (s *StorageInterface) Put(r record.Record) error {
+// listOptionRecords returns all config options with the given key prefix as records.
+func listOptionRecords(prefix string) ([]record.Record, error) {
+	optionsLock.Lock()
+	defer optionsLock.Unlock()
+
+	var records []record.Record
+	for _, opt := range options {
+		if strings.HasPrefix(opt.Key, prefix) {
+			r, err := opt.Export()
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+// setOptionRecord sets the value of a config option from a record written to the database.
+func setOptionRecord(r record.Record) error {
 	if r.Meta().Deleted > 0 {
 		return setConfigOption(r.DatabaseKey(), nil, false)
 	}
@@ -80,71 +94,6 @@ func (s *StorageInterface) Put(r record.Record) error {
 	return nil
 }
 
-// Delete deletes a record from the database.
-func (s *StorageInterface) Delete(key string) error {
-	return setConfigOption(key, nil, false)
-}
-
-// Query returns a an iterator for the supplied query.
-func (s *StorageInterface) Query(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
-
-	optionsLock.Lock()
-	defer optionsLock.Unlock()
-
-	it := iterator.New()
-	var opts []*Option
-	for _, opt := range options {
-		if strings.HasPrefix(opt.Key, q.DatabaseKeyPrefix()) {
-			opts = append(opts, opt)
-		}
-	}
-
-	go s.processQuery(it, opts)
-
-	return it, nil
-}
-
-func (s *StorageInterface) processQuery(it *iterator.Iterator, opts []*Option) {
-
-	sort.Sort(sortableOptions(opts))
-
-	for _, opt := range opts {
-		r, err := opt.Export()
-		if err != nil {
-			it.Finish(err)
-			return
-		}
-		it.Next <- r
-	}
-
-	it.Finish(nil)
-}
-
-// ReadOnly returns whether the database is read only.
-func (s *StorageInterface) ReadOnly() bool {
-	return false
-}
-
-func registerAsDatabase() error {
-	_, err := database.Register(&database.Database{
-		Name:        "config",
-		Description: "Configuration Manager",
-		StorageType: "injected",
-		PrimaryAPI:  "",
-	})
-	if err != nil {
-		return err
-	}
-
-	controller, err := database.InjectDatabase("config", &StorageInterface{})
-	if err != nil {
-		return err
-	}
-
-	dbController = controller
-	return nil
-}
-
 func pushFullUpdate() {
 	optionsLock.RLock()
 	defer optionsLock.RUnlock()
@@ -159,6 +108,6 @@ func pushUpdate(option *Option) {
 	if err != nil {
 		log.Errorf("failed to export option to push update: %s", err)
 	} else {
-		dbController.PushUpdate(r)
+		configDB.PushUpdate(r)
 	}
 }
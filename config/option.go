@@ -89,21 +89,3 @@ func (option *Option) Export() (record.Record, error) {
 
 	return r, nil
 }
-
-type sortableOptions []*Option
-
-// Len is the number of elements in the collection.
-func (opts sortableOptions) Len() int {
-	return len(opts)
-}
-
-// Less reports whether the element with
-// index i should sort before the element with index j.
-func (opts sortableOptions) Less(i, j int) bool {
-	return opts[i].Key < opts[j].Key
-}
-
-// Swap swaps the elements with indexes i and j.
-func (opts sortableOptions) Swap(i, j int) {
-	opts[i], opts[j] = opts[j], opts[i]
-}
@@ -11,6 +11,7 @@ type Container struct {
 	compartments [][]byte
 	offset       int
 	err          error
+	pooled       []*[]byte // buffers taken from the pool
 }
 
 // Data Handling
@@ -48,6 +49,31 @@ func (c *Container) AppendNumber(n uint64) {
 	c.compartments = append(c.compartments, varint.Pack64(n))
 }
 
+// AppendInt appends a signed number (zig-zag varint encoded).
+func (c *Container) AppendInt(n int64) {
+	c.compartments = append(c.compartments, varint.PackInt64(n))
+}
+
+// AppendFixed32 appends a uint32 with a fixed width of 4 bytes.
+func (c *Container) AppendFixed32(n uint32) {
+	c.compartments = append(c.compartments, varint.PackFixed32(n))
+}
+
+// AppendFixed64 appends a uint64 with a fixed width of 8 bytes.
+func (c *Container) AppendFixed64(n uint64) {
+	c.compartments = append(c.compartments, varint.PackFixed64(n))
+}
+
+// AppendFloat appends a float64 with a fixed width of 8 bytes.
+func (c *Container) AppendFloat(f float64) {
+	c.compartments = append(c.compartments, varint.PackFloat64(f))
+}
+
+// AppendString appends the length of the string and the string itself.
+func (c *Container) AppendString(s string) {
+	c.AppendAsBlock([]byte(s))
+}
+
 // AppendAsBlock appends the length of the data and the data itself. Data will NOT be copied.
 func (c *Container) AppendAsBlock(data []byte) {
 	c.AppendNumber(uint64(len(data)))
@@ -194,6 +220,9 @@ func (c *Container) PrependLength() {
 }
 
 func (c *Container) gather(n int) []byte {
+	if c.offset >= len(c.compartments) {
+		return nil
+	}
 	// check if first slice holds enough data
 	if len(c.compartments[c.offset]) >= n {
 		return c.compartments[c.offset][:n]
@@ -239,6 +268,9 @@ func (c *Container) GetNextBlock() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if blockSize > uint64(c.Length()) {
+		return nil, errors.New("container: not enough data for given block length")
+	}
 	return c.Get(int(blockSize))
 }
 
@@ -285,3 +317,56 @@ func (c *Container) GetNextN64() (uint64, error) {
 	c.skip(n)
 	return num, nil
 }
+
+// GetNextInt64 parses and returns a zig-zag encoded varint of type int64.
+func (c *Container) GetNextInt64() (int64, error) {
+	buf := c.gather(10)
+	num, n, err := varint.UnpackInt64(buf)
+	if err != nil {
+		return 0, err
+	}
+	c.skip(n)
+	return num, nil
+}
+
+// GetNextFixed32 parses and returns a uint32 with a fixed width of 4 bytes.
+func (c *Container) GetNextFixed32() (uint32, error) {
+	buf := c.gather(4)
+	num, n, err := varint.UnpackFixed32(buf)
+	if err != nil {
+		return 0, err
+	}
+	c.skip(n)
+	return num, nil
+}
+
+// GetNextFixed64 parses and returns a uint64 with a fixed width of 8 bytes.
+func (c *Container) GetNextFixed64() (uint64, error) {
+	buf := c.gather(8)
+	num, n, err := varint.UnpackFixed64(buf)
+	if err != nil {
+		return 0, err
+	}
+	c.skip(n)
+	return num, nil
+}
+
+// GetNextFloat parses and returns a float64 with a fixed width of 8 bytes.
+func (c *Container) GetNextFloat() (float64, error) {
+	buf := c.gather(8)
+	num, n, err := varint.UnpackFloat64(buf)
+	if err != nil {
+		return 0, err
+	}
+	c.skip(n)
+	return num, nil
+}
+
+// GetNextString returns the next block of data as a string.
+func (c *Container) GetNextString() (string, error) {
+	block, err := c.GetNextBlock()
+	if err != nil {
+		return "", err
+	}
+	return string(block), nil
+}
@@ -3,6 +3,7 @@
 import (
 	"bytes"
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/safing/portbase/utils"
@@ -218,3 +219,233 @@ func TestContainerMisc(t *testing.T) {
 func TestDeprecated(t *testing.T) {
 	NewContainer(utils.DuplicateBytes(testData))
 }
+
+// copyOfTestDataSplitted returns a copy of testDataSplitted, as containers take ownership of the passed slice.
+func copyOfTestDataSplitted() [][]byte {
+	return append([][]byte{}, testDataSplitted...)
+}
+
+func TestPeekAndSlice(t *testing.T) {
+	c1 := New(copyOfTestDataSplitted()...)
+
+	// peek within first compartment is zero-copy
+	p1, err := c1.Peek(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &p1[0] != &testDataSplitted[0][0] {
+		t.Error("Peek should not copy data held by a single compartment")
+	}
+	// peek over compartments
+	p2, err := c1.Peek(len(testData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1.Length() != len(testData) {
+		t.Error("Peek should not consume data")
+	}
+	_, err = c1.Peek(len(testData) + 1)
+	if err == nil {
+		t.Error("Peek should fail")
+	}
+
+	// slice
+	c2, err := c1.Slice(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &c2.compartments[1][0] != &testDataSplitted[1][0] {
+		t.Error("Slice should not copy data")
+	}
+	c3, err := c1.Slice(c1.Length())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c1.Slice(1)
+	if err == nil {
+		t.Error("Slice should fail")
+	}
+
+	compareMany(t, testData, p2, append(c2.CompileData(), c3.CompileData()...))
+}
+
+func TestRelease(t *testing.T) {
+	c1 := New(copyOfTestDataSplitted()...)
+	for i := 0; i < 10; i++ {
+		c1.Append(testData)
+	}
+	c1.CompileData()
+	if len(c1.pooled) != 0 {
+		t.Fatalf("compiled data must not be pooled, got %d pooled buffers", len(c1.pooled))
+	}
+	_, err := c1.ReadFrom(bytes.NewReader(testData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c1.pooled) != 1 {
+		t.Fatalf("expected one pooled buffer, got %d", len(c1.pooled))
+	}
+	c1.Release()
+	if c1.Length() != 0 || len(c1.pooled) != 0 {
+		t.Error("released container should be empty")
+	}
+
+	// container is usable after release
+	c1.Append(testData)
+	compareMany(t, testData, c1.CompileData())
+
+	// pool classes
+	for _, size := range []int{1, 64, 65, 4096, 1 << 16} {
+		buf := getBuffer(size)
+		if len(*buf) < size {
+			t.Errorf("expected buffer of at least size %d, got %d", size, len(*buf))
+		}
+		putBuffer(buf)
+	}
+	if len(*getBuffer(1<<16 + 1)) != 1<<16+1 {
+		t.Error("expected unpooled buffer")
+	}
+}
+
+func benchmarkData() [][]byte {
+	data := make([][]byte, 0, 100)
+	for i := 0; i < 10; i++ {
+		data = append(data, testDataSplitted...)
+	}
+	return data
+}
+
+func BenchmarkCompileData(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := New(append(make([][]byte, 0, len(data)), data...)...)
+		c.CompileData()
+	}
+}
+
+func BenchmarkReadFromPooled(b *testing.B) {
+	data := New(benchmarkData()...).CompileData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := New()
+		_, _ = c.ReadFrom(bytes.NewReader(data))
+		c.Release()
+	}
+}
+
+func BenchmarkGetCopy(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := New(append(make([][]byte, 0, len(data)), data...)...)
+		for c.Length() >= 100 {
+			_, _ = c.Get(100)
+		}
+	}
+}
+
+func BenchmarkSlice(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := New(append(make([][]byte, 0, len(data)), data...)...)
+		for c.Length() >= 100 {
+			_, _ = c.Slice(100)
+		}
+	}
+}
+
+func TestWireTypes(t *testing.T) {
+	c := New()
+	c.AppendInt(-1000)
+	c.AppendFixed32(7)
+	c.AppendFixed64(8)
+	c.AppendFloat(0.5)
+	c.AppendString("fox")
+	// read from a container with one byte per compartment
+	data := c.CompileData()
+	split := make([][]byte, len(data))
+	for i := range data {
+		split[i] = data[i : i+1]
+	}
+	c = New(split...)
+
+	n, err := c.GetNextInt64()
+	if err != nil || n != -1000 {
+		t.Errorf("GetNextInt64 failed: %d, %s", n, err)
+	}
+	n32, err := c.GetNextFixed32()
+	if err != nil || n32 != 7 {
+		t.Errorf("GetNextFixed32 failed: %d, %s", n32, err)
+	}
+	n64, err := c.GetNextFixed64()
+	if err != nil || n64 != 8 {
+		t.Errorf("GetNextFixed64 failed: %d, %s", n64, err)
+	}
+	f, err := c.GetNextFloat()
+	if err != nil || f != 0.5 {
+		t.Errorf("GetNextFloat failed: %f, %s", f, err)
+	}
+	s, err := c.GetNextString()
+	if err != nil || s != "fox" {
+		t.Errorf("GetNextString failed: %s, %s", s, err)
+	}
+	_, err = c.GetNextInt64()
+	if err == nil {
+		t.Error("GetNextInt64 should fail on empty container")
+	}
+}
+
+func FuzzContainerWireTypes(f *testing.F) {
+	f.Add(int64(-1), uint64(1), 1.5, "test")
+	f.Fuzz(func(t *testing.T, i int64, u uint64, fl float64, s string) {
+		c := New()
+		c.AppendInt(i)
+		c.AppendNumber(u)
+		c.AppendFixed64(u)
+		c.AppendFloat(fl)
+		c.AppendString(s)
+
+		i2, err := c.GetNextInt64()
+		if err != nil || i2 != i {
+			t.Fatalf("int mismatch: %d, %s", i2, err)
+		}
+		u2, err := c.GetNextN64()
+		if err != nil || u2 != u {
+			t.Fatalf("varint mismatch: %d, %s", u2, err)
+		}
+		u3, err := c.GetNextFixed64()
+		if err != nil || u3 != u {
+			t.Fatalf("fixed mismatch: %d, %s", u3, err)
+		}
+		fl2, err := c.GetNextFloat()
+		if err != nil || math.Float64bits(fl2) != math.Float64bits(fl) {
+			t.Fatalf("float mismatch: %f, %s", fl2, err)
+		}
+		s2, err := c.GetNextString()
+		if err != nil || s2 != s {
+			t.Fatalf("string mismatch: %q, %s", s2, err)
+		}
+	})
+}
+
+func FuzzContainerParsing(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})
+	f.Add([]byte{0x03, 'f', 'o', 'x'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// must not panic on arbitrary input
+		c := New(data)
+		_, _ = c.GetNextString()
+		c = New(data)
+		_, _ = c.GetNextInt64()
+		_, _ = c.GetNextFloat()
+		_, _ = c.GetNextFixed32()
+		_, _ = c.GetNextBlock()
+	})
+}
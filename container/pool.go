@@ -0,0 +1,128 @@
+package container
+
+import (
+	"errors"
+	"sync"
+)
+
+// Buffers are pooled in size classes of powers of two, from 64 bytes to 64KB. Larger buffers are allocated directly and are not pooled.
+// The pool is only used where the container chooses the buffer size, ie. ReadFrom and Peek, so that buffers are not over-allocated when they are never released. Data that is compiled or gathered for the caller is allocated with its exact size.
+const (
+	minPoolClass = 6  // 64B
+	maxPoolClass = 16 // 64KB
+)
+
+var (
+	bufferPools [maxPoolClass - minPoolClass + 1]sync.Pool
+)
+
+func init() {
+	for i := range bufferPools {
+		size := 1 << uint(i+minPoolClass)
+		bufferPools[i].New = func() interface{} {
+			buf := make([]byte, size)
+			return &buf
+		}
+	}
+}
+
+// poolClass returns the index of the smallest pool holding buffers of at least n bytes, or -1 if n is too big to be pooled.
+func poolClass(n int) int {
+	for class := minPoolClass; class <= maxPoolClass; class++ {
+		if n <= 1<<uint(class) {
+			return class - minPoolClass
+		}
+	}
+	return -1
+}
+
+// getBuffer returns a buffer of at least n bytes, taken from the pool if possible.
+func getBuffer(n int) *[]byte {
+	class := poolClass(n)
+	if class < 0 {
+		buf := make([]byte, n)
+		return &buf
+	}
+	return bufferPools[class].Get().(*[]byte)
+}
+
+// putBuffer returns a buffer obtained by getBuffer to the pool.
+func putBuffer(buf *[]byte) {
+	class := poolClass(cap(*buf))
+	if class < 0 || cap(*buf) != 1<<uint(class+minPoolClass) {
+		// not from the pool
+		return
+	}
+	*buf = (*buf)[:cap(*buf)]
+	bufferPools[class].Put(buf)
+}
+
+// newPooledBuffer returns a buffer of length n from the pool and tracks it for Release. Small buffers are allocated directly, so that many small reads do not pile up buffers.
+func (c *Container) newPooledBuffer(n int) []byte {
+	if n < 1<<minPoolClass || poolClass(n) < 0 {
+		return make([]byte, n)
+	}
+	buf := getBuffer(n)
+	c.pooled = append(c.pooled, buf)
+	return (*buf)[:n]
+}
+
+// Release returns all buffers the container took from the pool and empties the container. It should be called by the owner of a container that read data with ReadFrom or Peek, once neither the container nor any data returned by it (eg. by Get, Peek or Slice) is used anymore, as that memory may be reused. Containers that are not released are simply garbage collected.
+func (c *Container) Release() {
+	for _, buf := range c.pooled {
+		putBuffer(buf)
+	}
+	c.pooled = nil
+	c.compartments = nil
+	c.offset = 0
+}
+
+// Peek returns the next n bytes without consuming them. If the data is held by a single compartment, it is NOT copied, else it is gathered into a pooled buffer.
+func (c *Container) Peek(n int) ([]byte, error) {
+	if c.Length() < n {
+		return nil, errors.New("container: not enough data to peek")
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	c.skipEmpty()
+	if len(c.compartments[c.offset]) >= n {
+		return c.compartments[c.offset][:n], nil
+	}
+	buf := c.newPooledBuffer(n)
+	copied := 0
+	for i := c.offset; copied < n; i++ {
+		copied += copy(buf[copied:], c.compartments[i])
+	}
+	return buf, nil
+}
+
+// Slice returns a new container holding the next n bytes. Data is NOT copied and IS consumed.
+func (c *Container) Slice(n int) (*Container, error) {
+	if c.Length() < n {
+		return nil, errors.New("container: not enough data to slice")
+	}
+	new := &Container{}
+	for n > 0 {
+		c.skipEmpty()
+		compartment := c.compartments[c.offset]
+		if len(compartment) > n {
+			new.compartments = append(new.compartments, compartment[:n])
+			c.compartments[c.offset] = compartment[n:]
+			break
+		}
+		new.compartments = append(new.compartments, compartment)
+		n -= len(compartment)
+		c.compartments[c.offset] = nil
+		c.offset++
+	}
+	c.checkOffset()
+	return new, nil
+}
+
+// skipEmpty advances the offset past empty compartments.
+func (c *Container) skipEmpty() {
+	for c.offset < len(c.compartments)-1 && len(c.compartments[c.offset]) == 0 {
+		c.offset++
+	}
+}
@@ -0,0 +1,164 @@
+package container
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/safing/portbase/formats/varint"
+)
+
+// DefaultMaxBlockSize is the maximum block size used by a BlockDecoder if none is given.
+const DefaultMaxBlockSize = 1 << 20 // 1MB
+
+// Stream errors
+var (
+	ErrBlockTooLarge = errors.New("container: block exceeds maximum block size")
+)
+
+// Read reads data from the container into p, implementing io.Reader. It returns io.EOF if the container is empty. Data IS copied and IS consumed.
+func (c *Container) Read(p []byte) (n int, err error) {
+	if c.Length() == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n, _ = c.WriteToSlice(p)
+	return n, nil
+}
+
+// Write appends p to the container, implementing io.Writer. Data IS copied, as p may be reused by the caller.
+func (c *Container) Write(p []byte) (n int, err error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+	c.Append(data)
+	return len(p), nil
+}
+
+// WriteTo writes all data of the container to w, implementing io.WriterTo. Data is NOT copied and IS consumed.
+func (c *Container) WriteTo(w io.Writer) (n int64, err error) {
+	for c.offset < len(c.compartments) {
+		written, err := w.Write(c.compartments[c.offset])
+		n += int64(written)
+		if err != nil {
+			c.skip(written)
+			return n, err
+		}
+		c.compartments[c.offset] = nil
+		c.offset++
+	}
+	c.checkOffset()
+	return n, nil
+}
+
+// ReadFrom appends all data read from r until io.EOF, implementing io.ReaderFrom.
+func (c *Container) ReadFrom(r io.Reader) (n int64, err error) {
+	for {
+		buf := getBuffer(4096)
+		read, err := r.Read(*buf)
+		if read > 0 {
+			c.pooled = append(c.pooled, buf)
+			c.Append((*buf)[:read])
+			n += int64(read)
+		} else {
+			putBuffer(buf)
+		}
+		switch err {
+		case nil:
+		case io.EOF:
+			return n, nil
+		default:
+			return n, err
+		}
+	}
+}
+
+// WriteBlock writes data prefixed with its varint encoded length to w, using the same framing as varint.PrependLength and Container.PrependLength.
+func WriteBlock(w io.Writer, data []byte) error {
+	_, err := w.Write(varint.Pack64(uint64(len(data))))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// BlockDecoder reads varint length-prefixed blocks from a stream, without buffering more than one block.
+type BlockDecoder struct {
+	r            *bufio.Reader
+	maxBlockSize uint64
+}
+
+// NewBlockDecoder returns a new BlockDecoder reading from r. Blocks larger than maxBlockSize are rejected; a maxBlockSize of 0 or less uses DefaultMaxBlockSize.
+func NewBlockDecoder(r io.Reader, maxBlockSize int) *BlockDecoder {
+	if maxBlockSize <= 0 {
+		maxBlockSize = DefaultMaxBlockSize
+	}
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &BlockDecoder{
+		r:            br,
+		maxBlockSize: uint64(maxBlockSize),
+	}
+}
+
+// NextBlock reads and returns the next block. It returns io.EOF if the stream ends cleanly before a block and io.ErrUnexpectedEOF if it ends within a block.
+func (d *BlockDecoder) NextBlock() ([]byte, error) {
+	size, err := d.nextSize()
+	if err != nil {
+		return nil, err
+	}
+
+	block := make([]byte, size)
+	_, err = io.ReadFull(d.r, block)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+// NextBlockReader returns a reader for the next block, which allows for streaming the block itself. The returned reader must be read to the end before the next block can be read.
+func (d *BlockDecoder) NextBlockReader() (io.Reader, int, error) {
+	size, err := d.nextSize()
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.LimitReader(d.r, int64(size)), int(size), nil
+}
+
+// NextContainer reads the next block and returns it as a Container.
+func (d *BlockDecoder) NextContainer() (*Container, error) {
+	block, err := d.NextBlock()
+	if err != nil {
+		return nil, err
+	}
+	return New(block), nil
+}
+
+func (d *BlockDecoder) nextSize() (uint64, error) {
+	// peek first to distinguish a clean end of the stream
+	_, err := d.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, fmt.Errorf("container: failed to read block size: %s", err)
+	}
+	if size > d.maxBlockSize {
+		return 0, ErrBlockTooLarge
+	}
+	return size, nil
+}
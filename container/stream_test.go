@@ -0,0 +1,118 @@
+package container
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/safing/portbase/formats/varint"
+)
+
+func TestContainerReadWrite(t *testing.T) {
+	c := New()
+	for _, part := range testDataSplitted {
+		buf := append([]byte{}, part...)
+		n, err := c.Write(buf)
+		if err != nil || n != len(part) {
+			t.Fatalf("unexpected write result: %d, %s", n, err)
+		}
+		// overwrite buffer, container must have copied it
+		for i := range buf {
+			buf[i] = 0
+		}
+	}
+
+	data, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testData) {
+		t.Errorf("unexpected data: %s", string(data))
+	}
+
+	n, err := c.Read(make([]byte, 1))
+	if n != 0 || err != io.EOF {
+		t.Errorf("expected EOF on empty container, got %d, %s", n, err)
+	}
+
+	// ReadFrom and WriteTo
+	c = New()
+	_, err = c.ReadFrom(bytes.NewReader(testData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	written, err := New(copyOfTestDataSplitted()...).WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(written) != len(testData) || !bytes.Equal(buf.Bytes(), testData) {
+		t.Errorf("unexpected WriteTo result: %s", buf.String())
+	}
+	if !bytes.Equal(c.CompileData(), testData) {
+		t.Errorf("unexpected ReadFrom result: %s", string(c.CompileData()))
+	}
+}
+
+func TestBlockDecoder(t *testing.T) {
+	var stream bytes.Buffer
+	for _, part := range testDataSplitted {
+		err := WriteBlock(&stream, part)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// same framing as PrependLength
+	c := New(testData)
+	c.PrependLength()
+	stream.Write(c.CompileData())
+	stream.Write(varint.PrependLength(nil))
+
+	decoder := NewBlockDecoder(&stream, 0)
+	for _, part := range testDataSplitted {
+		block, err := decoder.NextBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(block, part) {
+			t.Errorf("unexpected block %q, expected %q", block, part)
+		}
+	}
+	blockReader, size, err := decoder.NextBlockReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ioutil.ReadAll(blockReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(testData) || !bytes.Equal(block, testData) {
+		t.Errorf("unexpected streamed block %q", block)
+	}
+	empty, err := decoder.NextContainer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Length() != 0 {
+		t.Errorf("expected empty block")
+	}
+	_, err = decoder.NextBlock()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	// max block size
+	decoder = NewBlockDecoder(bytes.NewReader(varint.PrependLength(testData)), 10)
+	_, err = decoder.NextBlock()
+	if err != ErrBlockTooLarge {
+		t.Errorf("expected ErrBlockTooLarge, got %v", err)
+	}
+
+	// truncated block
+	decoder = NewBlockDecoder(bytes.NewReader(varint.PrependLength(testData)[:10]), 0)
+	_, err = decoder.NextBlock()
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+	}
+}
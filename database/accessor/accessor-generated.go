@@ -0,0 +1,362 @@
+package accessor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Generated is implemented by structs that have an accessor generated by accessorgen.
+type Generated interface {
+	GeneratedAccessor() Accessor
+}
+
+// GeneratedFuncs is implemented by the code generated by accessorgen. The functions access the struct fields directly and return concrete types. Keys are never empty.
+type GeneratedFuncs interface {
+	Get(key string) (value interface{}, ok bool)
+	GetString(key string) (value string, ok bool)
+	GetStringArray(key string) (value []string, ok bool)
+	GetInt(key string) (value int64, ok bool)
+	GetFloat(key string) (value float64, ok bool)
+	GetBool(key string) (value bool, ok bool)
+	Set(key string, value interface{}) error
+}
+
+// GeneratedAccessor provides the Accessor interface on top of the typed functions generated by accessorgen. Only fields of interface types and of types of other packages are accessed with reflection, as the StructAccessor does.
+type GeneratedAccessor struct {
+	funcs GeneratedFuncs
+}
+
+// NewGeneratedAccessor returns a new GeneratedAccessor. It is called by generated code.
+func NewGeneratedAccessor(funcs GeneratedFuncs) *GeneratedAccessor {
+	return &GeneratedAccessor{
+		funcs: funcs,
+	}
+}
+
+// Set sets the value identified by key.
+func (ga *GeneratedAccessor) Set(key string, value interface{}) error {
+	if key == "" {
+		return ErrCannotSet(key)
+	}
+	return ga.funcs.Set(key, value)
+}
+
+// Get returns the value found by the given key and whether it could be successfully extracted.
+func (ga *GeneratedAccessor) Get(key string) (value interface{}, ok bool) {
+	if key == "" {
+		return nil, false
+	}
+	return ga.funcs.Get(key)
+}
+
+// GetString returns the string found by the given key and whether it could be successfully extracted.
+func (ga *GeneratedAccessor) GetString(key string) (value string, ok bool) {
+	if key == "" {
+		return emptyString, false
+	}
+	return ga.funcs.GetString(key)
+}
+
+// GetStringArray returns the []string found by the given key and whether it could be successfully extracted.
+func (ga *GeneratedAccessor) GetStringArray(key string) (value []string, ok bool) {
+	if key == "" {
+		return nil, false
+	}
+	return ga.funcs.GetStringArray(key)
+}
+
+// GetInt returns the int found by the given key and whether it could be successfully extracted.
+func (ga *GeneratedAccessor) GetInt(key string) (value int64, ok bool) {
+	if key == "" {
+		return 0, false
+	}
+	return ga.funcs.GetInt(key)
+}
+
+// GetFloat returns the float found by the given key and whether it could be successfully extracted.
+func (ga *GeneratedAccessor) GetFloat(key string) (value float64, ok bool) {
+	if key == "" {
+		return 0, false
+	}
+	return ga.funcs.GetFloat(key)
+}
+
+// GetBool returns the bool found by the given key and whether it could be successfully extracted.
+func (ga *GeneratedAccessor) GetBool(key string) (value bool, ok bool) {
+	if key == "" {
+		return false, false
+	}
+	return ga.funcs.GetBool(key)
+}
+
+// Exists returns the whether the given key exists.
+func (ga *GeneratedAccessor) Exists(key string) bool {
+	_, ok := ga.Get(key)
+	return ok
+}
+
+// Type returns the accessor type as a string.
+func (ga *GeneratedAccessor) Type() string {
+	return "GeneratedAccessor"
+}
+
+// The following helpers are used by generated code. They apply the same rules as the StructAccessor.
+
+// SplitKey splits the first selector off the given key path.
+func SplitKey(key string) (selector, rest string) {
+	i := strings.IndexByte(key, '.')
+	if i < 0 {
+		return key, ""
+	}
+	return key[:i], key[i+1:]
+}
+
+// SliceIndex parses the selector as an index into a slice or array of the given length.
+func SliceIndex(selector string, length int) (index int, ok bool) {
+	index, err := strconv.Atoi(selector)
+	if err != nil || index < 0 || index >= length {
+		return 0, false
+	}
+	return index, true
+}
+
+// GetField returns the value found by following the key path rest from the field that fieldPtr points to. It is used for fields that the generated code cannot access directly, such as interfaces.
+func GetField(fieldPtr interface{}, rest string) (value interface{}, ok bool) {
+	v, ok := resolvePath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest))
+	if !ok || !v.CanInterface() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+// GetFieldString is like GetField, but returns a string.
+func GetFieldString(fieldPtr interface{}, rest string) (value string, ok bool) {
+	v, ok := resolvePath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest))
+	if !ok {
+		return emptyString, false
+	}
+	return stringValue(v)
+}
+
+// GetFieldStringArray is like GetField, but returns a []string.
+func GetFieldStringArray(fieldPtr interface{}, rest string) (value []string, ok bool) {
+	v, ok := resolvePath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest))
+	if !ok {
+		return nil, false
+	}
+	return stringArrayValue(v)
+}
+
+// GetFieldInt is like GetField, but returns an int64.
+func GetFieldInt(fieldPtr interface{}, rest string) (value int64, ok bool) {
+	v, ok := resolvePath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest))
+	if !ok {
+		return 0, false
+	}
+	return intValue(v)
+}
+
+// GetFieldFloat is like GetField, but returns a float64.
+func GetFieldFloat(fieldPtr interface{}, rest string) (value float64, ok bool) {
+	v, ok := resolvePath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest))
+	if !ok {
+		return 0, false
+	}
+	return floatValue(v)
+}
+
+// GetFieldBool is like GetField, but returns a bool.
+func GetFieldBool(fieldPtr interface{}, rest string) (value bool, ok bool) {
+	v, ok := resolvePath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest))
+	if !ok {
+		return false, false
+	}
+	return boolValue(v)
+}
+
+// SetField sets the value found by following the key path rest from the field that fieldPtr points to, or the field itself if rest is empty. Key is the full key for error messages. It is used for fields that the generated code cannot access directly, and for values that need to be converted to the type of a composite field.
+func SetField(fieldPtr interface{}, rest, key string, value interface{}) error {
+	return setPath(reflect.ValueOf(fieldPtr).Elem(), splitPath(rest), key, value)
+}
+
+func splitPath(rest string) []string {
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, ".")
+}
+
+// ErrCannotSet returns the error for a key that does not exist or cannot be set.
+func ErrCannotSet(key string) error {
+	return fmt.Errorf("field %s does not exist or cannot be set", key)
+}
+
+// ConvertString returns the value as a string, which may be of a named string type.
+func ConvertString(key string, value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.String {
+		return "", fmt.Errorf("tried to set field %s (string) to a %T value", key, value)
+	}
+	return v.String(), nil
+}
+
+// ConvertBool returns the value as a bool, which may be of a named bool type.
+func ConvertBool(key string, value interface{}) (bool, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Bool {
+		return false, fmt.Errorf("tried to set field %s (bool) to a %T value", key, value)
+	}
+	return v.Bool(), nil
+}
+
+// number kinds
+const (
+	noNumber = iota
+	signedNumber
+	unsignedNumber
+	floatNumber
+)
+
+// number holds a number of any type in the widest type of its kind.
+type number struct {
+	kind int
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// toNumber returns the value as a number. Only named number types are handled with reflection.
+func toNumber(value interface{}) number {
+	switch v := value.(type) {
+	case int:
+		return number{kind: signedNumber, i: int64(v)}
+	case int8:
+		return number{kind: signedNumber, i: int64(v)}
+	case int16:
+		return number{kind: signedNumber, i: int64(v)}
+	case int32:
+		return number{kind: signedNumber, i: int64(v)}
+	case int64:
+		return number{kind: signedNumber, i: v}
+	case uint:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint8:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint16:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint32:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint64:
+		return number{kind: unsignedNumber, u: v}
+	case float32:
+		return number{kind: floatNumber, f: float64(v)}
+	case float64:
+		return number{kind: floatNumber, f: v}
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: signedNumber, i: v.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return number{kind: unsignedNumber, u: v.Uint()}
+	case reflect.Float32, reflect.Float64:
+		return number{kind: floatNumber, f: v.Float()}
+	default:
+		return number{kind: noNumber}
+	}
+}
+
+// ConvertInt returns the value as an int64 and checks if it fits into an int of the given bit size. A bit size of 0 stands for int. Floats are accepted if they are integral, as JSON does not distinguish between ints and floats.
+func ConvertInt(key string, value interface{}, bitSize int) (int64, error) {
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+	var n int64
+	num := toNumber(value)
+	switch num.kind {
+	case signedNumber:
+		n = num.i
+	case unsignedNumber:
+		if num.u > math.MaxInt64 {
+			return 0, fmt.Errorf("setting field %s (int%d) to %d would overflow", key, bitSize, num.u)
+		}
+		n = int64(num.u)
+	case floatNumber:
+		// we must not lose precision
+		if num.f != math.Trunc(num.f) {
+			return 0, fmt.Errorf("tried to set field %s (int%d) to non-integral value %f", key, bitSize, num.f)
+		}
+		if num.f < math.MinInt64 || num.f >= math.MaxInt64 {
+			return 0, fmt.Errorf("setting field %s (int%d) to %f would overflow", key, bitSize, num.f)
+		}
+		n = int64(num.f)
+	default:
+		return 0, fmt.Errorf("tried to set field %s (int%d) to a %T value", key, bitSize, value)
+	}
+	if bitSize < 64 && (n < -1<<uint(bitSize-1) || n > 1<<uint(bitSize-1)-1) {
+		return 0, fmt.Errorf("setting field %s (int%d) to %d would overflow", key, bitSize, n)
+	}
+	return n, nil
+}
+
+// ConvertUint returns the value as an uint64 and checks if it fits into an uint of the given bit size. A bit size of 0 stands for uint. Negative values are rejected, floats are accepted if they are integral.
+func ConvertUint(key string, value interface{}, bitSize int) (uint64, error) {
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+	var n uint64
+	num := toNumber(value)
+	switch num.kind {
+	case signedNumber:
+		if num.i < 0 {
+			return 0, fmt.Errorf("tried to set field %s (uint%d) to negative value %d", key, bitSize, num.i)
+		}
+		n = uint64(num.i)
+	case unsignedNumber:
+		n = num.u
+	case floatNumber:
+		if num.f < 0 || num.f != math.Trunc(num.f) {
+			return 0, fmt.Errorf("tried to set field %s (uint%d) to value %f", key, bitSize, num.f)
+		}
+		if num.f >= math.MaxUint64 {
+			return 0, fmt.Errorf("setting field %s (uint%d) to %f would overflow", key, bitSize, num.f)
+		}
+		n = uint64(num.f)
+	default:
+		return 0, fmt.Errorf("tried to set field %s (uint%d) to a %T value", key, bitSize, value)
+	}
+	if bitSize < 64 && n > 1<<uint(bitSize)-1 {
+		return 0, fmt.Errorf("setting field %s (uint%d) to %d would overflow", key, bitSize, n)
+	}
+	return n, nil
+}
+
+// ConvertFloat returns the value as a float64 and checks if it fits into a float of the given bit size.
+func ConvertFloat(key string, value interface{}, bitSize int) (float64, error) {
+	var f float64
+	num := toNumber(value)
+	switch num.kind {
+	case floatNumber:
+		f = num.f
+	case signedNumber:
+		f = float64(num.i)
+	case unsignedNumber:
+		f = float64(num.u)
+	default:
+		return 0, fmt.Errorf("tried to set field %s (float%d) to a %T value", key, bitSize, value)
+	}
+	if bitSize == 32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+		return 0, fmt.Errorf("setting field %s (float32) to %f would overflow", key, f)
+	}
+	return f, nil
+}
@@ -0,0 +1,230 @@
+package accessor
+
+import (
+	"reflect"
+	"testing"
+)
+
+//go:generate go run ./accessorgen -type TestStruct,TestNested,TestPathStruct -output generated-accessors_test.go
+
+type TestLevel int8
+
+type TestNested struct {
+	Name     string
+	Level    TestLevel
+	Child    TestChild
+	Ptr      *TestChild
+	Children []TestChild
+	Labels   map[string]string
+	Groups   map[string][]string
+	internal string
+}
+
+type TestChild struct {
+	Value int
+	Tags  []string
+}
+
+func TestGeneratedAccessor(t *testing.T) {
+	nested := &TestNested{
+		Name:  "root",
+		Level: 3,
+		Child: TestChild{
+			Value: 1,
+			Tags:  []string{"a", "b"},
+		},
+		Children: []TestChild{
+			{Value: 2},
+			{Value: 3},
+		},
+		Labels: map[string]string{
+			"color": "green",
+		},
+		internal: "hidden",
+	}
+	var acc Accessor = nested.GeneratedAccessor()
+
+	// get
+	testGetString(t, acc, "Name", true, "root")
+	testGetInt(t, acc, "Level", true, 3)
+	testGetInt(t, acc, "Child.Value", true, 1)
+	testGetStringArray(t, acc, "Child.Tags", true, []string{"a", "b"})
+	testGetString(t, acc, "Child.Tags.1", true, "b")
+	testGetInt(t, acc, "Children.1.Value", true, 3)
+	testGetString(t, acc, "Labels.color", true, "green")
+	testExists(t, acc, "Ptr", true)
+	testExists(t, acc, "Ptr.Value", false)
+	testExists(t, acc, "Children.2", false)
+	testExists(t, acc, "Labels.size", false)
+	testExists(t, acc, "Name.Value", false)
+	testExists(t, acc, "internal", false)
+
+	// set
+	testSet(t, acc, "Level", true, 5)
+	testSet(t, acc, "Level", false, 500)
+	testSet(t, acc, "Child.Value", true, int64(10))
+	testSet(t, acc, "Child.Tags.0", true, "c")
+	testSet(t, acc, "Children.0.Value", true, uint8(20))
+	testSet(t, acc, "Children.5.Value", false, 20)
+	testSet(t, acc, "Labels.size", true, "large")
+	testSet(t, acc, "Groups.admins", true, []string{"alice"})
+	testSet(t, acc, "Groups.admins.0", true, "bob")
+	testSet(t, acc, "Ptr", true, &TestChild{Value: 30})
+	testSet(t, acc, "Ptr.Value", true, 40)
+	testSet(t, acc, "Child", false, "child")
+	testSet(t, acc, "internal", false, "visible")
+
+	testGetInt(t, acc, "Level", true, 5)
+	testGetInt(t, acc, "Child.Value", true, 10)
+	testGetString(t, acc, "Child.Tags.0", true, "c")
+	testGetInt(t, acc, "Children.0.Value", true, 20)
+	testGetString(t, acc, "Labels.size", true, "large")
+	testGetString(t, acc, "Groups.admins.0", true, "bob")
+	testGetInt(t, acc, "Ptr.Value", true, 40)
+	if nested.internal != "hidden" {
+		t.Error("unexported field should not be changed")
+	}
+}
+
+func TestConvertUint(t *testing.T) {
+	n, err := ConvertUint("Value", 5, 64)
+	if err != nil || n != 5 {
+		t.Errorf("expected 5, got %d (%v)", n, err)
+	}
+	for _, value := range []interface{}{-1, int8(-1), int64(-1)} {
+		_, err := ConvertUint("Value", value, 64)
+		if err == nil {
+			t.Errorf("negative value %#v should be rejected", value)
+		}
+	}
+	_, err = ConvertUint("Value", 256, 8)
+	if err == nil {
+		t.Error("overflowing value should be rejected")
+	}
+}
+
+func TestGeneratedAccessorMatchesStructAccessor(t *testing.T) {
+	newSubject := func() *TestPathStruct {
+		return &TestPathStruct{
+			Items: []TestPathItem{{Name: "first", Value: 1}},
+			Tags:  []string{"a"},
+			Any:   map[string]interface{}{"n": 1.0},
+		}
+	}
+	viaStruct := newSubject()
+	viaGenerated := newSubject()
+	accs := []Accessor{
+		NewStructAccessor(viaStruct),
+		viaGenerated.GeneratedAccessor(),
+	}
+
+	sets := []struct {
+		key   string
+		value interface{}
+	}{
+		{"Nested.Name", "nested"},
+		{"Nested.Name", 1},
+		{"Nested.Value", 2.0},
+		{"Nested.Value", 2.5},
+		{"Nested.Value", uint64(1 << 63)},
+		{"Nested.Value", TestLevel(3)},
+		{"Items.0.Value", int64(3)},
+		{"Items.0.Value", "1"},
+		{"Items.-1.Name", "appended"},
+		{"Items.1.Value", 4.0},
+		{"Items.5.Value", 1},
+		{"Items.0.Name.Value", "deep"},
+		{"Labels.color", "green"},
+		{"Labels.color", true},
+		{"Tags.0", "b"},
+		{"Tags.-1", "c"},
+		{"Any.n", 2.5},
+		{"Ptr.Value", 5},
+		{"Ptr.Name", "created"},
+		{"Nested", "nested"},
+		{"Nested", TestPathItem{Name: "replaced"}},
+		{"Unknown", 1},
+		{"Any.n", nil},
+	}
+	for _, set := range sets {
+		structErr := accs[0].Set(set.key, set.value)
+		generatedErr := accs[1].Set(set.key, set.value)
+		if (structErr == nil) != (generatedErr == nil) {
+			t.Errorf("setting %s to %#v: struct accessor returned %v, generated accessor returned %v", set.key, set.value, structErr, generatedErr)
+		}
+	}
+	if !reflect.DeepEqual(viaStruct, viaGenerated) {
+		t.Errorf("accessors mismatch:\nstruct:    %+v\ngenerated: %+v", viaStruct, viaGenerated)
+	}
+
+	keys := []string{
+		"Nested", "Nested.Name", "Nested.Value", "Nested.Missing",
+		"Items", "Items.#", "Items.1", "Items.1.Name", "Items.2", "Items.-1",
+		"Labels", "Labels.#", "Labels.color", "Labels.size",
+		"Ptr", "Ptr.Value", "Tags", "Tags.1", "Any.n", "Unknown",
+	}
+	for _, key := range keys {
+		structValue, structOk := accs[0].Get(key)
+		generatedValue, generatedOk := accs[1].Get(key)
+		if structOk != generatedOk || !reflect.DeepEqual(structValue, generatedValue) {
+			t.Errorf("getting %s: struct accessor returned %#v (%v), generated accessor returned %#v (%v)", key, structValue, structOk, generatedValue, generatedOk)
+		}
+	}
+
+	// typed getters
+	for _, acc := range accs {
+		testGetInt(t, acc, "Items.#", true, 2)
+		testGetInt(t, acc, "Nested.Value", true, 0)
+		testGetStringArray(t, acc, "Tags", true, []string{"b", "c"})
+		testGetString(t, acc, "Ptr.Name", true, "created")
+	}
+
+	// named types
+	nested := &TestNested{}
+	for _, acc := range []Accessor{NewStructAccessor(nested), nested.GeneratedAccessor()} {
+		testSet(t, acc, "Level", true, TestLevel(2))
+		testSet(t, acc, "Level", true, 4.0)
+		testGetInt(t, acc, "Level", true, 4)
+	}
+}
+
+// Benchmark:
+// BenchmarkStructAccessor        	  833942	      1505 ns/op	     239 B/op	       9 allocs/op
+// BenchmarkGeneratedAccessor     	 5208081	       211.5 ns/op	       7 B/op	       0 allocs/op
+
+func newBenchmarkSubject() *TestNested {
+	return &TestNested{
+		Name:  "root",
+		Level: 3,
+		Child: TestChild{
+			Value: 1,
+			Tags:  []string{"a", "b"},
+		},
+		Children: []TestChild{
+			{Value: 2},
+			{Value: 3},
+		},
+		Labels: map[string]string{
+			"color": "green",
+		},
+	}
+}
+
+func benchmarkAccessor(b *testing.B, acc Accessor) {
+	for i := 0; i < b.N; i++ {
+		_, _ = acc.GetString("Name")
+		_, _ = acc.GetInt("Level")
+		_, _ = acc.GetInt("Children.1.Value")
+		_, _ = acc.GetString("Labels.color")
+		_, _ = acc.GetStringArray("Child.Tags")
+		_ = acc.Set("Child.Value", i)
+	}
+}
+
+func BenchmarkStructAccessor(b *testing.B) {
+	benchmarkAccessor(b, NewStructAccessor(newBenchmarkSubject()))
+}
+
+func BenchmarkGeneratedAccessor(b *testing.B) {
+	benchmarkAccessor(b, newBenchmarkSubject().GeneratedAccessor())
+}
@@ -4,6 +4,8 @@
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // StructAccessor is a json string with get functions.
@@ -18,67 +20,179 @@ func NewStructAccessor(object interface{}) *StructAccessor {
 	}
 }
 
-// Set sets the value identified by key.
+// Set sets the value identified by key. The key may be a path (eg. `field.sub.0`) into nested structs, maps with string keys and slices. The slice index -1 appends a new element. Missing pointers and maps along the path are created.
 func (sa *StructAccessor) Set(key string, value interface{}) error {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() {
-		return errors.New("struct field does not exist")
+	return setPath(sa.object, strings.Split(key, "."), key, value)
+}
+
+func setPath(v reflect.Value, path []string, key string, value interface{}) error {
+	if len(path) == 0 {
+		return setValue(v, key, value)
+	}
+	selector := path[0]
+
+	// create missing pointers
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return fmt.Errorf("cannot set %s: nil pointer", key)
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := exportedField(v, selector)
+		if !ok {
+			return errors.New("struct field does not exist")
+		}
+		return setPath(field, path[1:], key, value)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot set %s: map keys must be strings", key)
+		}
+		if !v.CanSet() {
+			return fmt.Errorf("field %s or struct is immutable", key)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		// map values are not addressable, modify a copy and put it back
+		mapKey := reflect.ValueOf(selector).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(mapKey); existing.IsValid() {
+			elem.Set(existing)
+		}
+		err := setPath(elem, path[1:], key, value)
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(mapKey, elem)
+		return nil
+
+	case reflect.Slice:
+		if selector == "-1" {
+			if !v.CanSet() {
+				return fmt.Errorf("field %s or struct is immutable", key)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err := setPath(elem, path[1:], key, value)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, elem))
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		index, err := strconv.Atoi(selector)
+		if err != nil || index < 0 || index >= v.Len() {
+			return fmt.Errorf("cannot set %s: index %s out of range", key, selector)
+		}
+		return setPath(v.Index(index), path[1:], key, value)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("cannot set %s: nil interface", key)
+		}
+		if !v.CanSet() {
+			return fmt.Errorf("field %s or struct is immutable", key)
+		}
+		// the dynamic value is not addressable, modify a copy and put it back
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		err := setPath(elem, path, key, value)
+		if err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	default:
+		return fmt.Errorf("cannot set %s: %s has no fields", key, v.Kind().String())
 	}
+}
+
+// setValue sets field to value, converting numbers like the JSON accessors do.
+func setValue(field reflect.Value, key string, value interface{}) error {
 	if !field.CanSet() {
 		return fmt.Errorf("field %s or struct is immutable", field.String())
 	}
 
+	// nil resets pointers, slices, maps and interfaces
+	if value == nil {
+		switch field.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		default:
+			return fmt.Errorf("tried to set field %s (%s) to nil", key, field.Kind().String())
+		}
+	}
+
 	newVal := reflect.ValueOf(value)
 
 	// set directly if type matches
-	if newVal.Kind() == field.Kind() {
+	if newVal.Type().AssignableTo(field.Type()) {
 		field.Set(newVal)
 		return nil
 	}
 
 	// handle special cases
 	switch field.Kind() {
-
-	// ints
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		var newInt int64
-		switch newVal.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			newInt = newVal.Int()
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			newInt = int64(newVal.Uint())
-		default:
-			return fmt.Errorf("tried to set field %s (%s) to a %s value", key, field.Kind().String(), newVal.Kind().String())
-		}
-		if field.OverflowInt(newInt) {
-			return fmt.Errorf("setting field %s (%s) to %d would overflow", key, field.Kind().String(), newInt)
+		n, err := ConvertInt(key, value, bitSize(field))
+		if err != nil {
+			return err
 		}
-		field.SetInt(newInt)
+		field.SetInt(n)
 
-		// uints
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		var newUint uint64
-		switch newVal.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			newUint = uint64(newVal.Int())
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			newUint = newVal.Uint()
-		default:
-			return fmt.Errorf("tried to set field %s (%s) to a %s value", key, field.Kind().String(), newVal.Kind().String())
+		n, err := ConvertUint(key, value, bitSize(field))
+		if err != nil {
+			return err
 		}
-		if field.OverflowUint(newUint) {
-			return fmt.Errorf("setting field %s (%s) to %d would overflow", key, field.Kind().String(), newUint)
-		}
-		field.SetUint(newUint)
+		field.SetUint(n)
 
-		// floats
 	case reflect.Float32, reflect.Float64:
-		switch newVal.Kind() {
-		case reflect.Float32, reflect.Float64:
-			field.SetFloat(newVal.Float())
-		default:
+		f, err := ConvertFloat(key, value, bitSize(field))
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+
+		// strings and bools, possibly of named types
+	case reflect.String:
+		s, err := ConvertString(key, value)
+		if err != nil {
+			return err
+		}
+		field.SetString(s)
+
+	case reflect.Bool:
+		b, err := ConvertBool(key, value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+
+		// slices, element by element
+	case reflect.Slice:
+		if newVal.Kind() != reflect.Slice && newVal.Kind() != reflect.Array {
 			return fmt.Errorf("tried to set field %s (%s) to a %s value", key, field.Kind().String(), newVal.Kind().String())
 		}
+		newSlice := reflect.MakeSlice(field.Type(), newVal.Len(), newVal.Len())
+		for i := 0; i < newVal.Len(); i++ {
+			err := setValue(newSlice.Index(i), fmt.Sprintf("%s.%d", key, i), newVal.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		field.Set(newSlice)
+
 	default:
 		return fmt.Errorf("tried to set field %s (%s) to a %s value", key, field.Kind().String(), newVal.Kind().String())
 	}
@@ -86,10 +200,83 @@ func (sa *StructAccessor) Set(key string, value interface{}) error {
 	return nil
 }
 
+// bitSize returns the bit size of a number field, 0 stands for int and uint.
+func bitSize(field reflect.Value) int {
+	if field.Kind() == reflect.Int || field.Kind() == reflect.Uint {
+		return 0
+	}
+	return field.Type().Bits()
+}
+
+// exportedField returns the exported field of the struct with the given name.
+func exportedField(v reflect.Value, name string) (reflect.Value, bool) {
+	structField, ok := v.Type().FieldByName(name)
+	if !ok || structField.PkgPath != "" {
+		return reflect.Value{}, false
+	}
+	return v.FieldByIndex(structField.Index), true
+}
+
+// resolve returns the value found by the given key, which may be a path into nested structs, maps with string keys and slices. The selector # returns the length of a map or slice.
+func (sa *StructAccessor) resolve(key string) (reflect.Value, bool) {
+	return resolvePath(sa.object, strings.Split(key, "."))
+}
+
+func resolvePath(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, selector := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			var ok bool
+			v, ok = exportedField(v, selector)
+			if !ok {
+				return reflect.Value{}, false
+			}
+		case reflect.Map:
+			if selector == "#" {
+				v = reflect.ValueOf(v.Len())
+				continue
+			}
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			v = v.MapIndex(reflect.ValueOf(selector).Convert(v.Type().Key()))
+		case reflect.Slice, reflect.Array:
+			if selector == "#" {
+				v = reflect.ValueOf(v.Len())
+				continue
+			}
+			index, err := strconv.Atoi(selector)
+			if err != nil || index < 0 || index >= v.Len() {
+				return reflect.Value{}, false
+			}
+			v = v.Index(index)
+		default:
+			return reflect.Value{}, false
+		}
+
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+
+	// resolve to the final value
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v, true
+}
+
 // Get returns the value found by the given json key and whether it could be successfully extracted.
 func (sa *StructAccessor) Get(key string) (value interface{}, ok bool) {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() || !field.CanInterface() {
+	field, ok := sa.resolve(key)
+	if !ok || !field.CanInterface() {
 		return nil, false
 	}
 	return field.Interface(), true
@@ -97,73 +284,100 @@ func (sa *StructAccessor) Get(key string) (value interface{}, ok bool) {
 
 // GetString returns the string found by the given json key and whether it could be successfully extracted.
 func (sa *StructAccessor) GetString(key string) (value string, ok bool) {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() || field.Kind() != reflect.String {
+	field, ok := sa.resolve(key)
+	if !ok {
 		return "", false
 	}
-	return field.String(), true
+	return stringValue(field)
 }
 
 // GetStringArray returns the []string found by the given json key and whether it could be successfully extracted.
 func (sa *StructAccessor) GetStringArray(key string) (value []string, ok bool) {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() || field.Kind() != reflect.Slice || !field.CanInterface() {
-		return nil, false
-	}
-	v := field.Interface()
-	slice, ok := v.([]string)
+	field, ok := sa.resolve(key)
 	if !ok {
 		return nil, false
 	}
-	return slice, true
+	return stringArrayValue(field)
 }
 
 // GetInt returns the int found by the given json key and whether it could be successfully extracted.
 func (sa *StructAccessor) GetInt(key string) (value int64, ok bool) {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() {
-		return 0, false
-	}
-	switch field.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return field.Int(), true
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return int64(field.Uint()), true
-	default:
+	field, ok := sa.resolve(key)
+	if !ok {
 		return 0, false
 	}
+	return intValue(field)
 }
 
 // GetFloat returns the float found by the given json key and whether it could be successfully extracted.
 func (sa *StructAccessor) GetFloat(key string) (value float64, ok bool) {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() {
-		return 0, false
-	}
-	switch field.Kind() {
-	case reflect.Float32, reflect.Float64:
-		return field.Float(), true
-	default:
+	field, ok := sa.resolve(key)
+	if !ok {
 		return 0, false
 	}
+	return floatValue(field)
 }
 
 // GetBool returns the bool found by the given json key and whether it could be successfully extracted.
 func (sa *StructAccessor) GetBool(key string) (value bool, ok bool) {
-	field := sa.object.FieldByName(key)
-	if !field.IsValid() || field.Kind() != reflect.Bool {
+	field, ok := sa.resolve(key)
+	if !ok {
 		return false, false
 	}
-	return field.Bool(), true
+	return boolValue(field)
 }
 
 // Exists returns the whether the given key exists.
 func (sa *StructAccessor) Exists(key string) bool {
-	field := sa.object.FieldByName(key)
-	return field.IsValid()
+	_, ok := sa.resolve(key)
+	return ok
 }
 
 // Type returns the accessor type as a string.
 func (sa *StructAccessor) Type() string {
 	return "StructAccessor"
 }
+
+// The following functions extract typed values for the typed getters of the struct and generated accessors.
+
+func stringValue(v reflect.Value) (string, bool) {
+	if v.Kind() != reflect.String {
+		return "", false
+	}
+	return v.String(), true
+}
+
+func stringArrayValue(v reflect.Value) ([]string, bool) {
+	if v.Kind() != reflect.Slice || !v.CanInterface() {
+		return nil, false
+	}
+	slice, ok := v.Interface().([]string)
+	return slice, ok
+}
+
+func intValue(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+func floatValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func boolValue(v reflect.Value) (bool, bool) {
+	if v.Kind() != reflect.Bool {
+		return false, false
+	}
+	return v.Bool(), true
+}
@@ -3,6 +3,7 @@
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/safing/portbase/utils"
@@ -140,6 +141,7 @@ func TestAccessor(t *testing.T) {
 		NewJSONAccessor(&testJSON),
 		NewJSONBytesAccessor(&testJSONBytes),
 		NewStructAccessor(testStruct),
+		testStruct.GeneratedAccessor(),
 	}
 
 	// get
@@ -275,3 +277,81 @@ func TestAccessor(t *testing.T) {
 	}
 
 }
+
+type TestPathItem struct {
+	Name  string
+	Value int
+}
+
+type TestPathStruct struct {
+	Nested TestPathItem
+	Items  []TestPathItem
+	Labels map[string]string
+	Ptr    *TestPathItem
+	Tags   []string
+	Any    map[string]interface{}
+}
+
+func TestStructAccessorPaths(t *testing.T) {
+	subject := &TestPathStruct{
+		Items: []TestPathItem{{Name: "first", Value: 1}},
+		Tags:  []string{"a"},
+		Any:   map[string]interface{}{"n": 1.0},
+	}
+	jsonData, err := json.Marshal(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accs := []Accessor{
+		NewStructAccessor(subject),
+		NewJSONBytesAccessor(&jsonData),
+	}
+
+	// both accessors must end up with the same data
+	for _, acc := range accs {
+		testSet(t, acc, "Nested.Name", true, "nested")
+		testSet(t, acc, "Nested.Value", true, uint8(2))
+		testSet(t, acc, "Items.0.Value", true, int64(3))
+		testSet(t, acc, "Items.-1.Name", true, "appended")
+		testSet(t, acc, "Items.1.Value", true, 4.0)
+		testSet(t, acc, "Labels.color", true, "green")
+		testSet(t, acc, "Tags.0", true, "b")
+		testSet(t, acc, "Tags.-1", true, "c")
+		testSet(t, acc, "Any.n", true, 2.5)
+
+		testSet(t, acc, "Nested.Name", false, 1)
+		testSet(t, acc, "Items.0.Value", false, "1")
+		testSet(t, acc, "Labels.color", false, true)
+	}
+	fromJSON := &TestPathStruct{}
+	err = json.Unmarshal(jsonData, fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subject, fromJSON) {
+		t.Errorf("accessors mismatch:\nstruct: %+v\njson:   %+v", subject, fromJSON)
+	}
+
+	for _, acc := range accs {
+		testGetString(t, acc, "Nested.Name", true, "nested")
+		testGetInt(t, acc, "Items.1.Value", true, 4)
+		testGetString(t, acc, "Items.1.Name", true, "appended")
+		testGetInt(t, acc, "Items.#", true, 2)
+		testGetString(t, acc, "Labels.color", true, "green")
+		testGetStringArray(t, acc, "Tags", true, []string{"b", "c"})
+		testGetFloat(t, acc, "Any.n", true, 2.5)
+		testExists(t, acc, "Items.2", false)
+		testExists(t, acc, "Labels.size", false)
+	}
+
+	// struct specifics
+	acc := NewStructAccessor(subject)
+	testSet(t, acc, "Ptr.Value", true, 5)
+	testGetInt(t, acc, "Ptr.Value", true, 5)
+	testSet(t, acc, "Ptr", true, nil)
+	testExists(t, acc, "Ptr.Value", false)
+	testSet(t, acc, "Items.0.Value", false, 1.5)
+	testSet(t, acc, "Items.5.Value", false, 1)
+	testSet(t, acc, "Tags", true, []interface{}{"x", "y"})
+	testGetStringArray(t, acc, "Tags", true, []string{"x", "y"})
+}
@@ -0,0 +1,732 @@
+// Command accessorgen generates typed accessor.Accessor implementations for structs, which avoid the reflection used by accessor.StructAccessor.
+//
+// Add a go:generate directive to the package that defines the structs:
+//
+//	//go:generate go run github.com/safing/portbase/database/accessor/accessorgen -type Example,Other
+//
+// The generated accessors support the same key paths and value conversions as accessor.StructAccessor: nested struct fields, slice indexes and map keys, separated by dots (eg. "Items.0.Name").
+// For every type along the paths, typed functions are generated that access fields, slice elements and map entries directly and return concrete types.
+// Only fields of interface types and of types of other packages are handled by the path functions of the StructAccessor, which use reflection.
+// Nested structs of the same package are generated along, so all structs of a package should be generated with one invocation.
+// Record structs that embed record.Base will automatically use the generated accessor.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct type names; required")
+	output    = flag.String("output", "", "output file name; default <type>-accessor.go")
+	dir       = flag.String("dir", ".", "directory of the package")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("accessorgen: ")
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	types := strings.Split(*typeNames, ",")
+
+	g, err := newGenerator(*dir, os.Getenv("GOPACKAGE"), types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := g.generate(types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputName := *output
+	if outputName == "" {
+		outputName = strings.ToLower(types[0]) + "-accessor.go"
+		if strings.HasSuffix(g.typeFiles[types[0]], "_test.go") {
+			outputName = strings.TrimSuffix(outputName, ".go") + "_test.go"
+		}
+	}
+	if !filepath.IsAbs(outputName) {
+		outputName = filepath.Join(*dir, outputName)
+	}
+	err = ioutil.WriteFile(outputName, src, 0644) //nolint:gosec // source files are not secret
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// field kinds
+const (
+	kindBasic = iota
+	kindStruct
+	kindPointer
+	kindSlice
+	kindArray
+	kindMap
+	// kindOpaque fields, such as interfaces and types of other packages, are accessed with the path functions of the StructAccessor.
+	kindOpaque
+)
+
+// fieldType describes a field type for code generation.
+type fieldType struct {
+	kind int
+	// expr is the type in Go syntax, which is the name for local named types.
+	expr string
+	// id names the functions generated for the type. Opaque types that cannot be part of a function name have no id.
+	id string
+	// basic is the underlying builtin type of basic types.
+	basic string
+	// length is the length of array types.
+	length string
+	// key is the key type of maps.
+	key *fieldType
+	// elem is the element type of pointers, slices, arrays and maps.
+	elem *fieldType
+}
+
+// getter describes a get function of accessor.Accessor.
+type getter struct {
+	name   string
+	result string
+	zero   string
+	// fallback is the function of the accessor package that is used for opaque types.
+	fallback string
+}
+
+var getters = []*getter{
+	{name: "Get", result: "interface{}", zero: "nil", fallback: "GetField"},
+	{name: "GetString", result: "string", zero: `""`, fallback: "GetFieldString"},
+	{name: "GetStringArray", result: "[]string", zero: "nil", fallback: "GetFieldStringArray"},
+	{name: "GetInt", result: "int64", zero: "0", fallback: "GetFieldInt"},
+	{name: "GetFloat", result: "float64", zero: "0", fallback: "GetFieldFloat"},
+	{name: "GetBool", result: "bool", zero: "false", fallback: "GetFieldBool"},
+}
+
+// queuedFunc is a function that is called by generated code and still needs to be generated. Setters have no getter.
+type queuedFunc struct {
+	get *getter
+	t   *fieldType
+}
+
+type generator struct {
+	fset      *token.FileSet
+	pkgName   string
+	specs     map[string]*ast.TypeSpec
+	typeFiles map[string]string
+	fields    map[string][]structField
+
+	buf       bytes.Buffer
+	generated map[string]bool
+	queue     []queuedFunc
+}
+
+func newGenerator(dir, pkgName string, types []string) (*generator, error) {
+	g := &generator{
+		fset:      token.NewFileSet(),
+		specs:     make(map[string]*ast.TypeSpec),
+		typeFiles: make(map[string]string),
+		fields:    make(map[string][]structField),
+		generated: make(map[string]bool),
+	}
+
+	pkgs, err := parser.ParseDir(g.fset, dir, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// find package
+	var pkg *ast.Package
+	if pkgName != "" {
+		pkg = pkgs[pkgName]
+	} else {
+		for _, p := range pkgs {
+			if hasType(p, types[0]) {
+				pkg = p
+				break
+			}
+		}
+	}
+	if pkg == nil {
+		return nil, fmt.Errorf("could not find package of type %s in %s", types[0], dir)
+	}
+	g.pkgName = pkg.Name
+
+	// collect type declarations
+	for fileName, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				g.specs[typeSpec.Name.Name] = typeSpec
+				g.typeFiles[typeSpec.Name.Name] = fileName
+			}
+		}
+	}
+
+	for _, name := range types {
+		if _, ok := g.localStruct(name); !ok {
+			return nil, fmt.Errorf("type %s is not a struct in package %s", name, g.pkgName)
+		}
+	}
+	return g, nil
+}
+
+func hasType(pkg *ast.Package, name string) bool {
+	for _, file := range pkg.Files {
+		if file.Scope.Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// qualifier returns the qualifier for the accessor package.
+func (g *generator) qualifier() string {
+	if g.pkgName == "accessor" {
+		return ""
+	}
+	return "accessor."
+}
+
+func (g *generator) generate(types []string) ([]byte, error) {
+	q := g.qualifier()
+
+	g.printf("// Code generated by accessorgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", g.pkgName)
+	if q != "" {
+		g.printf("import \"github.com/safing/portbase/database/accessor\"\n\n")
+	}
+
+	for _, name := range types {
+		t := g.classify(ast.NewIdent(name), 0)
+		funcsType := "accessorFuncs" + name
+
+		g.printf("// GeneratedAccessor returns a typed accessor for %s.\n", name)
+		g.printf("func (obj *%s) GeneratedAccessor() %sAccessor {\n", name, q)
+		g.printf("return %sNewGeneratedAccessor(%s{obj})\n}\n\n", q, funcsType)
+
+		g.printf("// %s implements accessor.GeneratedFuncs for %s.\n", funcsType, name)
+		g.printf("type %s struct {\nobj *%s\n}\n\n", funcsType, name)
+		for _, get := range getters {
+			g.printf("func (f %s) %s(key string) (%s, bool) {\n", funcsType, get.name, get.result)
+			g.printf("return %s\n}\n\n", g.getCall(get, t, "f.obj", "key"))
+		}
+		g.printf("func (f %s) Set(key string, value interface{}) error {\n", funcsType)
+		g.printf("return %s\n}\n\n", g.setCall(t, "f.obj", "key"))
+	}
+
+	// generate the functions for all types along the paths
+	for len(g.queue) > 0 {
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		if next.get != nil {
+			g.generateGetter(next.get, next.t)
+		} else {
+			g.generateSetter(next.t)
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %s\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+type structField struct {
+	name string
+	expr string
+	t    *fieldType
+}
+
+// structFields returns the exported fields of a local struct, including the fields promoted from embedded local structs.
+func (g *generator) structFields(name string) []structField {
+	fields, ok := g.fields[name]
+	if !ok {
+		st, _ := g.localStruct(name)
+		fields = g.collectFields(st, "obj.", make(map[string]bool))
+		g.fields[name] = fields
+	}
+	return fields
+}
+
+func (g *generator) collectFields(st *ast.StructType, prefix string, seen map[string]bool) []structField {
+	var fields []structField
+	var embedded []*ast.StructType
+	var embeddedPrefixes []string
+
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			// embedded field
+			ident, ok := field.Type.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			embeddedStruct, ok := g.localStruct(ident.Name)
+			if !ok || !ast.IsExported(ident.Name) || seen[ident.Name] {
+				continue
+			}
+			seen[ident.Name] = true
+			fields = append(fields, structField{
+				name: ident.Name,
+				expr: prefix + ident.Name,
+				t:    g.classify(field.Type, 0),
+			})
+			embedded = append(embedded, embeddedStruct)
+			embeddedPrefixes = append(embeddedPrefixes, prefix+ident.Name+".")
+			continue
+		}
+
+		for _, name := range field.Names {
+			if !name.IsExported() || seen[name.Name] {
+				continue
+			}
+			seen[name.Name] = true
+			fields = append(fields, structField{
+				name: name.Name,
+				expr: prefix + name.Name,
+				t:    g.classify(field.Type, 0),
+			})
+		}
+	}
+
+	// promoted fields are shadowed by the fields of the outer struct
+	for i, embeddedStruct := range embedded {
+		fields = append(fields, g.collectFields(embeddedStruct, embeddedPrefixes[i], seen)...)
+	}
+	return fields
+}
+
+func (g *generator) localStruct(name string) (*ast.StructType, bool) {
+	spec, ok := g.specs[name]
+	if !ok {
+		return nil, false
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	return st, ok
+}
+
+// hasLock returns whether the local struct contains a value of the sync package, which must not be copied.
+func (g *generator) hasLock(name string, depth int) bool {
+	st, ok := g.localStruct(name)
+	if !ok || depth > 10 {
+		return false
+	}
+	for _, field := range st.Fields.List {
+		switch v := field.Type.(type) {
+		case *ast.SelectorExpr:
+			if pkg, ok := v.X.(*ast.Ident); ok && pkg.Name == "sync" {
+				return true
+			}
+		case *ast.Ident:
+			if g.hasLock(v.Name, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var builtinBasics = map[string]string{
+	"string":  "string",
+	"bool":    "bool",
+	"int":     "int",
+	"int8":    "int8",
+	"int16":   "int16",
+	"int32":   "int32",
+	"int64":   "int64",
+	"uint":    "uint",
+	"uint8":   "uint8",
+	"uint16":  "uint16",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"float32": "float32",
+	"float64": "float64",
+	"byte":    "uint8",
+	"rune":    "int32",
+}
+
+// underlyingBasic resolves the builtin type of basic types, including local named types.
+func (g *generator) underlyingBasic(expr ast.Expr, depth int) (string, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || depth > 10 {
+		return "", false
+	}
+	if spec, ok := g.specs[ident.Name]; ok {
+		return g.underlyingBasic(spec.Type, depth+1)
+	}
+	basic, ok := builtinBasics[ident.Name]
+	return basic, ok
+}
+
+func (g *generator) classify(expr ast.Expr, depth int) *fieldType {
+	t := &fieldType{
+		kind: kindOpaque,
+		expr: types.ExprString(expr),
+	}
+	// types of other packages would need to be imported
+	if hasSelector(expr) || depth > 10 {
+		return t
+	}
+
+	if basic, ok := g.underlyingBasic(expr, 0); ok {
+		t.kind = kindBasic
+		t.id = exportedName(t.expr)
+		t.basic = basic
+		return t
+	}
+
+	switch v := expr.(type) {
+	case *ast.Ident:
+		t.id = exportedName(v.Name)
+		spec, ok := g.specs[v.Name]
+		if !ok {
+			// builtin interfaces, such as error
+			return t
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			t.kind = kindStruct
+			return t
+		}
+		// local named pointers, slices, arrays and maps
+		underlying := g.classify(spec.Type, depth+1)
+		switch underlying.kind {
+		case kindPointer, kindSlice, kindArray, kindMap:
+			t.kind = underlying.kind
+			t.length = underlying.length
+			t.key = underlying.key
+			t.elem = underlying.elem
+		}
+
+	case *ast.InterfaceType:
+		if len(v.Methods.List) == 0 {
+			t.id = "Interface"
+		}
+
+	case *ast.StarExpr:
+		t.elem = g.classify(v.X, depth+1)
+		if t.elem.id != "" {
+			t.kind = kindPointer
+			t.id = "Ptr" + t.elem.id
+		}
+
+	case *ast.ArrayType:
+		t.elem = g.classify(v.Elt, depth+1)
+		if t.elem.id == "" {
+			return t
+		}
+		if v.Len == nil {
+			t.kind = kindSlice
+			t.id = "Slice" + t.elem.id
+			return t
+		}
+		if length, ok := v.Len.(*ast.BasicLit); ok && length.Kind == token.INT {
+			t.kind = kindArray
+			t.length = length.Value
+			t.id = "Array" + length.Value + t.elem.id
+		}
+
+	case *ast.MapType:
+		t.key = g.classify(v.Key, depth+1)
+		t.elem = g.classify(v.Value, depth+1)
+		// maps are accessed with string keys
+		if t.key.kind == kindBasic && t.key.basic == "string" && t.elem.id != "" {
+			t.kind = kindMap
+			t.id = "Map" + t.key.id + t.elem.id
+		}
+	}
+
+	return t
+}
+
+func hasSelector(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if _, ok := n.(*ast.SelectorExpr); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func exportedName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// leafValue returns the expression that the getter returns for the value obj points to, or an empty string if the value is not of the requested type.
+func leafValue(get *getter, t *fieldType) string {
+	switch get.name {
+	case "Get":
+		return "*obj"
+	case "GetStringArray":
+		if t.kind == kindSlice && t.expr == "[]string" {
+			return "*obj"
+		}
+	case "GetString":
+		if t.kind == kindBasic && t.basic == "string" {
+			return convertValue(t, "*obj", "string")
+		}
+	case "GetInt":
+		if t.kind == kindBasic && (strings.HasPrefix(t.basic, "int") || strings.HasPrefix(t.basic, "uint")) {
+			return convertValue(t, "*obj", "int64")
+		}
+	case "GetFloat":
+		if t.kind == kindBasic && strings.HasPrefix(t.basic, "float") {
+			return convertValue(t, "*obj", "float64")
+		}
+	case "GetBool":
+		if t.kind == kindBasic && t.basic == "bool" {
+			return convertValue(t, "*obj", "bool")
+		}
+	}
+	return ""
+}
+
+// convertValue returns the expression that converts value from type t to the type to.
+func convertValue(t *fieldType, value, to string) string {
+	if t.expr == to {
+		return value
+	}
+	return to + "(" + value + ")"
+}
+
+// canYield returns whether the getter may find a value in the type or the types along its paths.
+func (g *generator) canYield(get *getter, t *fieldType, visited map[string]bool) bool {
+	if get.name == "Get" || t.kind == kindOpaque || leafValue(get, t) != "" {
+		return true
+	}
+
+	switch t.kind {
+	case kindStruct:
+		// recursive structs add nothing new
+		if visited[t.id] {
+			return false
+		}
+		visited[t.id] = true
+		for _, field := range g.structFields(t.expr) {
+			if g.canYield(get, field.t, visited) {
+				return true
+			}
+		}
+		return false
+	case kindPointer:
+		return g.canYield(get, t.elem, visited)
+	case kindSlice, kindArray, kindMap:
+		// the selector # returns the length
+		return get.name == "GetInt" || g.canYield(get, t.elem, visited)
+	default:
+		return false
+	}
+}
+
+// queue queues the generation of the function, if it was not generated yet, and returns its name.
+func (g *generator) queueFunc(get *getter, t *fieldType) string {
+	name := "accessor" + t.id + "Set"
+	if get != nil {
+		name = "accessor" + t.id + get.name
+	}
+	if !g.generated[name] {
+		g.generated[name] = true
+		g.queue = append(g.queue, queuedFunc{get: get, t: t})
+	}
+	return name
+}
+
+// getCall returns the expression that returns the result of the getter for the value ptr points to, following path.
+func (g *generator) getCall(get *getter, t *fieldType, ptr, path string) string {
+	switch {
+	case !g.canYield(get, t, make(map[string]bool)):
+		return get.zero + ", false"
+	case t.kind == kindOpaque:
+		return fmt.Sprintf("%s%s(%s, %s)", g.qualifier(), get.fallback, ptr, path)
+	default:
+		return fmt.Sprintf("%s(%s, %s)", g.queueFunc(get, t), ptr, path)
+	}
+}
+
+// setCall returns the expression that sets the value ptr points to, or the value found by following path.
+func (g *generator) setCall(t *fieldType, ptr, path string) string {
+	if t.kind == kindOpaque {
+		return fmt.Sprintf("%sSetField(%s, %s, key, value)", g.qualifier(), ptr, path)
+	}
+	return fmt.Sprintf("%s(%s, key, %s, value)", g.queueFunc(nil, t), ptr, path)
+}
+
+// mapKey returns the expression that converts the selector to the key type of the map.
+func mapKey(t *fieldType) string {
+	return convertValue(&fieldType{expr: "string"}, "selector", t.key.expr)
+}
+
+func (g *generator) generateGetter(get *getter, t *fieldType) {
+	q := g.qualifier()
+	failed := get.zero + ", false"
+	leaf := failed
+	if value := leafValue(get, t); value != "" {
+		leaf = value + ", true"
+	}
+	if get.name == "Get" && t.kind == kindStruct && g.hasLock(t.expr, 0) {
+		// locks must not be copied
+		leaf = q + "GetField(obj, \"\")"
+	}
+
+	g.printf("func accessor%s%s(obj *%s, path string) (%s, bool) {\n", t.id, get.name, t.expr, get.result)
+	if t.kind == kindBasic {
+		g.printf("if path != \"\" {\nreturn %s\n}\nreturn %s\n}\n\n", failed, leaf)
+		return
+	}
+	g.printf("if path == \"\" {\nreturn %s\n}\n", leaf)
+
+	switch t.kind {
+	case kindStruct:
+		var cases []structField
+		for _, field := range g.structFields(t.expr) {
+			if g.canYield(get, field.t, make(map[string]bool)) {
+				cases = append(cases, field)
+			}
+		}
+		if len(cases) > 0 {
+			g.printf("field, rest := %sSplitKey(path)\nswitch field {\n", q)
+			for _, field := range cases {
+				g.printf("case %q:\nreturn %s\n", field.name, g.getCall(get, field.t, "&"+field.expr, "rest"))
+			}
+			g.printf("}\n")
+		}
+
+	case kindPointer:
+		g.printf("if *obj == nil {\nreturn %s\n}\n", failed)
+		g.printf("return %s\n}\n\n", g.getCall(get, t.elem, "*obj", "path"))
+		return
+
+	case kindSlice, kindArray, kindMap:
+		countable := get.name == "Get" || get.name == "GetInt"
+		elemYields := g.canYield(get, t.elem, make(map[string]bool))
+		if countable || elemYields {
+			g.printf("selector, rest := %sSplitKey(path)\n", q)
+		}
+		if countable {
+			g.printf("if selector == \"#\" {\nif rest != \"\" {\nreturn %s\n}\n", failed)
+			if get.name == "GetInt" {
+				g.printf("return int64(len(*obj)), true\n}\n")
+			} else {
+				g.printf("return len(*obj), true\n}\n")
+			}
+		}
+		if elemYields {
+			if t.kind == kindMap {
+				g.printf("elem, ok := (*obj)[%s]\nif !ok {\nreturn %s\n}\n", mapKey(t), failed)
+				g.printf("return %s\n}\n\n", g.getCall(get, t.elem, "&elem", "rest"))
+				return
+			}
+			g.printf("index, ok := %sSliceIndex(selector, len(*obj))\nif !ok {\nreturn %s\n}\n", q, failed)
+			g.printf("return %s\n}\n\n", g.getCall(get, t.elem, "&(*obj)[index]", "rest"))
+			return
+		}
+	}
+
+	g.printf("return %s\n}\n\n", failed)
+}
+
+// generateSetter generates a function that sets the value obj points to, or the value found by following the path. Values are converted and missing pointers and maps are created like the StructAccessor does.
+func (g *generator) generateSetter(t *fieldType) {
+	q := g.qualifier()
+
+	g.printf("func accessor%sSet(obj *%s, key, path string, value interface{}) error {\n", t.id, t.expr)
+	if t.kind == kindBasic {
+		g.printf("if path != \"\" {\nreturn %sErrCannotSet(key)\n}\n", q)
+		switch t.basic {
+		case "string":
+			g.printf("v, err := %sConvertString(key, value)\n", q)
+		case "bool":
+			g.printf("v, err := %sConvertBool(key, value)\n", q)
+		case "int", "int8", "int16", "int32", "int64":
+			g.printf("v, err := %sConvertInt(key, value, %s)\n", q, bitSize(t.basic, "int"))
+		case "uint", "uint8", "uint16", "uint32", "uint64":
+			g.printf("v, err := %sConvertUint(key, value, %s)\n", q, bitSize(t.basic, "uint"))
+		case "float32", "float64":
+			g.printf("v, err := %sConvertFloat(key, value, %s)\n", q, bitSize(t.basic, "float"))
+		}
+		g.printf("if err != nil {\nreturn err\n}\n")
+		// the conversion functions return the widest type of the kind
+		converted := t.expr + "(v)"
+		switch t.expr {
+		case "string", "bool", "int64", "uint64", "float64":
+			converted = "v"
+		}
+		g.printf("*obj = %s\nreturn nil\n}\n\n", converted)
+		return
+	}
+
+	// values of the exact type are set directly, others are converted
+	g.printf("if path == \"\" {\n")
+	if t.kind != kindStruct || !g.hasLock(t.expr, 0) {
+		g.printf("if v, ok := value.(%s); ok {\n*obj = v\nreturn nil\n}\n", t.expr)
+	}
+	g.printf("return %sSetField(obj, \"\", key, value)\n}\n", q)
+
+	switch t.kind {
+	case kindStruct:
+		fields := g.structFields(t.expr)
+		if len(fields) > 0 {
+			g.printf("field, rest := %sSplitKey(path)\nswitch field {\n", q)
+			for _, field := range fields {
+				g.printf("case %q:\nreturn %s\n", field.name, g.setCall(field.t, "&"+field.expr, "rest"))
+			}
+			g.printf("}\n")
+		}
+		g.printf("return %sErrCannotSet(key)\n}\n\n", q)
+
+	case kindPointer:
+		g.printf("if *obj == nil {\n*obj = new(%s)\n}\n", t.elem.expr)
+		g.printf("return %s\n}\n\n", g.setCall(t.elem, "*obj", "path"))
+
+	case kindSlice, kindArray:
+		g.printf("selector, rest := %sSplitKey(path)\n", q)
+		if t.kind == kindSlice {
+			// the index -1 appends a new element
+			g.printf("if selector == \"-1\" {\nvar elem %s\n", t.elem.expr)
+			g.printf("err := %s\nif err != nil {\nreturn err\n}\n", g.setCall(t.elem, "&elem", "rest"))
+			g.printf("*obj = append(*obj, elem)\nreturn nil\n}\n")
+		}
+		g.printf("index, ok := %sSliceIndex(selector, len(*obj))\nif !ok {\nreturn %sErrCannotSet(key)\n}\n", q, q)
+		g.printf("return %s\n}\n\n", g.setCall(t.elem, "&(*obj)[index]", "rest"))
+
+	case kindMap:
+		// map values are not addressable, modify a copy and put it back
+		g.printf("selector, rest := %sSplitKey(path)\n", q)
+		g.printf("if *obj == nil {\n*obj = make(%s)\n}\n", t.expr)
+		g.printf("elem := (*obj)[%s]\n", mapKey(t))
+		g.printf("err := %s\nif err != nil {\nreturn err\n}\n", g.setCall(t.elem, "&elem", "rest"))
+		g.printf("(*obj)[%s] = elem\nreturn nil\n}\n\n", mapKey(t))
+	}
+}
+
+// bitSize returns the bit size of a builtin number type, 0 stands for the size of int and uint.
+func bitSize(basic, prefix string) string {
+	size := strings.TrimPrefix(basic, prefix)
+	if size == "" {
+		return "0"
+	}
+	return size
+}
@@ -0,0 +1,1628 @@
+// Code generated by accessorgen. DO NOT EDIT.
+
+package accessor
+
+// GeneratedAccessor returns a typed accessor for TestStruct.
+func (obj *TestStruct) GeneratedAccessor() Accessor {
+	return NewGeneratedAccessor(accessorFuncsTestStruct{obj})
+}
+
+// accessorFuncsTestStruct implements accessor.GeneratedFuncs for TestStruct.
+type accessorFuncsTestStruct struct {
+	obj *TestStruct
+}
+
+func (f accessorFuncsTestStruct) Get(key string) (interface{}, bool) {
+	return accessorTestStructGet(f.obj, key)
+}
+
+func (f accessorFuncsTestStruct) GetString(key string) (string, bool) {
+	return accessorTestStructGetString(f.obj, key)
+}
+
+func (f accessorFuncsTestStruct) GetStringArray(key string) ([]string, bool) {
+	return accessorTestStructGetStringArray(f.obj, key)
+}
+
+func (f accessorFuncsTestStruct) GetInt(key string) (int64, bool) {
+	return accessorTestStructGetInt(f.obj, key)
+}
+
+func (f accessorFuncsTestStruct) GetFloat(key string) (float64, bool) {
+	return accessorTestStructGetFloat(f.obj, key)
+}
+
+func (f accessorFuncsTestStruct) GetBool(key string) (bool, bool) {
+	return accessorTestStructGetBool(f.obj, key)
+}
+
+func (f accessorFuncsTestStruct) Set(key string, value interface{}) error {
+	return accessorTestStructSet(f.obj, key, key, value)
+}
+
+// GeneratedAccessor returns a typed accessor for TestNested.
+func (obj *TestNested) GeneratedAccessor() Accessor {
+	return NewGeneratedAccessor(accessorFuncsTestNested{obj})
+}
+
+// accessorFuncsTestNested implements accessor.GeneratedFuncs for TestNested.
+type accessorFuncsTestNested struct {
+	obj *TestNested
+}
+
+func (f accessorFuncsTestNested) Get(key string) (interface{}, bool) {
+	return accessorTestNestedGet(f.obj, key)
+}
+
+func (f accessorFuncsTestNested) GetString(key string) (string, bool) {
+	return accessorTestNestedGetString(f.obj, key)
+}
+
+func (f accessorFuncsTestNested) GetStringArray(key string) ([]string, bool) {
+	return accessorTestNestedGetStringArray(f.obj, key)
+}
+
+func (f accessorFuncsTestNested) GetInt(key string) (int64, bool) {
+	return accessorTestNestedGetInt(f.obj, key)
+}
+
+func (f accessorFuncsTestNested) GetFloat(key string) (float64, bool) {
+	return 0, false
+}
+
+func (f accessorFuncsTestNested) GetBool(key string) (bool, bool) {
+	return false, false
+}
+
+func (f accessorFuncsTestNested) Set(key string, value interface{}) error {
+	return accessorTestNestedSet(f.obj, key, key, value)
+}
+
+// GeneratedAccessor returns a typed accessor for TestPathStruct.
+func (obj *TestPathStruct) GeneratedAccessor() Accessor {
+	return NewGeneratedAccessor(accessorFuncsTestPathStruct{obj})
+}
+
+// accessorFuncsTestPathStruct implements accessor.GeneratedFuncs for TestPathStruct.
+type accessorFuncsTestPathStruct struct {
+	obj *TestPathStruct
+}
+
+func (f accessorFuncsTestPathStruct) Get(key string) (interface{}, bool) {
+	return accessorTestPathStructGet(f.obj, key)
+}
+
+func (f accessorFuncsTestPathStruct) GetString(key string) (string, bool) {
+	return accessorTestPathStructGetString(f.obj, key)
+}
+
+func (f accessorFuncsTestPathStruct) GetStringArray(key string) ([]string, bool) {
+	return accessorTestPathStructGetStringArray(f.obj, key)
+}
+
+func (f accessorFuncsTestPathStruct) GetInt(key string) (int64, bool) {
+	return accessorTestPathStructGetInt(f.obj, key)
+}
+
+func (f accessorFuncsTestPathStruct) GetFloat(key string) (float64, bool) {
+	return accessorTestPathStructGetFloat(f.obj, key)
+}
+
+func (f accessorFuncsTestPathStruct) GetBool(key string) (bool, bool) {
+	return accessorTestPathStructGetBool(f.obj, key)
+}
+
+func (f accessorFuncsTestPathStruct) Set(key string, value interface{}) error {
+	return accessorTestPathStructSet(f.obj, key, key, value)
+}
+
+func accessorTestStructGet(obj *TestStruct, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "S":
+		return accessorStringGet(&obj.S, rest)
+	case "A":
+		return accessorSliceStringGet(&obj.A, rest)
+	case "I":
+		return accessorIntGet(&obj.I, rest)
+	case "I8":
+		return accessorInt8Get(&obj.I8, rest)
+	case "I16":
+		return accessorInt16Get(&obj.I16, rest)
+	case "I32":
+		return accessorInt32Get(&obj.I32, rest)
+	case "I64":
+		return accessorInt64Get(&obj.I64, rest)
+	case "UI":
+		return accessorUintGet(&obj.UI, rest)
+	case "UI8":
+		return accessorUint8Get(&obj.UI8, rest)
+	case "UI16":
+		return accessorUint16Get(&obj.UI16, rest)
+	case "UI32":
+		return accessorUint32Get(&obj.UI32, rest)
+	case "UI64":
+		return accessorUint64Get(&obj.UI64, rest)
+	case "F32":
+		return accessorFloat32Get(&obj.F32, rest)
+	case "F64":
+		return accessorFloat64Get(&obj.F64, rest)
+	case "B":
+		return accessorBoolGet(&obj.B, rest)
+	}
+	return nil, false
+}
+
+func accessorTestStructGetString(obj *TestStruct, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "S":
+		return accessorStringGetString(&obj.S, rest)
+	case "A":
+		return accessorSliceStringGetString(&obj.A, rest)
+	}
+	return "", false
+}
+
+func accessorTestStructGetStringArray(obj *TestStruct, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "A":
+		return accessorSliceStringGetStringArray(&obj.A, rest)
+	}
+	return nil, false
+}
+
+func accessorTestStructGetInt(obj *TestStruct, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "A":
+		return accessorSliceStringGetInt(&obj.A, rest)
+	case "I":
+		return accessorIntGetInt(&obj.I, rest)
+	case "I8":
+		return accessorInt8GetInt(&obj.I8, rest)
+	case "I16":
+		return accessorInt16GetInt(&obj.I16, rest)
+	case "I32":
+		return accessorInt32GetInt(&obj.I32, rest)
+	case "I64":
+		return accessorInt64GetInt(&obj.I64, rest)
+	case "UI":
+		return accessorUintGetInt(&obj.UI, rest)
+	case "UI8":
+		return accessorUint8GetInt(&obj.UI8, rest)
+	case "UI16":
+		return accessorUint16GetInt(&obj.UI16, rest)
+	case "UI32":
+		return accessorUint32GetInt(&obj.UI32, rest)
+	case "UI64":
+		return accessorUint64GetInt(&obj.UI64, rest)
+	}
+	return 0, false
+}
+
+func accessorTestStructGetFloat(obj *TestStruct, path string) (float64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "F32":
+		return accessorFloat32GetFloat(&obj.F32, rest)
+	case "F64":
+		return accessorFloat64GetFloat(&obj.F64, rest)
+	}
+	return 0, false
+}
+
+func accessorTestStructGetBool(obj *TestStruct, path string) (bool, bool) {
+	if path == "" {
+		return false, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "B":
+		return accessorBoolGetBool(&obj.B, rest)
+	}
+	return false, false
+}
+
+func accessorTestStructSet(obj *TestStruct, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(TestStruct); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "S":
+		return accessorStringSet(&obj.S, key, rest, value)
+	case "A":
+		return accessorSliceStringSet(&obj.A, key, rest, value)
+	case "I":
+		return accessorIntSet(&obj.I, key, rest, value)
+	case "I8":
+		return accessorInt8Set(&obj.I8, key, rest, value)
+	case "I16":
+		return accessorInt16Set(&obj.I16, key, rest, value)
+	case "I32":
+		return accessorInt32Set(&obj.I32, key, rest, value)
+	case "I64":
+		return accessorInt64Set(&obj.I64, key, rest, value)
+	case "UI":
+		return accessorUintSet(&obj.UI, key, rest, value)
+	case "UI8":
+		return accessorUint8Set(&obj.UI8, key, rest, value)
+	case "UI16":
+		return accessorUint16Set(&obj.UI16, key, rest, value)
+	case "UI32":
+		return accessorUint32Set(&obj.UI32, key, rest, value)
+	case "UI64":
+		return accessorUint64Set(&obj.UI64, key, rest, value)
+	case "F32":
+		return accessorFloat32Set(&obj.F32, key, rest, value)
+	case "F64":
+		return accessorFloat64Set(&obj.F64, key, rest, value)
+	case "B":
+		return accessorBoolSet(&obj.B, key, rest, value)
+	}
+	return ErrCannotSet(key)
+}
+
+func accessorTestNestedGet(obj *TestNested, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringGet(&obj.Name, rest)
+	case "Level":
+		return accessorTestLevelGet(&obj.Level, rest)
+	case "Child":
+		return accessorTestChildGet(&obj.Child, rest)
+	case "Ptr":
+		return accessorPtrTestChildGet(&obj.Ptr, rest)
+	case "Children":
+		return accessorSliceTestChildGet(&obj.Children, rest)
+	case "Labels":
+		return accessorMapStringStringGet(&obj.Labels, rest)
+	case "Groups":
+		return accessorMapStringSliceStringGet(&obj.Groups, rest)
+	}
+	return nil, false
+}
+
+func accessorTestNestedGetString(obj *TestNested, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringGetString(&obj.Name, rest)
+	case "Child":
+		return accessorTestChildGetString(&obj.Child, rest)
+	case "Ptr":
+		return accessorPtrTestChildGetString(&obj.Ptr, rest)
+	case "Children":
+		return accessorSliceTestChildGetString(&obj.Children, rest)
+	case "Labels":
+		return accessorMapStringStringGetString(&obj.Labels, rest)
+	case "Groups":
+		return accessorMapStringSliceStringGetString(&obj.Groups, rest)
+	}
+	return "", false
+}
+
+func accessorTestNestedGetStringArray(obj *TestNested, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Child":
+		return accessorTestChildGetStringArray(&obj.Child, rest)
+	case "Ptr":
+		return accessorPtrTestChildGetStringArray(&obj.Ptr, rest)
+	case "Children":
+		return accessorSliceTestChildGetStringArray(&obj.Children, rest)
+	case "Groups":
+		return accessorMapStringSliceStringGetStringArray(&obj.Groups, rest)
+	}
+	return nil, false
+}
+
+func accessorTestNestedGetInt(obj *TestNested, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Level":
+		return accessorTestLevelGetInt(&obj.Level, rest)
+	case "Child":
+		return accessorTestChildGetInt(&obj.Child, rest)
+	case "Ptr":
+		return accessorPtrTestChildGetInt(&obj.Ptr, rest)
+	case "Children":
+		return accessorSliceTestChildGetInt(&obj.Children, rest)
+	case "Labels":
+		return accessorMapStringStringGetInt(&obj.Labels, rest)
+	case "Groups":
+		return accessorMapStringSliceStringGetInt(&obj.Groups, rest)
+	}
+	return 0, false
+}
+
+func accessorTestNestedSet(obj *TestNested, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(TestNested); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringSet(&obj.Name, key, rest, value)
+	case "Level":
+		return accessorTestLevelSet(&obj.Level, key, rest, value)
+	case "Child":
+		return accessorTestChildSet(&obj.Child, key, rest, value)
+	case "Ptr":
+		return accessorPtrTestChildSet(&obj.Ptr, key, rest, value)
+	case "Children":
+		return accessorSliceTestChildSet(&obj.Children, key, rest, value)
+	case "Labels":
+		return accessorMapStringStringSet(&obj.Labels, key, rest, value)
+	case "Groups":
+		return accessorMapStringSliceStringSet(&obj.Groups, key, rest, value)
+	}
+	return ErrCannotSet(key)
+}
+
+func accessorTestPathStructGet(obj *TestPathStruct, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Nested":
+		return accessorTestPathItemGet(&obj.Nested, rest)
+	case "Items":
+		return accessorSliceTestPathItemGet(&obj.Items, rest)
+	case "Labels":
+		return accessorMapStringStringGet(&obj.Labels, rest)
+	case "Ptr":
+		return accessorPtrTestPathItemGet(&obj.Ptr, rest)
+	case "Tags":
+		return accessorSliceStringGet(&obj.Tags, rest)
+	case "Any":
+		return accessorMapStringInterfaceGet(&obj.Any, rest)
+	}
+	return nil, false
+}
+
+func accessorTestPathStructGetString(obj *TestPathStruct, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Nested":
+		return accessorTestPathItemGetString(&obj.Nested, rest)
+	case "Items":
+		return accessorSliceTestPathItemGetString(&obj.Items, rest)
+	case "Labels":
+		return accessorMapStringStringGetString(&obj.Labels, rest)
+	case "Ptr":
+		return accessorPtrTestPathItemGetString(&obj.Ptr, rest)
+	case "Tags":
+		return accessorSliceStringGetString(&obj.Tags, rest)
+	case "Any":
+		return accessorMapStringInterfaceGetString(&obj.Any, rest)
+	}
+	return "", false
+}
+
+func accessorTestPathStructGetStringArray(obj *TestPathStruct, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Tags":
+		return accessorSliceStringGetStringArray(&obj.Tags, rest)
+	case "Any":
+		return accessorMapStringInterfaceGetStringArray(&obj.Any, rest)
+	}
+	return nil, false
+}
+
+func accessorTestPathStructGetInt(obj *TestPathStruct, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Nested":
+		return accessorTestPathItemGetInt(&obj.Nested, rest)
+	case "Items":
+		return accessorSliceTestPathItemGetInt(&obj.Items, rest)
+	case "Labels":
+		return accessorMapStringStringGetInt(&obj.Labels, rest)
+	case "Ptr":
+		return accessorPtrTestPathItemGetInt(&obj.Ptr, rest)
+	case "Tags":
+		return accessorSliceStringGetInt(&obj.Tags, rest)
+	case "Any":
+		return accessorMapStringInterfaceGetInt(&obj.Any, rest)
+	}
+	return 0, false
+}
+
+func accessorTestPathStructGetFloat(obj *TestPathStruct, path string) (float64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Any":
+		return accessorMapStringInterfaceGetFloat(&obj.Any, rest)
+	}
+	return 0, false
+}
+
+func accessorTestPathStructGetBool(obj *TestPathStruct, path string) (bool, bool) {
+	if path == "" {
+		return false, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Any":
+		return accessorMapStringInterfaceGetBool(&obj.Any, rest)
+	}
+	return false, false
+}
+
+func accessorTestPathStructSet(obj *TestPathStruct, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(TestPathStruct); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Nested":
+		return accessorTestPathItemSet(&obj.Nested, key, rest, value)
+	case "Items":
+		return accessorSliceTestPathItemSet(&obj.Items, key, rest, value)
+	case "Labels":
+		return accessorMapStringStringSet(&obj.Labels, key, rest, value)
+	case "Ptr":
+		return accessorPtrTestPathItemSet(&obj.Ptr, key, rest, value)
+	case "Tags":
+		return accessorSliceStringSet(&obj.Tags, key, rest, value)
+	case "Any":
+		return accessorMapStringInterfaceSet(&obj.Any, key, rest, value)
+	}
+	return ErrCannotSet(key)
+}
+
+func accessorStringGet(obj *string, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorSliceStringGet(obj *[]string, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return nil, false
+		}
+		return len(*obj), true
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return nil, false
+	}
+	return accessorStringGet(&(*obj)[index], rest)
+}
+
+func accessorIntGet(obj *int, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorInt8Get(obj *int8, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorInt16Get(obj *int16, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorInt32Get(obj *int32, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorInt64Get(obj *int64, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorUintGet(obj *uint, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorUint8Get(obj *uint8, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorUint16Get(obj *uint16, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorUint32Get(obj *uint32, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorUint64Get(obj *uint64, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorFloat32Get(obj *float32, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorFloat64Get(obj *float64, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorBoolGet(obj *bool, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorStringGetString(obj *string, path string) (string, bool) {
+	if path != "" {
+		return "", false
+	}
+	return *obj, true
+}
+
+func accessorSliceStringGetString(obj *[]string, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	selector, rest := SplitKey(path)
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return "", false
+	}
+	return accessorStringGetString(&(*obj)[index], rest)
+}
+
+func accessorSliceStringGetStringArray(obj *[]string, path string) ([]string, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	return nil, false
+}
+
+func accessorSliceStringGetInt(obj *[]string, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return 0, false
+		}
+		return int64(len(*obj)), true
+	}
+	return 0, false
+}
+
+func accessorIntGetInt(obj *int, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorInt8GetInt(obj *int8, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorInt16GetInt(obj *int16, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorInt32GetInt(obj *int32, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorInt64GetInt(obj *int64, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return *obj, true
+}
+
+func accessorUintGetInt(obj *uint, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorUint8GetInt(obj *uint8, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorUint16GetInt(obj *uint16, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorUint32GetInt(obj *uint32, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorUint64GetInt(obj *uint64, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorFloat32GetFloat(obj *float32, path string) (float64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return float64(*obj), true
+}
+
+func accessorFloat64GetFloat(obj *float64, path string) (float64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return *obj, true
+}
+
+func accessorBoolGetBool(obj *bool, path string) (bool, bool) {
+	if path != "" {
+		return false, false
+	}
+	return *obj, true
+}
+
+func accessorStringSet(obj *string, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertString(key, value)
+	if err != nil {
+		return err
+	}
+	*obj = v
+	return nil
+}
+
+func accessorSliceStringSet(obj *[]string, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.([]string); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	selector, rest := SplitKey(path)
+	if selector == "-1" {
+		var elem string
+		err := accessorStringSet(&elem, key, rest, value)
+		if err != nil {
+			return err
+		}
+		*obj = append(*obj, elem)
+		return nil
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return ErrCannotSet(key)
+	}
+	return accessorStringSet(&(*obj)[index], key, rest, value)
+}
+
+func accessorIntSet(obj *int, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertInt(key, value, 0)
+	if err != nil {
+		return err
+	}
+	*obj = int(v)
+	return nil
+}
+
+func accessorInt8Set(obj *int8, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertInt(key, value, 8)
+	if err != nil {
+		return err
+	}
+	*obj = int8(v)
+	return nil
+}
+
+func accessorInt16Set(obj *int16, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertInt(key, value, 16)
+	if err != nil {
+		return err
+	}
+	*obj = int16(v)
+	return nil
+}
+
+func accessorInt32Set(obj *int32, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertInt(key, value, 32)
+	if err != nil {
+		return err
+	}
+	*obj = int32(v)
+	return nil
+}
+
+func accessorInt64Set(obj *int64, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertInt(key, value, 64)
+	if err != nil {
+		return err
+	}
+	*obj = v
+	return nil
+}
+
+func accessorUintSet(obj *uint, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertUint(key, value, 0)
+	if err != nil {
+		return err
+	}
+	*obj = uint(v)
+	return nil
+}
+
+func accessorUint8Set(obj *uint8, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertUint(key, value, 8)
+	if err != nil {
+		return err
+	}
+	*obj = uint8(v)
+	return nil
+}
+
+func accessorUint16Set(obj *uint16, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertUint(key, value, 16)
+	if err != nil {
+		return err
+	}
+	*obj = uint16(v)
+	return nil
+}
+
+func accessorUint32Set(obj *uint32, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertUint(key, value, 32)
+	if err != nil {
+		return err
+	}
+	*obj = uint32(v)
+	return nil
+}
+
+func accessorUint64Set(obj *uint64, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertUint(key, value, 64)
+	if err != nil {
+		return err
+	}
+	*obj = v
+	return nil
+}
+
+func accessorFloat32Set(obj *float32, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertFloat(key, value, 32)
+	if err != nil {
+		return err
+	}
+	*obj = float32(v)
+	return nil
+}
+
+func accessorFloat64Set(obj *float64, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertFloat(key, value, 64)
+	if err != nil {
+		return err
+	}
+	*obj = v
+	return nil
+}
+
+func accessorBoolSet(obj *bool, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertBool(key, value)
+	if err != nil {
+		return err
+	}
+	*obj = v
+	return nil
+}
+
+func accessorTestLevelGet(obj *TestLevel, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorTestChildGet(obj *TestChild, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Value":
+		return accessorIntGet(&obj.Value, rest)
+	case "Tags":
+		return accessorSliceStringGet(&obj.Tags, rest)
+	}
+	return nil, false
+}
+
+func accessorPtrTestChildGet(obj **TestChild, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	if *obj == nil {
+		return nil, false
+	}
+	return accessorTestChildGet(*obj, path)
+}
+
+func accessorSliceTestChildGet(obj *[]TestChild, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return nil, false
+		}
+		return len(*obj), true
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return nil, false
+	}
+	return accessorTestChildGet(&(*obj)[index], rest)
+}
+
+func accessorMapStringStringGet(obj *map[string]string, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return nil, false
+		}
+		return len(*obj), true
+	}
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return nil, false
+	}
+	return accessorStringGet(&elem, rest)
+}
+
+func accessorMapStringSliceStringGet(obj *map[string][]string, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return nil, false
+		}
+		return len(*obj), true
+	}
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return nil, false
+	}
+	return accessorSliceStringGet(&elem, rest)
+}
+
+func accessorTestChildGetString(obj *TestChild, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Tags":
+		return accessorSliceStringGetString(&obj.Tags, rest)
+	}
+	return "", false
+}
+
+func accessorPtrTestChildGetString(obj **TestChild, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	if *obj == nil {
+		return "", false
+	}
+	return accessorTestChildGetString(*obj, path)
+}
+
+func accessorSliceTestChildGetString(obj *[]TestChild, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	selector, rest := SplitKey(path)
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return "", false
+	}
+	return accessorTestChildGetString(&(*obj)[index], rest)
+}
+
+func accessorMapStringStringGetString(obj *map[string]string, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return "", false
+	}
+	return accessorStringGetString(&elem, rest)
+}
+
+func accessorMapStringSliceStringGetString(obj *map[string][]string, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return "", false
+	}
+	return accessorSliceStringGetString(&elem, rest)
+}
+
+func accessorTestChildGetStringArray(obj *TestChild, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Tags":
+		return accessorSliceStringGetStringArray(&obj.Tags, rest)
+	}
+	return nil, false
+}
+
+func accessorPtrTestChildGetStringArray(obj **TestChild, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	if *obj == nil {
+		return nil, false
+	}
+	return accessorTestChildGetStringArray(*obj, path)
+}
+
+func accessorSliceTestChildGetStringArray(obj *[]TestChild, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	selector, rest := SplitKey(path)
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return nil, false
+	}
+	return accessorTestChildGetStringArray(&(*obj)[index], rest)
+}
+
+func accessorMapStringSliceStringGetStringArray(obj *map[string][]string, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return nil, false
+	}
+	return accessorSliceStringGetStringArray(&elem, rest)
+}
+
+func accessorTestLevelGetInt(obj *TestLevel, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorTestChildGetInt(obj *TestChild, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Value":
+		return accessorIntGetInt(&obj.Value, rest)
+	case "Tags":
+		return accessorSliceStringGetInt(&obj.Tags, rest)
+	}
+	return 0, false
+}
+
+func accessorPtrTestChildGetInt(obj **TestChild, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	if *obj == nil {
+		return 0, false
+	}
+	return accessorTestChildGetInt(*obj, path)
+}
+
+func accessorSliceTestChildGetInt(obj *[]TestChild, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return 0, false
+		}
+		return int64(len(*obj)), true
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return 0, false
+	}
+	return accessorTestChildGetInt(&(*obj)[index], rest)
+}
+
+func accessorMapStringStringGetInt(obj *map[string]string, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return 0, false
+		}
+		return int64(len(*obj)), true
+	}
+	return 0, false
+}
+
+func accessorMapStringSliceStringGetInt(obj *map[string][]string, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return 0, false
+		}
+		return int64(len(*obj)), true
+	}
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return 0, false
+	}
+	return accessorSliceStringGetInt(&elem, rest)
+}
+
+func accessorTestLevelSet(obj *TestLevel, key, path string, value interface{}) error {
+	if path != "" {
+		return ErrCannotSet(key)
+	}
+	v, err := ConvertInt(key, value, 8)
+	if err != nil {
+		return err
+	}
+	*obj = TestLevel(v)
+	return nil
+}
+
+func accessorTestChildSet(obj *TestChild, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(TestChild); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Value":
+		return accessorIntSet(&obj.Value, key, rest, value)
+	case "Tags":
+		return accessorSliceStringSet(&obj.Tags, key, rest, value)
+	}
+	return ErrCannotSet(key)
+}
+
+func accessorPtrTestChildSet(obj **TestChild, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(*TestChild); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	if *obj == nil {
+		*obj = new(TestChild)
+	}
+	return accessorTestChildSet(*obj, key, path, value)
+}
+
+func accessorSliceTestChildSet(obj *[]TestChild, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.([]TestChild); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	selector, rest := SplitKey(path)
+	if selector == "-1" {
+		var elem TestChild
+		err := accessorTestChildSet(&elem, key, rest, value)
+		if err != nil {
+			return err
+		}
+		*obj = append(*obj, elem)
+		return nil
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return ErrCannotSet(key)
+	}
+	return accessorTestChildSet(&(*obj)[index], key, rest, value)
+}
+
+func accessorMapStringStringSet(obj *map[string]string, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(map[string]string); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	selector, rest := SplitKey(path)
+	if *obj == nil {
+		*obj = make(map[string]string)
+	}
+	elem := (*obj)[selector]
+	err := accessorStringSet(&elem, key, rest, value)
+	if err != nil {
+		return err
+	}
+	(*obj)[selector] = elem
+	return nil
+}
+
+func accessorMapStringSliceStringSet(obj *map[string][]string, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(map[string][]string); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	selector, rest := SplitKey(path)
+	if *obj == nil {
+		*obj = make(map[string][]string)
+	}
+	elem := (*obj)[selector]
+	err := accessorSliceStringSet(&elem, key, rest, value)
+	if err != nil {
+		return err
+	}
+	(*obj)[selector] = elem
+	return nil
+}
+
+func accessorTestPathItemGet(obj *TestPathItem, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringGet(&obj.Name, rest)
+	case "Value":
+		return accessorIntGet(&obj.Value, rest)
+	}
+	return nil, false
+}
+
+func accessorSliceTestPathItemGet(obj *[]TestPathItem, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return nil, false
+		}
+		return len(*obj), true
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return nil, false
+	}
+	return accessorTestPathItemGet(&(*obj)[index], rest)
+}
+
+func accessorPtrTestPathItemGet(obj **TestPathItem, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	if *obj == nil {
+		return nil, false
+	}
+	return accessorTestPathItemGet(*obj, path)
+}
+
+func accessorMapStringInterfaceGet(obj *map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return *obj, true
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return nil, false
+		}
+		return len(*obj), true
+	}
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return nil, false
+	}
+	return GetField(&elem, rest)
+}
+
+func accessorTestPathItemGetString(obj *TestPathItem, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringGetString(&obj.Name, rest)
+	}
+	return "", false
+}
+
+func accessorSliceTestPathItemGetString(obj *[]TestPathItem, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	selector, rest := SplitKey(path)
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return "", false
+	}
+	return accessorTestPathItemGetString(&(*obj)[index], rest)
+}
+
+func accessorPtrTestPathItemGetString(obj **TestPathItem, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	if *obj == nil {
+		return "", false
+	}
+	return accessorTestPathItemGetString(*obj, path)
+}
+
+func accessorMapStringInterfaceGetString(obj *map[string]interface{}, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return "", false
+	}
+	return GetFieldString(&elem, rest)
+}
+
+func accessorMapStringInterfaceGetStringArray(obj *map[string]interface{}, path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return nil, false
+	}
+	return GetFieldStringArray(&elem, rest)
+}
+
+func accessorTestPathItemGetInt(obj *TestPathItem, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Value":
+		return accessorIntGetInt(&obj.Value, rest)
+	}
+	return 0, false
+}
+
+func accessorSliceTestPathItemGetInt(obj *[]TestPathItem, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return 0, false
+		}
+		return int64(len(*obj)), true
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return 0, false
+	}
+	return accessorTestPathItemGetInt(&(*obj)[index], rest)
+}
+
+func accessorPtrTestPathItemGetInt(obj **TestPathItem, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	if *obj == nil {
+		return 0, false
+	}
+	return accessorTestPathItemGetInt(*obj, path)
+}
+
+func accessorMapStringInterfaceGetInt(obj *map[string]interface{}, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	if selector == "#" {
+		if rest != "" {
+			return 0, false
+		}
+		return int64(len(*obj)), true
+	}
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return 0, false
+	}
+	return GetFieldInt(&elem, rest)
+}
+
+func accessorMapStringInterfaceGetFloat(obj *map[string]interface{}, path string) (float64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return 0, false
+	}
+	return GetFieldFloat(&elem, rest)
+}
+
+func accessorMapStringInterfaceGetBool(obj *map[string]interface{}, path string) (bool, bool) {
+	if path == "" {
+		return false, false
+	}
+	selector, rest := SplitKey(path)
+	elem, ok := (*obj)[selector]
+	if !ok {
+		return false, false
+	}
+	return GetFieldBool(&elem, rest)
+}
+
+func accessorTestPathItemSet(obj *TestPathItem, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(TestPathItem); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	field, rest := SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringSet(&obj.Name, key, rest, value)
+	case "Value":
+		return accessorIntSet(&obj.Value, key, rest, value)
+	}
+	return ErrCannotSet(key)
+}
+
+func accessorSliceTestPathItemSet(obj *[]TestPathItem, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.([]TestPathItem); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	selector, rest := SplitKey(path)
+	if selector == "-1" {
+		var elem TestPathItem
+		err := accessorTestPathItemSet(&elem, key, rest, value)
+		if err != nil {
+			return err
+		}
+		*obj = append(*obj, elem)
+		return nil
+	}
+	index, ok := SliceIndex(selector, len(*obj))
+	if !ok {
+		return ErrCannotSet(key)
+	}
+	return accessorTestPathItemSet(&(*obj)[index], key, rest, value)
+}
+
+func accessorPtrTestPathItemSet(obj **TestPathItem, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(*TestPathItem); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	if *obj == nil {
+		*obj = new(TestPathItem)
+	}
+	return accessorTestPathItemSet(*obj, key, path, value)
+}
+
+func accessorMapStringInterfaceSet(obj *map[string]interface{}, key, path string, value interface{}) error {
+	if path == "" {
+		if v, ok := value.(map[string]interface{}); ok {
+			*obj = v
+			return nil
+		}
+		return SetField(obj, "", key, value)
+	}
+	selector, rest := SplitKey(path)
+	if *obj == nil {
+		*obj = make(map[string]interface{})
+	}
+	elem := (*obj)[selector]
+	err := SetField(&elem, rest, key, value)
+	if err != nil {
+		return err
+	}
+	(*obj)[selector] = elem
+	return nil
+}
@@ -7,6 +7,8 @@
 	"github.com/safing/portbase/database/record"
 )
 
+//go:generate go run ./accessor/accessorgen -type Example
+
 type Example struct {
 	record.Base
 	sync.Mutex
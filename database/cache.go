@@ -0,0 +1,225 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluele/gcache"
+
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/log"
+)
+
+// recordCache holds the records of a controller that were recently used, and, in write-behind mode, the records that were not yet written to the storage. All records are held as snapshots that are never handed out, every user gets its own copy.
+type recordCache struct {
+	lock sync.Mutex
+
+	// records holds recently used records, it is nil if the cache is disabled.
+	records gcache.Cache
+	// generation is increased with every write, in order to detect records that were loaded from the storage while a write happened.
+	generation uint64
+
+	writeBehind bool
+	dirty       map[string]*dirtyRecord
+	dirtySeq    uint64
+	// flushLock prevents concurrent flushes from writing versions of a record out of order.
+	flushLock sync.Mutex
+}
+
+// dirtyRecord is a record waiting to be flushed. It is a snapshot of the record as it was when it was written, so that flushing does not need to lock records.
+type dirtyRecord struct {
+	r   *record.Wrapper
+	seq uint64
+}
+
+// snapshotRecord returns an immutable copy of the record. The caller must hold the record lock.
+func snapshotRecord(r record.Record) (*record.Wrapper, error) {
+	if w, ok := r.(*record.Wrapper); ok {
+		return w.Copy(), nil
+	}
+
+	data, err := r.MarshalRecord(r)
+	if err != nil {
+		return nil, err
+	}
+	return record.NewRawWrapper(r.DatabaseName(), r.DatabaseKey(), data)
+}
+
+// enableCache enables the record cache of the controller, if a cache size or a write-behind interval is set. It must be called before the controller is used.
+func (c *Controller) enableCache(size int, writeBehindInterval time.Duration) {
+	if size <= 0 && writeBehindInterval <= 0 {
+		return
+	}
+
+	c.cache = &recordCache{
+		writeBehind: writeBehindInterval > 0,
+		dirty:       make(map[string]*dirtyRecord),
+	}
+	if size > 0 {
+		c.cache.records = gcache.New(size).ARC().Build()
+	}
+	if writeBehindInterval > 0 {
+		go c.flusher(writeBehindInterval)
+	}
+}
+
+// get returns a copy of the record with the given database key, if it is cached or waiting to be flushed.
+func (rc *recordCache) get(dbKey string) (record.Record, bool) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	if rc.records != nil {
+		cacheVal, err := rc.records.Get(dbKey)
+		if err == nil {
+			return cacheVal.(*record.Wrapper).Copy(), true
+		}
+	}
+	if entry, ok := rc.dirty[dbKey]; ok {
+		return entry.r.Copy(), true
+	}
+	return nil, false
+}
+
+// currentGeneration must be called before loading a record from the storage that is then added with add.
+func (rc *recordCache) currentGeneration() uint64 {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	return rc.generation
+}
+
+// add caches a snapshot of a record loaded from the storage, unless a write happened since generation was retrieved. The caller must hold the record lock.
+func (rc *recordCache) add(r record.Record, generation uint64) {
+	if rc.records == nil {
+		return
+	}
+	snapshot, err := snapshotRecord(r)
+	if err != nil {
+		log.Warningf("database: failed to cache record %s: %s", r.Key(), err)
+		return
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	if rc.generation == generation {
+		_ = rc.records.Set(r.DatabaseKey(), snapshot)
+	}
+}
+
+// invalidate removes a record that was changed in the storage from the cache. Dirty records are kept.
+func (rc *recordCache) invalidate(dbKey string) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.generation++
+	if rc.records != nil {
+		rc.records.Remove(dbKey)
+	}
+}
+
+// purge removes all records from the cache. Dirty records are kept.
+func (rc *recordCache) purge() {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.generation++
+	if rc.records != nil {
+		rc.records.Purge()
+	}
+}
+
+// markDirty caches a record that must be written to the storage with the next flush. A dirty record replaces any earlier dirty version. The caller must hold the record lock.
+func (rc *recordCache) markDirty(r record.Record) error {
+	data, err := r.MarshalRecord(r)
+	if err != nil {
+		return err
+	}
+	snapshot, err := record.NewRawWrapper(r.DatabaseName(), r.DatabaseKey(), data)
+	if err != nil {
+		return err
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.generation++
+	rc.dirtySeq++
+	rc.dirty[r.DatabaseKey()] = &dirtyRecord{
+		r:   snapshot,
+		seq: rc.dirtySeq,
+	}
+	if rc.records != nil {
+		_ = rc.records.Set(r.DatabaseKey(), snapshot)
+	}
+	return nil
+}
+
+// dirtyRecords returns the records waiting to be flushed. They stay dirty until marked as flushed.
+func (rc *recordCache) dirtyRecords() []*dirtyRecord {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	entries := make([]*dirtyRecord, 0, len(rc.dirty))
+	for _, entry := range rc.dirty {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// flushed removes a record from the dirty records, unless it was written again in the meantime.
+func (rc *recordCache) flushed(entry *dirtyRecord) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	dbKey := entry.r.DatabaseKey()
+	if current, ok := rc.dirty[dbKey]; ok && current.seq == entry.seq {
+		delete(rc.dirty, dbKey)
+	}
+}
+
+// flush writes all dirty records to the storage. The caller must hold the writeLock.
+func (c *Controller) flush() (err error) {
+	if c.cache == nil || !c.cache.writeBehind {
+		return nil
+	}
+
+	c.cache.flushLock.Lock()
+	defer c.cache.flushLock.Unlock()
+
+	for _, entry := range c.cache.dirtyRecords() {
+		putErr := c.storage.Put(entry.r)
+		if putErr != nil {
+			// keep the record dirty and try again with the next flush
+			err = putErr
+			continue
+		}
+		c.cache.flushed(entry)
+	}
+	return err
+}
+
+// flusher flushes the dirty records in the given interval until the controller is stopped.
+func (c *Controller) flusher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-shutdownSignal:
+			return
+		}
+
+		c.writeLock.RLock()
+		if c.stopped() {
+			c.writeLock.RUnlock()
+			return
+		}
+		err := c.flush()
+		c.writeLock.RUnlock()
+		if err != nil {
+			log.Warningf("database: failed to flush records: %s", err)
+		}
+	}
+}
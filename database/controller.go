@@ -1,7 +1,11 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/tevino/abool"
 
@@ -9,14 +13,20 @@
 	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
 	"github.com/safing/portbase/database/storage"
+	"github.com/safing/portbase/log"
 )
 
 // A Controller takes care of all the extra database logic.
 type Controller struct {
+	lastUsed int64 // unix timestamp, accessed atomically, must be first for alignment
+
 	storage storage.Interface
 
 	hooks         []*RegisteredHook
 	subscriptions []*Subscription
+	search        *searchIndex
+	cache         *recordCache
+	quota         *quotaTracker
 
 	writeLock sync.RWMutex
 	//  Lock: nobody may write
@@ -25,19 +35,51 @@ type Controller struct {
 	//  Lock: nobody may read
 	// RLock: concurrent reading
 
+	signed      *abool.AtomicBool // all records must be signed
 	migrating   *abool.AtomicBool // TODO
 	hibernating *abool.AtomicBool // TODO
+	unloaded    *abool.AtomicBool
 }
 
 // newController creates a new controller for a storage.
 func newController(storageInt storage.Interface) *Controller {
 	return &Controller{
 		storage:     storageInt,
+		lastUsed:    time.Now().Unix(),
+		signed:      abool.NewBool(false),
 		migrating:   abool.NewBool(false),
 		hibernating: abool.NewBool(false),
+		unloaded:    abool.NewBool(false),
 	}
 }
 
+// stopped returns whether the controller may not be used anymore, because the database system is shutting down or the database was unloaded.
+func (c *Controller) stopped() bool {
+	return shuttingDown.IsSet() || c.unloaded.IsSet()
+}
+
+// stoppedErr returns ErrShuttingDown or ErrDatabaseUnloaded, if the controller may not be used anymore.
+func (c *Controller) stoppedErr() error {
+	switch {
+	case shuttingDown.IsSet():
+		return ErrShuttingDown
+	case c.unloaded.IsSet():
+		return ErrDatabaseUnloaded
+	default:
+		return nil
+	}
+}
+
+// touch marks the controller as used now.
+func (c *Controller) touch() {
+	atomic.StoreInt64(&c.lastUsed, time.Now().Unix())
+}
+
+// LastUsed returns when the controller was last requested.
+func (c *Controller) LastUsed() time.Time {
+	return time.Unix(atomic.LoadInt64(&c.lastUsed), 0)
+}
+
 // ReadOnly returns whether the storage is read only.
 func (c *Controller) ReadOnly() bool {
 	return c.storage.ReadOnly()
@@ -53,8 +95,8 @@ func (c *Controller) Get(key string) (record.Record, error) {
 	c.readLock.RLock()
 	defer c.readLock.RUnlock()
 
-	if shuttingDown.IsSet() {
-		return nil, ErrShuttingDown
+	if err := c.stoppedErr(); err != nil {
+		return nil, err
 	}
 
 	// process hooks
@@ -67,13 +109,38 @@ func (c *Controller) Get(key string) (record.Record, error) {
 		}
 	}
 
-	r, err := c.storage.Get(key)
-	if err != nil {
-		// replace not found error
-		if err == storage.ErrNotFound {
-			return nil, ErrNotFound
+	var r record.Record
+	var cached bool
+	var err error
+	if c.cache != nil {
+		r, cached = c.cache.get(key)
+	}
+
+	if !cached {
+		var generation uint64
+		if c.cache != nil {
+			generation = c.cache.currentGeneration()
+		}
+
+		r, err = c.storage.Get(key)
+		if err != nil {
+			// replace not found error
+			if err == storage.ErrNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+
+		// verify signed records before anything else sees them
+		if !c.verified(r) {
+			return nil, ErrInvalidSignature
+		}
+
+		if c.cache != nil {
+			r.Lock()
+			c.cache.add(r, generation)
+			r.Unlock()
 		}
-		return nil, err
 	}
 
 	r.Lock()
@@ -101,8 +168,43 @@ func (c *Controller) Put(r record.Record) (err error) {
 	c.writeLock.RLock()
 	defer c.writeLock.RUnlock()
 
-	if shuttingDown.IsSet() {
-		return ErrShuttingDown
+	return c.put(r)
+}
+
+// PutIf saves a record in the database, if cond returns true for the currently stored version of the record. The stored version is nil if there is none, and it may be deleted or expired. No other writes happen in between.
+func (c *Controller) PutIf(r record.Record, cond func(stored record.Record) bool) (saved bool, err error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if err := c.stoppedErr(); err != nil {
+		return false, err
+	}
+
+	var stored record.Record
+	var cached bool
+	if c.cache != nil {
+		stored, cached = c.cache.get(r.DatabaseKey())
+	}
+	if !cached {
+		stored, err = c.storage.Get(r.DatabaseKey())
+	}
+	switch {
+	case err == storage.ErrNotFound:
+		stored = nil
+	case err != nil:
+		return false, err
+	}
+
+	if !cond(stored) {
+		return false, nil
+	}
+	return true, c.put(r)
+}
+
+// put saves a record in the database. The caller must hold the writeLock.
+func (c *Controller) put(r record.Record) (err error) {
+	if err := c.stoppedErr(); err != nil {
+		return err
 	}
 
 	if c.ReadOnly() {
@@ -119,18 +221,42 @@ func (c *Controller) Put(r record.Record) (err error) {
 		}
 	}
 
-	err = c.storage.Put(r)
+	if c.signed.IsSet() {
+		r.Meta().MakeSigned()
+	}
+
+	var revertQuota func()
+	if c.quota != nil {
+		revertQuota, err = c.quota.reserve(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.cache != nil && c.cache.writeBehind {
+		err = c.cache.markDirty(r)
+	} else {
+		err = c.storage.Put(r)
+		if c.cache != nil {
+			c.cache.invalidate(r.DatabaseKey())
+		}
+	}
 	if err != nil {
+		if revertQuota != nil {
+			revertQuota()
+		}
 		return err
 	}
 
+	// update search index
+	if c.search != nil {
+		c.search.update(r)
+	}
+
 	// process subscriptions
 	for _, sub := range c.subscriptions {
 		if r.Meta().CheckPermission(sub.local, sub.internal) && sub.q.Matches(r) {
-			select {
-			case sub.Feed <- r:
-			default:
-			}
+			sub.push(r)
 		}
 	}
 
@@ -139,11 +265,22 @@ func (c *Controller) Put(r record.Record) (err error) {
 
 // Query executes the given query on the database.
 func (c *Controller) Query(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
+	// the storage must see all records
+	err := c.flushForRead()
+	if err != nil {
+		return nil, err
+	}
+
 	c.readLock.RLock()
 
-	if shuttingDown.IsSet() {
+	err = c.stoppedErr()
+	if err != nil {
 		c.readLock.RUnlock()
-		return nil, ErrShuttingDown
+		return nil, err
+	}
+
+	if q.HasSearch() {
+		return c.searchQuery(q, local, internal)
 	}
 
 	it, err := c.storage.Query(q, local, internal)
@@ -152,42 +289,144 @@ func (c *Controller) Query(q *query.Query, local, internal bool) (*iterator.Iter
 		return nil, err
 	}
 
+	go c.readUnlockerAfterQuery(it)
+	return it.Filter(c.verified), nil
+}
+
+// flushForRead flushes dirty records before reading from the storage directly.
+func (c *Controller) flushForRead() error {
+	if c.cache == nil || !c.cache.writeBehind {
+		return nil
+	}
+
+	c.writeLock.RLock()
+	defer c.writeLock.RUnlock()
+
+	if err := c.stoppedErr(); err != nil {
+		return err
+	}
+	err := c.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %s", err)
+	}
+	return nil
+}
+
+// searchQuery executes a full-text search query using the search index. The read lock must be held and is released when the query is done.
+func (c *Controller) searchQuery(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
+	if c.search == nil {
+		c.readLock.RUnlock()
+		return nil, ErrSearchNotEnabled
+	}
+
+	err := c.search.build(c.storage)
+	if err != nil {
+		c.readLock.RUnlock()
+		return nil, fmt.Errorf("failed to build search index: %s", err)
+	}
+
+	it := iterator.New()
+	go c.searchExecutor(it, q, c.search.find(q.DatabaseKeyPrefix(), q.SearchTerms()), local, internal)
 	go c.readUnlockerAfterQuery(it)
 	return it, nil
 }
 
+func (c *Controller) searchExecutor(it *iterator.Iterator, q *query.Query, dbKeys []string, local, internal bool) {
+	for _, dbKey := range dbKeys {
+		r, err := c.storage.Get(dbKey)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			it.Finish(err)
+			return
+		}
+		it.CountScanned()
+
+		if !c.verified(r) {
+			it.CountSkippedValidity()
+			continue
+		}
+		if !r.Meta().CheckValidity() {
+			it.CountSkippedValidity()
+			continue
+		}
+		if !r.Meta().CheckPermission(local, internal) {
+			it.CountSkippedPermission()
+			continue
+		}
+		if !q.MatchesRecord(r) {
+			continue
+		}
+
+		it.CountMatched()
+		select {
+		case it.Next <- r:
+		case <-it.Done:
+			it.Finish(nil)
+			return
+		case <-time.After(1 * time.Second):
+			it.Finish(errors.New("query timeout"))
+			return
+		}
+	}
+
+	it.Finish(nil)
+}
+
+// verified returns whether a record loaded from the storage passes signature verification. Records of signed databases must always be signed, records of other databases are verified if they are marked as signed.
+func (c *Controller) verified(r record.Record) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if !c.signed.IsSet() && !r.Meta().IsSigned() {
+		return true
+	}
+	err := record.VerifySignature(r)
+	if err != nil {
+		log.Warningf("database: record %s failed signature verification: %s", r.Key(), err)
+		return false
+	}
+	return true
+}
+
 // PushUpdate pushes a record update to subscribers.
 func (c *Controller) PushUpdate(r record.Record) {
 	if c != nil {
 		c.readLock.RLock()
 		defer c.readLock.RUnlock()
 
-		if shuttingDown.IsSet() {
+		if c.stopped() {
 			return
 		}
 
+		if c.cache != nil {
+			c.cache.invalidate(r.DatabaseKey())
+		}
+		if c.search != nil {
+			c.search.update(r)
+		}
+
 		for _, sub := range c.subscriptions {
 			if r.Meta().CheckPermission(sub.local, sub.internal) && sub.q.Matches(r) {
-				select {
-				case sub.Feed <- r:
-				default:
-				}
+				sub.push(r)
 			}
 		}
 	}
 }
 
-func (c *Controller) addSubscription(sub *Subscription) {
+func (c *Controller) addSubscription(sub *Subscription) error {
 	c.readLock.Lock()
 	defer c.readLock.Unlock()
 	c.writeLock.Lock()
 	defer c.writeLock.Unlock()
 
-	if shuttingDown.IsSet() {
-		return
+	if err := c.stoppedErr(); err != nil {
+		return err
 	}
 
 	c.subscriptions = append(c.subscriptions, sub)
+	return nil
 }
 
 func (c *Controller) readUnlockerAfterQuery(it *iterator.Iterator) {
@@ -200,26 +439,92 @@ func (c *Controller) Maintain() error {
 	c.writeLock.RLock()
 	defer c.writeLock.RUnlock()
 
-	if shuttingDown.IsSet() {
+	if c.stopped() {
 		return nil
 	}
 
+	err := c.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %s", err)
+	}
+
 	return c.storage.Maintain()
 }
 
-// MaintainThorough runs the MaintainThorough method on the storage.
+// MaintainThorough upgrades records stored in an older record format and runs the MaintainThorough method on the storage.
 func (c *Controller) MaintainThorough() error {
+	err := c.upgradeRecords()
+	if err != nil {
+		return fmt.Errorf("failed to upgrade records: %s", err)
+	}
+
 	c.writeLock.RLock()
 	defer c.writeLock.RUnlock()
 
-	if shuttingDown.IsSet() {
+	if c.stopped() {
 		return nil
 	}
 
+	err = c.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %s", err)
+	}
+
 	return c.storage.MaintainThorough()
 }
 
-// Shutdown shuts down the storage.
+// check verifies the integrity of the storage, if supported.
+func (c *Controller) check() error {
+	c.writeLock.RLock()
+	defer c.writeLock.RUnlock()
+
+	if c.stopped() {
+		return nil
+	}
+
+	// records that cannot be flushed are kept and written to a recovered storage
+	_ = c.flush()
+
+	return checkStorage(c.storage)
+}
+
+// replaceStorage shuts down the storage and replaces it with the one returned by open. Hooks, subscriptions and records waiting to be flushed are kept. If open fails, the controller is marked as unloaded.
+func (c *Controller) replaceStorage(open func() (storage.Interface, error)) error {
+	// acquire full locks
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if err := c.stoppedErr(); err != nil {
+		return err
+	}
+
+	// the storage is replaced anyway
+	_ = c.storage.Shutdown()
+
+	storageInt, err := open()
+	if err != nil {
+		c.unloaded.Set()
+		return err
+	}
+	c.storage = storageInt
+	if c.cache != nil {
+		c.cache.purge()
+	}
+	if c.quota != nil {
+		err = c.quota.count(storageInt)
+		if err != nil {
+			log.Warningf("database: failed to count records of replaced storage: %s", err)
+		}
+	}
+	if c.search != nil {
+		c.search = newSearchIndex(c.search.fields)
+	}
+	return nil
+}
+
+// Shutdown flushes records waiting to be written and shuts down the storage.
 func (c *Controller) Shutdown() error {
 	// acquire full locks
 	c.readLock.Lock()
@@ -227,5 +532,36 @@ func (c *Controller) Shutdown() error {
 	c.writeLock.Lock()
 	defer c.writeLock.Unlock()
 
-	return c.storage.Shutdown()
+	flushErr := c.flush()
+	err := c.storage.Shutdown()
+	if flushErr != nil {
+		return fmt.Errorf("failed to flush records: %s", flushErr)
+	}
+	return err
+}
+
+// unload flushes records waiting to be written, shuts down the storage and marks the controller as unloaded, unless it has active hooks or subscriptions.
+func (c *Controller) unload() error {
+	// acquire full locks
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if len(c.hooks) > 0 || len(c.subscriptions) > 0 {
+		return ErrDatabaseInUse
+	}
+
+	err := c.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %s", err)
+	}
+
+	// the controller stays usable if the storage cannot be shut down
+	err = c.storage.Shutdown()
+	if err != nil {
+		return err
+	}
+	c.unloaded.Set()
+	return nil
 }
@@ -5,7 +5,9 @@
 	"fmt"
 	"sync"
 
+	"github.com/safing/portbase/database/record"
 	"github.com/safing/portbase/database/storage"
+	"github.com/safing/portbase/log"
 )
 
 var (
@@ -23,6 +25,7 @@ func getController(name string) (*Controller, error) {
 	controller, ok := controllers[name]
 	controllersLock.RUnlock()
 	if ok {
+		controller.touch()
 		return controller, nil
 	}
 
@@ -33,6 +36,13 @@ func getController(name string) (*Controller, error) {
 		return nil, ErrShuttingDown
 	}
 
+	// check again, database might have been started in the meantime
+	controller, ok = controllers[name]
+	if ok {
+		controller.touch()
+		return controller, nil
+	}
+
 	// get db registration
 	registeredDB, err := getDatabase(name)
 	if err != nil {
@@ -45,17 +55,49 @@ func getController(name string) (*Controller, error) {
 		return nil, fmt.Errorf(`could not start database %s (type %s): %s`, name, registeredDB.StorageType, err)
 	}
 
-	// start database
-	storageInt, err := storage.StartDatabase(name, registeredDB.StorageType, dbLocation)
+	// start database, recover if damaged
+	storageInt, err := openStorage(name, registeredDB.StorageType, dbLocation)
+	if err != nil && isDamaged(err) && registeredDB.Recovery != RecoveryDisabled {
+		storageInt, err = recoverStorage(name, registeredDB, dbLocation, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf(`could not start database %s (type %s): %s`, name, registeredDB.StorageType, err)
 	}
 
 	controller = newController(storageInt)
+	if len(registeredDB.SearchFields) > 0 {
+		controller.search = newSearchIndex(registeredDB.SearchFields)
+	}
+	controller.signed.SetTo(registeredDB.Signed)
+	record.SetDatabaseCompression(name, registeredDB.Compression)
+	err = controller.enableQuota(name, registeredDB.SoftQuota, registeredDB.HardQuota)
+	if err != nil {
+		_ = storageInt.Shutdown()
+		return nil, fmt.Errorf(`could not start database %s (type %s): %s`, name, registeredDB.StorageType, err)
+	}
+	controller.enableCache(registeredDB.CacheSize, registeredDB.WriteBehindInterval)
 	controllers[name] = controller
 	return controller, nil
 }
 
+// updateController applies the settings of a database registration that may change at runtime to the loaded controller of the database.
+func updateController(settings *Database) {
+	controllersLock.RLock()
+	controller, ok := controllers[settings.Name]
+	controllersLock.RUnlock()
+	if !ok {
+		return
+	}
+
+	controller.signed.SetTo(settings.Signed)
+	if !controller.Injected() {
+		err := controller.updateQuota(settings.Name, settings.SoftQuota, settings.HardQuota)
+		if err != nil {
+			log.Warningf("database: failed to apply quota to database %s: %s", settings.Name, err)
+		}
+	}
+}
+
 // InjectDatabase injects an already running database into the system.
 func InjectDatabase(name string, storageInt storage.Interface) (*Controller, error) {
 	controllersLock.Lock()
@@ -83,6 +125,11 @@ func InjectDatabase(name string, storageInt storage.Interface) (*Controller, err
 	}
 
 	controller := newController(storageInt)
+	if len(registeredDB.SearchFields) > 0 {
+		controller.search = newSearchIndex(registeredDB.SearchFields)
+	}
+	controller.enableCache(registeredDB.CacheSize, registeredDB.WriteBehindInterval)
+	record.SetDatabaseCompression(name, registeredDB.Compression)
 	controllers[name] = controller
 	return controller, nil
 }
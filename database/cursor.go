@@ -0,0 +1,227 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+)
+
+const defaultCursorPageSize = 100
+
+// CursorOptions select the records a Cursor iterates over.
+type CursorOptions struct {
+	Database string
+	// Start is the first database key of the range, End is the first database key after the range. Empty values do not limit the range.
+	Start string
+	End   string
+	// Reverse iterates from the end of the range to its start.
+	Reverse bool
+	// PageSize is the maximum number of records returned by Next. Defaults to 100.
+	PageSize int
+}
+
+// Cursor iterates over a key range of a database in key order, page by page. Its position can be saved as a token in order to resume the iteration later, eg. after a restart or on the next page of a UI. Records that are written while iterating are returned if they were not passed yet.
+type Cursor struct {
+	i     *Interface
+	state cursorState
+}
+
+// cursorState is the content of a cursor token.
+type cursorState struct {
+	Database string `json:"d"`
+	Start    string `json:"s,omitempty"`
+	End      string `json:"e,omitempty"`
+	Reverse  bool   `json:"r,omitempty"`
+	PageSize int    `json:"p"`
+	After    string `json:"a,omitempty"`
+	Done     bool   `json:"x,omitempty"`
+}
+
+// NewCursor returns a cursor for the given key range.
+func (i *Interface) NewCursor(opts *CursorOptions) (*Cursor, error) {
+	if opts.Database == "" {
+		return nil, errors.New("cursor needs a database")
+	}
+	if opts.Start != "" && opts.End != "" && opts.Start > opts.End {
+		return nil, errors.New("cursor start must not be after end")
+	}
+	if opts.PageSize < 0 {
+		return nil, errors.New("cursor page size must not be negative")
+	}
+
+	c := &Cursor{
+		i: i,
+		state: cursorState{
+			Database: opts.Database,
+			Start:    opts.Start,
+			End:      opts.End,
+			Reverse:  opts.Reverse,
+			PageSize: opts.PageSize,
+		},
+	}
+	if c.state.PageSize == 0 {
+		c.state.PageSize = defaultCursorPageSize
+	}
+	return c, nil
+}
+
+// ResumeCursor returns a cursor that continues where the cursor the token was taken from stopped.
+func (i *Interface) ResumeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursorToken
+	}
+	c := &Cursor{i: i}
+	err = json.Unmarshal(data, &c.state)
+	if err != nil || c.state.Database == "" || c.state.PageSize <= 0 {
+		return nil, ErrInvalidCursorToken
+	}
+	return c, nil
+}
+
+// Next returns the next page of records. It returns no records when the iteration is done.
+func (c *Cursor) Next() ([]record.Record, error) {
+	if c.state.Done {
+		return nil, nil
+	}
+
+	var records []record.Record
+	var more bool
+	err := retryUnloaded(func() error {
+		db, err := getController(c.state.Database)
+		if err != nil {
+			return err
+		}
+
+		records, more, err = db.ReadRange(&storage.KeyRange{
+			Start:   c.state.Start,
+			End:     c.state.End,
+			Reverse: c.state.Reverse,
+			After:   c.state.After,
+			Limit:   c.state.PageSize,
+		}, c.i.options.Local, c.i.options.Internal)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) > 0 {
+		c.state.After = records[len(records)-1].DatabaseKey()
+	}
+	c.state.Done = !more
+	return records, nil
+}
+
+// Done returns whether all records were returned.
+func (c *Cursor) Done() bool {
+	return c.state.Done
+}
+
+// Token returns an opaque token of the current position, which can be passed to ResumeCursor.
+func (c *Cursor) Token() string {
+	// marshaling a struct of strings, ints and bools cannot fail
+	data, _ := json.Marshal(c.state)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ReadRange returns the next records of the key range, in key order. Storages that do not support reading ranges natively are queried and the results sorted, which makes every page as expensive as reading the whole range.
+func (c *Controller) ReadRange(kr *storage.KeyRange, local, internal bool) (records []record.Record, more bool, err error) {
+	// the storage must see all records
+	err = c.flushForRead()
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.readLock.RLock()
+	defer c.readLock.RUnlock()
+
+	err = c.stoppedErr()
+	if err != nil {
+		return nil, false, err
+	}
+
+	for {
+		if rangeReader, ok := c.storage.(storage.RangeReader); ok {
+			records, more, err = rangeReader.ReadRange(kr, local, internal)
+		} else {
+			records, more, err = c.readRangeByQuery(kr, local, internal)
+		}
+		if err != nil || len(records) == 0 {
+			return records, more, err
+		}
+		last := records[len(records)-1].DatabaseKey()
+
+		// drop records that fail signature verification
+		verified := records[:0]
+		for _, r := range records {
+			if c.verified(r) {
+				verified = append(verified, r)
+			}
+		}
+		if len(verified) > 0 || !more {
+			return verified, more, nil
+		}
+
+		// the whole page was dropped, continue after it
+		next := *kr
+		next.After = last
+		kr = &next
+	}
+}
+
+// readRangeByQuery emulates reading a key range with a query. The caller must hold the readLock.
+func (c *Controller) readRangeByQuery(kr *storage.KeyRange, local, internal bool) (records []record.Record, more bool, err error) {
+	// narrow down the query to the common prefix of the range
+	var prefix string
+	if kr.Start != "" && kr.End != "" {
+		prefix = commonPrefix(kr.Start, kr.End)
+	}
+	// storages only use the key prefix of queries
+	q, err := query.New(":" + prefix).Check()
+	if err != nil {
+		return nil, false, err
+	}
+
+	it, err := c.storage.Query(q, local, internal)
+	if err != nil {
+		return nil, false, err
+	}
+	for r := range it.Next {
+		if kr.Contains(r.DatabaseKey()) {
+			records = append(records, r)
+		}
+	}
+	if it.Err() != nil {
+		return nil, false, it.Err()
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if kr.Reverse {
+			return records[i].DatabaseKey() > records[j].DatabaseKey()
+		}
+		return records[i].DatabaseKey() < records[j].DatabaseKey()
+	})
+	if kr.Limit > 0 && len(records) > kr.Limit {
+		return records[:kr.Limit], true, nil
+	}
+	return records, false, nil
+}
+
+func commonPrefix(a, b string) string {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return a[:i]
+		}
+	}
+	return a[:n]
+}
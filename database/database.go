@@ -14,6 +14,24 @@ type Database struct {
 	Registered  time.Time
 	LastUpdated time.Time
 	LastLoaded  time.Time
+
+	// SearchFields enables the full-text search index for the listed record fields.
+	SearchFields []string `json:",omitempty"`
+	// Compression sets the compression (dsd.GZIP, dsd.ZSTD) used for storing records. Records smaller than dsd.CompressionThreshold are stored uncompressed.
+	Compression uint8 `json:",omitempty"`
+	// Recovery sets how a damaged storage is recovered (RecoveryStartFresh, RecoveryFromBackup). By default, damaged storages are left untouched.
+	Recovery uint8 `json:",omitempty"`
+	// CacheSize enables a record cache for the given amount of records, shared by all users of the database.
+	CacheSize int `json:",omitempty"`
+	// WriteBehindInterval enables write-behind: records are written to the storage in this interval and when the database is shut down, successive writes to the same record are only written once. Records that are not written yet are lost if the process crashes.
+	WriteBehindInterval time.Duration `json:",omitempty"`
+	// SoftQuota sets the size above which a warning is logged and a notification is shown. Quotas are not supported for injected databases.
+	// Databases with a quota load and serialize all of their records when they are started, in order to account their size, and keep the size of every record in memory.
+	SoftQuota *Quota `json:",omitempty"`
+	// HardQuota sets the size above which writes that increase the size of the database are rejected with ErrQuotaExceeded.
+	HardQuota *Quota `json:",omitempty"`
+	// Signed requires all records to be signed. Records are signed when they are saved and must implement record.StorageSigner. Records that fail verification, including unsigned records, are not returned. Not supported for injected databases.
+	Signed bool `json:",omitempty"`
 }
 
 // MigrateTo migrates the database to another storage type.
@@ -1,19 +1,33 @@
 package database
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime/pprof"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/tevino/abool"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/database/accessor"
 	q "github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
 	_ "github.com/safing/portbase/database/storage/badger"
 	_ "github.com/safing/portbase/database/storage/bbolt"
 	_ "github.com/safing/portbase/database/storage/fstree"
+	_ "github.com/safing/portbase/database/storage/hashmap"
+	_ "github.com/safing/portbase/database/storage/sqlite"
+	"github.com/safing/portbase/formats/dsd"
 )
 
 func makeKey(dbName, key string) string {
@@ -81,6 +95,11 @@ func testDatabase(t *testing.T, storageType string) {
 		log.Fatalf("A and A1 mismatch, A1: %v", A1)
 	}
 
+	// generated accessor is preferred
+	if _, ok := A.GetAccessor(A).(*accessor.GeneratedAccessor); !ok {
+		t.Fatalf("expected generated accessor, got %T", A.GetAccessor(A))
+	}
+
 	query, err := q.New(dbName).Where(
 		q.And(
 			q.Where("Name", q.EndsWith, "bert"),
@@ -107,6 +126,10 @@ func testDatabase(t *testing.T, storageType string) {
 		t.Fatalf("expected two records, got %d", cnt)
 	}
 
+	if sub.Dropped() {
+		t.Fatal("no updates should have been dropped")
+	}
+
 	err = hook.Cancel()
 	if err != nil {
 		t.Fatal(err)
@@ -118,6 +141,1327 @@ func testDatabase(t *testing.T, storageType string) {
 
 }
 
+func TestSubscriptionDropped(t *testing.T) {
+	sub := &Subscription{
+		dropped: abool.NewBool(false),
+		Feed:    make(chan record.Record, 1),
+	}
+	sub.push(NewExample("test:a", "A", 1))
+	if sub.Dropped() {
+		t.Fatal("update should not have been dropped")
+	}
+	sub.push(NewExample("test:b", "B", 2))
+	if !sub.Dropped() {
+		t.Fatal("update should have been dropped")
+	}
+	if sub.Dropped() {
+		t.Fatal("dropped flag should be reset")
+	}
+}
+
+func testSearch(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("testing-search-%s", storageType)
+	_, err := Register(&Database{
+		Name:         dbName,
+		Description:  fmt.Sprintf("Unit Test Database for full-text search on %s", storageType),
+		StorageType:  storageType,
+		PrimaryAPI:   "",
+		SearchFields: []string{"Name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewInterface(nil)
+
+	for key, name := range map[string]string{
+		"A": "Herbert the Great",
+		"B": "Fritz the Great Great",
+		"C": "Norbert",
+	} {
+		err = NewExample(makeKey(dbName, key), name, 100).Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(terms string, expected ...string) {
+		it, err := db.Query(q.New(dbName).Search(terms).MustBeValid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for r := range it.Next {
+			keys = append(keys, r.DatabaseKey())
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("search for %q: expected %v, got %v", terms, expected, keys)
+		}
+	}
+
+	search("great", "B", "A")
+	search("herbert great", "A", "B")
+	search("norbert", "C")
+	search("nobody")
+
+	// explain and stats
+	plan, err := db.Explain(q.New(dbName).Search("great").MustBeValid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Strategy != StrategySearchIndex || plan.EstimatedCost != 2 {
+		t.Fatalf("unexpected search plan: %+v", plan)
+	}
+	plan, err = db.Explain(q.New(dbName).MustBeValid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Strategy != StrategyPrefixScan || plan.EstimatedCost != 3 {
+		t.Fatalf("unexpected scan plan: %+v", plan)
+	}
+	it, err := db.Query(q.New(dbName).MustBeValid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range it.Next {
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	stats := it.Stats()
+	if stats.Matched != 3 || stats.Scanned < stats.Matched {
+		t.Fatalf("unexpected query stats: %+v", stats)
+	}
+
+	// index must follow updates and deletions
+	err = NewExample(makeKey(dbName, "C"), "Norbert the Great", 100).Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Delete(makeKey(dbName, "A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	search("great", "B", "C")
+
+	// search is not available without an index
+	_, err = db.Query(q.New("testing-bbolt").Search("herbert").MustBeValid())
+	if err != ErrSearchNotEnabled {
+		t.Fatalf("expected ErrSearchNotEnabled, got %v", err)
+	}
+}
+
+type signedExample struct {
+	Example
+}
+
+func (e *signedExample) SigningKeyID() string {
+	return "test"
+}
+
+func testSigning(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := dsd.NewMemoryKeyStore()
+	ks.AddSigningKey("test", key)
+	dsd.SetKeyStore(ks)
+	defer dsd.SetKeyStore(nil)
+
+	db := NewInterface(nil)
+
+	signed := &signedExample{}
+	signed.Name = "Signed"
+	signed.SetKey(makeKey("testing-bbolt", "signed"))
+	signed.CreateMeta()
+	signed.Meta().MakeSigned()
+	err = db.Put(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.Get(makeKey("testing-bbolt", "signed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Meta().IsSigned() {
+		t.Fatal("record should be signed")
+	}
+
+	// signed database
+	_, err = Register(&Database{
+		Name:        "testing-signed",
+		Description: "Unit Test Database for signed records",
+		StorageType: "fstree",
+		Signed:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		signed = &signedExample{}
+		signed.Name = "Signed"
+		signed.SetKey(makeKey("testing-signed", key))
+		err = db.Put(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Put(NewExample(makeKey("testing-signed", "c"), "Unsigned", 1))
+	if err == nil {
+		t.Fatal("records that cannot be signed should be rejected")
+	}
+
+	// records written to the storage directly, without a signature
+	c, err := getController("testing-signed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := NewExample(makeKey("testing-signed", "b"), "Forged", 1)
+	forged.CreateMeta()
+	err = c.storage.Put(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Get(makeKey("testing-signed", "b"))
+	if err != ErrInvalidSignature {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	it, err := db.Query(q.New("testing-signed:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for r := range it.Next {
+		keys = append(keys, r.DatabaseKey())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Fatalf("expected only the signed record, got %v", keys)
+	}
+	if stats := it.Stats(); stats.Matched != 1 || stats.SkippedValidity != 1 {
+		t.Fatalf("unexpected query stats: %+v", stats)
+	}
+	cursor, err := db.NewCursor(&CursorOptions{Database: "testing-signed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := cursor.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].DatabaseKey() != "a" {
+		t.Fatalf("expected only the signed record, got %v", records)
+	}
+
+	// unknown signing key
+	dsd.SetKeyStore(dsd.NewMemoryKeyStore())
+	_, err = db.Get(makeKey("testing-bbolt", "signed"))
+	if err != ErrInvalidSignature {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+// v1Example is stored in record format version 1.
+type v1Example struct {
+	Example
+}
+
+func (e *v1Example) MarshalRecord(self record.Record) ([]byte, error) {
+	c := container.New([]byte{1})
+	metaSection, err := dsd.Dump(e.Meta(), dsd.GenCode)
+	if err != nil {
+		return nil, err
+	}
+	c.AppendAsBlock(metaSection)
+	dataSection, err := e.Marshal(self, dsd.JSON)
+	if err != nil {
+		return nil, err
+	}
+	c.Append(dataSection)
+	return c.CompileData(), nil
+}
+
+func testUpgrade(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("testing-%s", storageType)
+	key := makeKey(dbName, "v1")
+	db := NewInterface(nil)
+
+	old := &v1Example{}
+	old.Name = "Old"
+	old.SetKey(key)
+	err := db.Put(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	needsUpgrade := func() bool {
+		r, err := db.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.(*record.Wrapper).NeedsUpgrade()
+	}
+	if !needsUpgrade() {
+		t.Fatal("record should be stored in version 1")
+	}
+
+	err = MaintainThorough()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsUpgrade() {
+		t.Fatal("record should have been upgraded")
+	}
+	A, err := GetExample(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Name != "Old" || A.Meta().Created != old.Meta().Created {
+		t.Fatalf("unexpected upgraded record: %+v", A)
+	}
+
+	// writes during the upgrade are not overwritten with the old version
+	const records = 100
+	for i := 0; i < records; i++ {
+		old := &v1Example{}
+		old.Name = "Old"
+		old.SetKey(makeKey(dbName, fmt.Sprintf("v1-%d", i)))
+		err = db.Put(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < records; i++ {
+			err := NewExample(makeKey(dbName, fmt.Sprintf("v1-%d", i)), "New", i).Save()
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	err = MaintainThorough()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	for i := 0; i < records; i++ {
+		A, err := GetExample(makeKey(dbName, fmt.Sprintf("v1-%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if A.Name != "New" || A.Score != i {
+			t.Fatalf("write during upgrade was lost: %+v", A)
+		}
+	}
+}
+
+func testPatch(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("testing-%s", storageType)
+	key := makeKey(dbName, "patch")
+	db := NewInterface(nil)
+
+	err := NewExample(key, "Patch", 1).Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON Patch
+	err = db.Patch(key, []byte(`[{"op": "test", "path": "/Name", "value": "Patch"}, {"op": "replace", "path": "/Score", "value": 2}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// JSON Merge Patch
+	err = db.Patch(key, []byte(` {"Name": "Merged"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, err := GetExample(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Name != "Merged" || A.Score != 2 {
+		t.Fatalf("unexpected patched record: %+v", A)
+	}
+
+	// failed test operation
+	err = db.Patch(key, []byte(`[{"op": "test", "path": "/Name", "value": "Patch"}, {"op": "replace", "path": "/Score", "value": 3}]`))
+	if err == nil {
+		t.Fatal("patch with failing test operation should fail")
+	}
+	// invalid patch
+	err = db.Patch(key, []byte(`"Name"`))
+	if err != ErrInvalidPatch {
+		t.Fatalf("expected ErrInvalidPatch, got %v", err)
+	}
+	// missing record
+	err = db.Patch(makeKey(dbName, "missing"), []byte(`{"Name": "Missing"}`))
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	A, err = GetExample(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Score != 2 {
+		t.Fatalf("failed patch should not modify the record: %+v", A)
+	}
+
+	// struct records keep their metadata
+	A.Lock()
+	err = replaceStructData(A, []byte(`{"Name": "Struct"}`))
+	A.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Name != "Struct" || A.Score != 0 || A.Key() != key || A.Meta() == nil {
+		t.Fatalf("unexpected struct record after replacing data: %+v", A)
+	}
+
+	// concurrent patches must not overwrite each other
+	listKey := makeKey(dbName, "patch-list")
+	list, err := record.NewWrapper(listKey, &record.Meta{}, record.JSON, []byte(`{"Items":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Put(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, maxPatchAttempts)
+	for i := 0; i < maxPatchAttempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- db.Patch(listKey, []byte(fmt.Sprintf(`[{"op": "add", "path": "/Items/-", "value": %d}]`, i)))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := db.Get(listKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, ok := accessor.NewJSONBytesAccessor(&r.(*record.Wrapper).Data).GetInt("Items.#")
+	if !ok || items != maxPatchAttempts {
+		t.Fatalf("expected %d patched items, got %v", maxPatchAttempts, items)
+	}
+}
+
+func testLifecycle(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("lifecycle-%s", storageType)
+	newName := fmt.Sprintf("renamed-%s", storageType)
+	_, err := Register(&Database{
+		Name:        dbName,
+		Description: fmt.Sprintf("Lifecycle Test Database for %s", storageType),
+		StorageType: storageType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewInterface(nil)
+
+	err = NewExample(makeKey(dbName, "A"), "Lifecycle", 1).Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// databases in use cannot be unloaded
+	hook, err := RegisterHook(q.New(dbName).MustBeValid(), &HookBase{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Unload(dbName)
+	if err != ErrDatabaseInUse {
+		t.Fatalf("expected ErrDatabaseInUse, got %v", err)
+	}
+	err = hook.Cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unload and start again
+	c, err := getController(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Unload(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Get("A")
+	if err != ErrDatabaseUnloaded {
+		t.Fatalf("unloaded controller should not be usable, got %v", err)
+	}
+	calls := 0
+	err = retryUnloaded(func() error {
+		calls++
+		if calls == 1 {
+			return c.Put(NewExample(makeKey(dbName, "A"), "Lifecycle", 1))
+		}
+		current, err := getController(dbName)
+		if err != nil {
+			return err
+		}
+		_, err = current.Get("A")
+		return err
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("operation on unloaded controller should be retried once, got %v after %d calls", err, calls)
+	}
+	A, err := GetExample(makeKey(dbName, "A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Name != "Lifecycle" {
+		t.Fatalf("unexpected record after reload: %+v", A)
+	}
+
+	// idle unloading
+	err = UnloadIdle(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllersLock.RLock()
+	_, loaded := controllers[dbName]
+	controllersLock.RUnlock()
+	if !loaded {
+		t.Fatal("recently used database should not be unloaded")
+	}
+	err = UnloadIdle(-time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllersLock.RLock()
+	_, loaded = controllers[dbName]
+	controllersLock.RUnlock()
+	if loaded {
+		t.Fatal("idle database should be unloaded")
+	}
+	// rename
+	err = Rename(dbName, "x")
+	if err == nil {
+		t.Fatal("should fail to rename to invalid name")
+	}
+	err = Rename(dbName, newName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Get(makeKey(dbName, "A"))
+	if err == nil {
+		t.Fatal("old database name should not be accessible anymore")
+	}
+	A, err = GetExample(makeKey(newName, "A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Name != "Lifecycle" {
+		t.Fatalf("unexpected record after rename: %+v", A)
+	}
+
+	// delete
+	err = Delete(newName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Get(makeKey(newName, "A"))
+	if err == nil {
+		t.Fatal("deleted database should not be accessible anymore")
+	}
+	_, err = os.Stat(filepath.Join(databasesStructure.Path, newName))
+	if !os.IsNotExist(err) {
+		t.Fatalf("database directory should be removed, got %v", err)
+	}
+
+	// re-register with the same name starts with an empty database
+	_, err = Register(&Database{
+		Name:        newName,
+		Description: fmt.Sprintf("Lifecycle Test Database for %s", storageType),
+		StorageType: storageType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Get(makeKey(newName, "A"))
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+type failingPutHook struct {
+	HookBase
+}
+
+func (h *failingPutHook) UsesPrePut() bool {
+	return true
+}
+
+func (h *failingPutHook) PrePut(r record.Record) (record.Record, error) {
+	return nil, errors.New("rejected")
+}
+
+type failingPutStorage struct {
+	storage.Interface
+}
+
+func (s *failingPutStorage) Put(r record.Record) error {
+	return errors.New("rejected")
+}
+
+func testCache(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("cache-%s", storageType)
+	_, err := Register(&Database{
+		Name:        dbName,
+		Description: fmt.Sprintf("Cache Test Database for %s", storageType),
+		StorageType: storageType,
+		CacheSize:   100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writes through one interface are seen by all others
+	db1 := NewInterface(nil)
+	db2 := NewInterface(nil)
+	A := NewExample(makeKey(dbName, "A"), "Herbert", 411)
+	err = db1.Put(A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	A1, err := GetExample(A.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A1.Score != 411 {
+		t.Fatalf("unexpected score %d", A1.Score)
+	}
+	A.Score = 412
+	err = db1.Put(A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	A1, err = GetExample(A.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A1.Score != 412 {
+		t.Fatalf("cache was not invalidated, got score %d", A1.Score)
+	}
+	err = db1.Delete(A.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db2.Get(A.Key())
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// unsaved changes and failed writes do not reach the cache
+	C := NewExample(makeKey(dbName, "C"), "Herbert", 1)
+	err = db1.Put(C)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := db1.Get(C.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Meta().Delete()
+	hook, err := RegisterHook(q.New(dbName).MustBeValid(), &failingPutHook{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db1.Delete(C.Key())
+	if err == nil {
+		t.Fatal("delete should fail")
+	}
+	err = hook.Cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db2.Get(C.Key())
+	if err != nil {
+		t.Fatalf("record should be unchanged, got %v", err)
+	}
+
+	// write-behind
+	wbName := fmt.Sprintf("write-behind-%s", storageType)
+	_, err = Register(&Database{
+		Name:                wbName,
+		Description:         fmt.Sprintf("Write-Behind Test Database for %s", storageType),
+		StorageType:         storageType,
+		WriteBehindInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := getController(wbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	B := NewExample(makeKey(wbName, "B"), "Herbert", 1)
+	for i := 1; i <= 3; i++ {
+		B.Score = i
+		err = db1.Put(B)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.cache.dirty) != 1 {
+		t.Fatalf("successive writes should be coalesced, got %d dirty records", len(c.cache.dirty))
+	}
+	_, err = c.storage.Get("B")
+	if err == nil {
+		t.Fatal("record should not be written before flush")
+	}
+	B1, err := GetExample(B.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if B1.Score != 3 {
+		t.Fatalf("unexpected score %d", B1.Score)
+	}
+
+	// queries see unflushed records
+	it, err := db2.Query(q.New(wbName).MustBeValid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for range it.Next {
+		cnt++
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if cnt != 1 {
+		t.Fatalf("expected 1 record, got %d", cnt)
+	}
+
+	// unloading flushes, the database stays loaded if that fails
+	B.Score = 4
+	err = db1.Put(B)
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthyStorage := c.storage
+	c.storage = &failingPutStorage{healthyStorage}
+	err = Unload(wbName)
+	if err == nil {
+		t.Fatal("unload should fail if records cannot be flushed")
+	}
+	controllersLock.RLock()
+	stillLoaded := controllers[wbName] == c
+	controllersLock.RUnlock()
+	if !stillLoaded || c.stopped() {
+		t.Fatal("database should stay loaded if unloading fails")
+	}
+	c.storage = healthyStorage
+	err = Unload(wbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	B1, err = GetExample(B.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if B1.Score != 4 {
+		t.Fatalf("unexpected score %d after reload", B1.Score)
+	}
+
+	// flush in interval
+	_, err = Register(&Database{
+		Name:                wbName,
+		Description:         fmt.Sprintf("Write-Behind Test Database for %s", storageType),
+		StorageType:         storageType,
+		WriteBehindInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Unload(wbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = getController(wbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	B.Score = 5
+	err = db1.Put(B)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		c.readLock.RLock()
+		r, err := c.storage.Get("B")
+		c.readLock.RUnlock()
+		if err == nil {
+			B1 = &Example{}
+			err = record.Unwrap(r, B1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if B1.Score == 5 {
+				break
+			}
+		}
+		if i > 100 {
+			t.Fatal("record was not flushed in interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testQuota(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("quota-%s", storageType)
+	reports := make(chan *QuotaReport, 10)
+	OnSoftQuotaExceeded(func(report *QuotaReport) {
+		if report.Database == dbName {
+			reports <- report
+		}
+	})
+	_, err := Register(&Database{
+		Name:        dbName,
+		Description: fmt.Sprintf("Quota Test Database for %s", storageType),
+		StorageType: storageType,
+		SoftQuota:   &Quota{Records: 2},
+		HardQuota:   &Quota{Records: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewInterface(nil)
+
+	for _, key := range []string{"A", "B"} {
+		err = db.Put(NewExample(makeKey(dbName, key), "Herbert", 411))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-reports:
+		t.Fatal("soft quota should not be exceeded yet")
+	default:
+	}
+
+	// soft quota
+	err = db.Put(NewExample(makeKey(dbName, "C"), "Herbert", 411))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case report := <-reports:
+		if report.Records != 3 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("soft quota should be reported")
+	}
+
+	// hard quota
+	err = db.Put(NewExample(makeKey(dbName, "D"), "Herbert", 411))
+	if err != ErrQuotaExceeded {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	err = db.Put(NewExample(makeKey(dbName, "A"), "Herbert", 412))
+	if err != nil {
+		t.Fatalf("writes that do not grow the database should be allowed, got %v", err)
+	}
+	err = db.Delete(makeKey(dbName, "C"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Put(NewExample(makeKey(dbName, "D"), "Herbert", 411))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// usage is counted on load
+	c, err := getController(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, bytes, ok := c.Usage()
+	if !ok || records != 3 || bytes == 0 {
+		t.Fatalf("unexpected usage: %d records, %d bytes", records, bytes)
+	}
+	err = Unload(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = getController(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloadedRecords, reloadedBytes, _ := c.Usage()
+	if reloadedRecords != records || reloadedBytes != bytes {
+		t.Fatalf("usage after reload %d/%d differs from %d/%d", reloadedRecords, reloadedBytes, records, bytes)
+	}
+
+	// quota changes apply to the loaded database
+	_, err = Register(&Database{
+		Name:        dbName,
+		Description: fmt.Sprintf("Quota Test Database for %s", storageType),
+		StorageType: storageType,
+		HardQuota:   &Quota{Records: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Put(NewExample(makeKey(dbName, "E"), "Herbert", 411))
+	if err != nil {
+		t.Fatalf("raised hard quota should allow the write, got %v", err)
+	}
+	err = db.Put(NewExample(makeKey(dbName, "F"), "Herbert", 411))
+	if err != ErrQuotaExceeded {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	_, err = Register(&Database{
+		Name:        dbName,
+		Description: fmt.Sprintf("Quota Test Database for %s", storageType),
+		StorageType: storageType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Put(NewExample(makeKey(dbName, "F"), "Herbert", 411))
+	if err != nil {
+		t.Fatalf("removed hard quota should allow the write, got %v", err)
+	}
+	if _, _, ok := c.Usage(); ok {
+		t.Fatal("usage should not be tracked without a quota")
+	}
+}
+
+func testCursor(t *testing.T, storageType string) {
+	dbName := fmt.Sprintf("cursor-%s", storageType)
+	_, err := Register(&Database{
+		Name:        dbName,
+		Description: fmt.Sprintf("Cursor Test Database for %s", storageType),
+		StorageType: storageType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewInterface(nil)
+
+	for i := 0; i < 25; i++ {
+		err = db.Put(NewExample(makeKey(dbName, fmt.Sprintf("k%02d", i)), "Herbert", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Delete(makeKey(dbName, "k07"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readAll := func(c *Cursor) (keys []string, pages int) {
+		for !c.Done() {
+			records, err := c.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range records {
+				keys = append(keys, r.DatabaseKey())
+			}
+			pages++
+		}
+		return keys, pages
+	}
+
+	// forward over all records
+	c, err := db.NewCursor(&CursorOptions{
+		Database: dbName,
+		PageSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, pages := readAll(c)
+	if len(keys) != 24 || pages != 3 {
+		t.Fatalf("expected 24 keys in 3 pages, got %d in %d: %v", len(keys), pages, keys)
+	}
+	if keys[0] != "k00" || keys[7] != "k08" || keys[23] != "k24" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	// reverse over a range, resumed from a token
+	c, err = db.NewCursor(&CursorOptions{
+		Database: dbName,
+		Start:    "k05",
+		End:      "k15",
+		Reverse:  true,
+		PageSize: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := c.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[0].DatabaseKey() != "k14" || records[3].DatabaseKey() != "k11" {
+		t.Fatalf("unexpected first page: %v", records)
+	}
+	c, err = db.ResumeCursor(c.Token())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ = readAll(c)
+	if strings.Join(keys, ",") != "k10,k09,k08,k06,k05" {
+		t.Fatalf("unexpected keys after resume: %v", keys)
+	}
+
+	_, err = db.ResumeCursor("invalid")
+	if err != ErrInvalidCursorToken {
+		t.Fatalf("expected ErrInvalidCursorToken, got %v", err)
+	}
+}
+
+// checkingStorage is a storage that returns err from its integrity check.
+type checkingStorage struct {
+	storage.Interface
+	err error
+}
+
+func (s *checkingStorage) Check() error {
+	return s.err
+}
+
+func testRecovery(t *testing.T) {
+	reports := make(chan *RecoveryReport, 10)
+	OnRecovery(func(report *RecoveryReport) {
+		// handlers may use the recovered database
+		_, _ = NewInterface(nil).Get(report.Database + ":A")
+		reports <- report
+	})
+	db := NewInterface(nil)
+	nextReport := func() *RecoveryReport {
+		select {
+		case report := <-reports:
+			return report
+		case <-time.After(time.Second):
+			t.Fatal("recovery should be reported")
+			return nil
+		}
+	}
+	noReport := func(msg string) {
+		select {
+		case report := <-reports:
+			t.Fatalf("%s: %+v", msg, report)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	damage := func(dbName, storageType, file string) {
+		location, err := getLocation(dbName, storageType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(location, file), []byte("damaged damaged damaged damaged damaged damaged damaged damaged damaged damaged damaged"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// recovery disabled
+	_, err := Register(&Database{
+		Name:        "recovery-disabled",
+		StorageType: "hashmap-snapshot",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	damage("recovery-disabled", "hashmap-snapshot", "snapshot.db")
+	_, err = db.Get("recovery-disabled:A")
+	if err == nil || err == ErrNotFound {
+		t.Fatalf("damaged database should fail to start, got %v", err)
+	}
+	noReport("damaged database should not be recovered")
+
+	// start fresh
+	_, err = Register(&Database{
+		Name:        "recovery-fresh",
+		StorageType: "hashmap-snapshot",
+		Recovery:    RecoveryStartFresh,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	damage("recovery-fresh", "hashmap-snapshot", "snapshot.db")
+	_, err = db.Get("recovery-fresh:A")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	report := nextReport()
+	if report.Database != "recovery-fresh" || report.RestoredFrom != "" {
+		t.Fatalf("unexpected recovery report: %+v", report)
+	}
+	_, err = os.Stat(filepath.Join(report.MovedTo, "snapshot.db"))
+	if err != nil {
+		t.Fatalf("damaged storage should be kept: %s", err)
+	}
+
+	// damaged bbolt and badger storages are recovered
+	for _, storageType := range []string{"bbolt", "badger"} {
+		dbName := "recovery-" + storageType
+		_, err = Register(&Database{
+			Name:        dbName,
+			StorageType: storageType,
+			Recovery:    RecoveryStartFresh,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := "db.bbolt"
+		if storageType == "badger" {
+			file = "MANIFEST"
+		}
+		damage(dbName, storageType, file)
+		_, err = db.Get(dbName + ":A")
+		if err != ErrNotFound {
+			t.Fatalf("expected ErrNotFound after recovering %s, got %v", storageType, err)
+		}
+		report = nextReport()
+		if report.Database != dbName {
+			t.Fatalf("unexpected recovery report: %+v", report)
+		}
+	}
+
+	// errors that do not indicate damage are not recovered
+	_, err = Register(&Database{
+		Name:        "recovery-unreadable",
+		StorageType: "hashmap-snapshot",
+		Recovery:    RecoveryStartFresh,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := getLocation("recovery-unreadable", "hashmap-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(filepath.Join(location, "snapshot.db"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Get("recovery-unreadable:A")
+	if err == nil || err == ErrNotFound {
+		t.Fatalf("unreadable database should fail to start, got %v", err)
+	}
+	noReport("unreadable database should not be recovered")
+	_, err = os.Stat(filepath.Join(location, "snapshot.db"))
+	if err != nil {
+		t.Fatalf("unreadable database should be left in place: %s", err)
+	}
+
+	// restore from backup
+	_, err = Register(&Database{
+		Name:        "recovery-backup",
+		StorageType: "sqlite",
+		Recovery:    RecoveryFromBackup,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewExample("recovery-backup:A", "Backup", 1).Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Backup("recovery-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewExample("recovery-backup:B", "After Backup", 2).Save()
+	if err != nil {
+		t.Fatalf("database should be usable after backup: %s", err)
+	}
+	err = Unload("recovery-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err = getLocation("recovery-backup", "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups := listBackups(filepath.Join(filepath.Dir(location), backupsDirName), "sqlite")
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup, got %v", backups)
+	}
+	damage("recovery-backup", "sqlite", "db.sqlite")
+	A, err := GetExample("recovery-backup:A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if A.Name != "Backup" {
+		t.Fatalf("unexpected record after restore: %+v", A)
+	}
+	report = nextReport()
+	if report.RestoredFrom != backups[0] {
+		t.Fatalf("unexpected recovery report: %+v", report)
+	}
+
+	// only the latest backups are kept
+	backupsDir := filepath.Join(filepath.Dir(location), backupsDirName)
+	for _, timestamp := range []string{"20191001-120000", "20191002-120000", "20191003-120000"} {
+		err = os.Mkdir(filepath.Join(backupsDir, "sqlite-"+timestamp), 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneBackups(backupsDir, "sqlite")
+	backups = listBackups(backupsDir, "sqlite")
+	if len(backups) != keptBackups || !strings.HasSuffix(backups[keptBackups-1], "sqlite-20191002-120000") {
+		t.Fatalf("unexpected backups after pruning: %v", backups)
+	}
+
+	// only damage reported by the storage is recovered
+	err = checkStorage(&checkingStorage{err: errors.New("database is locked")})
+	if err == nil || isDamaged(err) {
+		t.Fatalf("failed check should not be treated as damage, got %v", err)
+	}
+	err = checkStorage(&checkingStorage{err: fmt.Errorf("%w: bad page", storage.ErrCorrupted)})
+	if !isDamaged(err) {
+		t.Fatalf("reported damage should be recovered, got %v", err)
+	}
+
+	// healthy storages pass the check
+	err = CheckStorages()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testInjected(t *testing.T) {
+	objects := make(map[string]*Example)
+	for _, key := range []string{"a/1", "a/2", "a/3", "b/1"} {
+		e := NewExample(makeKey("injected", key), "Herbert", len(objects))
+		e.CreateMeta()
+		objects[key] = e
+	}
+	objects["a/3"].Meta().MakeSecret()
+
+	injected, err := NewInjectedDatabase("injected", "Injected Test Database",
+		func(key string) (record.Record, error) {
+			e, ok := objects[key]
+			if !ok {
+				return nil, ErrNotFound
+			}
+			return e, nil
+		},
+		nil,
+		func(prefix string) ([]record.Record, error) {
+			var records []record.Record
+			for _, e := range objects {
+				records = append(records, e)
+			}
+			return records, nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !injected.ReadOnly() {
+		t.Fatal("expected injected database without setter to be read only")
+	}
+	db := NewInterface(nil)
+
+	r, err := db.Get("injected:a/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != objects["a/1"] {
+		t.Fatal("expected object from getter")
+	}
+	_, err = db.Get("injected:c/1")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// secret records are filtered, other prefixes are skipped
+	it, err := db.Query(q.New("injected:a/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for r := range it.Next {
+		keys = append(keys, r.DatabaseKey())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if strings.Join(keys, ",") != "a/1,a/2" {
+		t.Fatalf("unexpected query result: %v", keys)
+	}
+
+	err = db.Put(NewExample("injected:a/4", "Fritz", 4))
+	if err != ErrReadOnly {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+
+	// injected databases are managed by their owner
+	err = Delete("injected")
+	if err == nil {
+		t.Fatal("should fail to delete an injected database")
+	}
+	_, err = db.Get("injected:a/1")
+	if err != nil {
+		t.Fatalf("injected database should still be available: %s", err)
+	}
+
+	// patch a record of a writable injected database
+	live := NewExample("injected-rw:a/1", "Herbert", 1)
+	live.CreateMeta()
+	var set record.Record
+	_, err = NewInjectedDatabase("injected-rw", "Writable Injected Test Database",
+		func(key string) (record.Record, error) {
+			if key != "a/1" {
+				return nil, ErrNotFound
+			}
+			return live, nil
+		},
+		func(r record.Record) error {
+			set = r
+			return nil
+		},
+		func(prefix string) ([]record.Record, error) {
+			return []record.Record{live}, nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Patch("injected-rw:a/1", []byte(`{"Name": "Fritz"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := set.(*Example)
+	if !ok || e == live || e.Name != "Fritz" || e.Score != 1 {
+		t.Fatalf("unexpected record passed to setter: %+v", set)
+	}
+	if live.Name != "Fritz" || live.Meta().Modified != e.Meta().Modified {
+		t.Fatalf("patched record was not updated after saving: %+v", live)
+	}
+}
+
 func TestDatabaseSystem(t *testing.T) {
 
 	// panic after 10 seconds, to check for locks
@@ -142,6 +1486,29 @@ func TestDatabaseSystem(t *testing.T) {
 	testDatabase(t, "badger")
 	testDatabase(t, "bbolt")
 	testDatabase(t, "fstree")
+	testDatabase(t, "hashmap")
+	testDatabase(t, "sqlite")
+	testSearch(t, "bbolt")
+	testSigning(t)
+	testUpgrade(t, "badger")
+	testUpgrade(t, "bbolt")
+	testUpgrade(t, "fstree")
+	testPatch(t, "badger")
+	testPatch(t, "bbolt")
+	testPatch(t, "fstree")
+	testLifecycle(t, "fstree")
+	testLifecycle(t, "sqlite")
+	testLifecycle(t, "hashmap-snapshot")
+	testRecovery(t)
+	testCache(t, "fstree")
+	testCache(t, "sqlite")
+	testQuota(t, "fstree")
+	testQuota(t, "hashmap-snapshot")
+	testCursor(t, "badger")
+	testCursor(t, "bbolt")
+	testCursor(t, "fstree")
+	testCursor(t, "hashmap")
+	testInjected(t)
 
 	err = MaintainRecordStates()
 	if err != nil {
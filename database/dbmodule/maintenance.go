@@ -9,10 +9,14 @@
 	"github.com/safing/portbase/modules"
 )
 
+// IdleTimeout is the duration after which unused databases are unloaded.
+var IdleTimeout = 1 * time.Hour
+
 func registerMaintenanceTasks() {
 	module.NewTask("basic maintenance", maintainBasic).Repeat(10 * time.Minute).MaxDelay(10 * time.Minute)
 	module.NewTask("thorough maintenance", maintainThorough).Repeat(1 * time.Hour).MaxDelay(1 * time.Hour)
 	module.NewTask("record maintenance", maintainRecords).Repeat(1 * time.Hour).MaxDelay(1 * time.Hour)
+	module.NewTask("unload idle databases", unloadIdle).Repeat(10 * time.Minute).MaxDelay(10 * time.Minute)
 }
 
 func maintainBasic(ctx context.Context, task *modules.Task) {
@@ -35,3 +39,10 @@ func maintainRecords(ctx context.Context, task *modules.Task) {
 		log.Errorf("database: record states maintenance error: %s", err)
 	}
 }
+
+func unloadIdle(ctx context.Context, task *modules.Task) {
+	err := database.UnloadIdle(IdleTimeout)
+	if err != nil {
+		log.Errorf("database: failed to unload idle databases: %s", err)
+	}
+}
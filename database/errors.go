@@ -6,8 +6,16 @@
 
 // Errors
 var (
-	ErrNotFound         = errors.New("database entry could not be found")
-	ErrPermissionDenied = errors.New("access to database record denied")
-	ErrReadOnly         = errors.New("database is read only")
-	ErrShuttingDown     = errors.New("database system is shutting down")
+	ErrNotFound           = errors.New("database entry could not be found")
+	ErrPermissionDenied   = errors.New("access to database record denied")
+	ErrReadOnly           = errors.New("database is read only")
+	ErrShuttingDown       = errors.New("database system is shutting down")
+	ErrDatabaseUnloaded   = errors.New("database was unloaded")
+	ErrSearchNotEnabled   = errors.New("full-text search is not enabled for this database")
+	ErrInvalidSignature   = errors.New("database record signature verification failed")
+	ErrDatabaseInUse      = errors.New("database has active hooks or subscriptions")
+	ErrQuotaExceeded      = errors.New("database quota exceeded")
+	ErrInvalidCursorToken = errors.New("invalid cursor token")
+	ErrInvalidPatch       = errors.New("invalid patch: must be a JSON Patch (RFC 6902) array or a JSON Merge Patch (RFC 7396) object")
+	ErrConcurrentChange   = errors.New("record was changed concurrently too often")
 )
@@ -0,0 +1,147 @@
+// Code generated by accessorgen. DO NOT EDIT.
+
+package database
+
+import "github.com/safing/portbase/database/accessor"
+
+// GeneratedAccessor returns a typed accessor for Example.
+func (obj *Example) GeneratedAccessor() accessor.Accessor {
+	return accessor.NewGeneratedAccessor(accessorFuncsExample{obj})
+}
+
+// accessorFuncsExample implements accessor.GeneratedFuncs for Example.
+type accessorFuncsExample struct {
+	obj *Example
+}
+
+func (f accessorFuncsExample) Get(key string) (interface{}, bool) {
+	return accessorExampleGet(f.obj, key)
+}
+
+func (f accessorFuncsExample) GetString(key string) (string, bool) {
+	return accessorExampleGetString(f.obj, key)
+}
+
+func (f accessorFuncsExample) GetStringArray(key string) ([]string, bool) {
+	return nil, false
+}
+
+func (f accessorFuncsExample) GetInt(key string) (int64, bool) {
+	return accessorExampleGetInt(f.obj, key)
+}
+
+func (f accessorFuncsExample) GetFloat(key string) (float64, bool) {
+	return 0, false
+}
+
+func (f accessorFuncsExample) GetBool(key string) (bool, bool) {
+	return false, false
+}
+
+func (f accessorFuncsExample) Set(key string, value interface{}) error {
+	return accessorExampleSet(f.obj, key, key, value)
+}
+
+func accessorExampleGet(obj *Example, path string) (interface{}, bool) {
+	if path == "" {
+		return accessor.GetField(obj, "")
+	}
+	field, rest := accessor.SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringGet(&obj.Name, rest)
+	case "Score":
+		return accessorIntGet(&obj.Score, rest)
+	}
+	return nil, false
+}
+
+func accessorExampleGetString(obj *Example, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	field, rest := accessor.SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringGetString(&obj.Name, rest)
+	}
+	return "", false
+}
+
+func accessorExampleGetInt(obj *Example, path string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	field, rest := accessor.SplitKey(path)
+	switch field {
+	case "Score":
+		return accessorIntGetInt(&obj.Score, rest)
+	}
+	return 0, false
+}
+
+func accessorExampleSet(obj *Example, key, path string, value interface{}) error {
+	if path == "" {
+		return accessor.SetField(obj, "", key, value)
+	}
+	field, rest := accessor.SplitKey(path)
+	switch field {
+	case "Name":
+		return accessorStringSet(&obj.Name, key, rest, value)
+	case "Score":
+		return accessorIntSet(&obj.Score, key, rest, value)
+	}
+	return accessor.ErrCannotSet(key)
+}
+
+func accessorStringGet(obj *string, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorIntGet(obj *int, path string) (interface{}, bool) {
+	if path != "" {
+		return nil, false
+	}
+	return *obj, true
+}
+
+func accessorStringGetString(obj *string, path string) (string, bool) {
+	if path != "" {
+		return "", false
+	}
+	return *obj, true
+}
+
+func accessorIntGetInt(obj *int, path string) (int64, bool) {
+	if path != "" {
+		return 0, false
+	}
+	return int64(*obj), true
+}
+
+func accessorStringSet(obj *string, key, path string, value interface{}) error {
+	if path != "" {
+		return accessor.ErrCannotSet(key)
+	}
+	v, err := accessor.ConvertString(key, value)
+	if err != nil {
+		return err
+	}
+	*obj = v
+	return nil
+}
+
+func accessorIntSet(obj *int, key, path string, value interface{}) error {
+	if path != "" {
+		return accessor.ErrCannotSet(key)
+	}
+	v, err := accessor.ConvertInt(key, value, 0)
+	if err != nil {
+		return err
+	}
+	*obj = int(v)
+	return nil
+}
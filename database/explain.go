@@ -0,0 +1,78 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/storage"
+)
+
+// Query execution strategies.
+const (
+	StrategyPrefixScan  = "prefix scan"
+	StrategySearchIndex = "search index"
+)
+
+// Post-filters applied to every record read by a query.
+const (
+	FilterValidity   = "validity"
+	FilterPermission = "permission"
+	FilterWhere      = "where"
+)
+
+// QueryPlan describes how a query will be executed.
+type QueryPlan struct {
+	Query       string
+	Strategy    string
+	KeyPrefix   string
+	SearchTerms []string
+	PostFilters []string
+	// EstimatedCost is the estimated number of records that will be read, or -1 if the storage cannot provide an estimate.
+	EstimatedCost int
+}
+
+// Explain returns the execution plan for the given query.
+func (c *Controller) Explain(q *query.Query) (*QueryPlan, error) {
+	c.readLock.RLock()
+	defer c.readLock.RUnlock()
+
+	if err := c.stoppedErr(); err != nil {
+		return nil, err
+	}
+
+	plan := &QueryPlan{
+		Query:         q.Print(),
+		Strategy:      StrategyPrefixScan,
+		KeyPrefix:     q.DatabaseKeyPrefix(),
+		PostFilters:   []string{FilterValidity, FilterPermission},
+		EstimatedCost: -1,
+	}
+	if q.HasWhereCondition() {
+		plan.PostFilters = append(plan.PostFilters, FilterWhere)
+	}
+
+	if q.HasSearch() {
+		if c.search == nil {
+			return nil, ErrSearchNotEnabled
+		}
+		err := c.search.build(c.storage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build search index: %s", err)
+		}
+
+		plan.Strategy = StrategySearchIndex
+		plan.SearchTerms = q.SearchTerms()
+		plan.EstimatedCost = len(c.search.find(q.DatabaseKeyPrefix(), q.SearchTerms()))
+		return plan, nil
+	}
+
+	if counter, ok := c.storage.(storage.KeyCounter); ok {
+		n, err := counter.CountKeys(q.DatabaseKeyPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate query cost: %s", err)
+		}
+		plan.EstimatedCost = n
+	}
+
+	return plan, nil
+}
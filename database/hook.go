@@ -30,22 +30,35 @@ func RegisterHook(q *query.Query, hook Hook) (*RegisteredHook, error) {
 		return nil, err
 	}
 
-	c, err := getController(q.DatabaseName())
+	rh := &RegisteredHook{
+		q: q,
+		h: hook,
+	}
+	err = retryUnloaded(func() error {
+		c, err := getController(q.DatabaseName())
+		if err != nil {
+			return err
+		}
+		return c.addHook(rh)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return rh, nil
+}
 
+func (c *Controller) addHook(rh *RegisteredHook) error {
 	c.readLock.Lock()
 	defer c.readLock.Unlock()
 	c.writeLock.Lock()
 	defer c.writeLock.Unlock()
 
-	rh := &RegisteredHook{
-		q: q,
-		h: hook,
+	if err := c.stoppedErr(); err != nil {
+		return err
 	}
+
 	c.hooks = append(c.hooks, rh)
-	return rh, nil
+	return nil
 }
 
 // Cancel unhooks the hook.
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/safing/portbase/database/iterator"
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+)
+
+// InjectedGetter returns the object with the given database key, or ErrNotFound.
+type InjectedGetter func(key string) (record.Record, error)
+
+// InjectedSetter applies a record that was written to the database. Deleted records are marked as deleted in their metadata. The record is locked.
+type InjectedSetter func(r record.Record) error
+
+// InjectedLister returns all objects with a database key that starts with the given prefix.
+type InjectedLister func(prefix string) ([]record.Record, error)
+
+// InjectedDatabase provides objects held in memory as a database, eg. runtime state. It takes care of query matching, permission checks and read-only enforcement; the objects are accessed through a getter, a setter and a lister. The objects must have their key and metadata set.
+type InjectedDatabase struct {
+	storage.InjectBase
+
+	getter     InjectedGetter
+	setter     InjectedSetter
+	lister     InjectedLister
+	controller *Controller
+}
+
+// NewInjectedDatabase registers and injects a database that is backed by the given functions. If setter is nil, the database is read only.
+func NewInjectedDatabase(name, description string, getter InjectedGetter, setter InjectedSetter, lister InjectedLister) (*InjectedDatabase, error) {
+	if getter == nil || lister == nil {
+		return nil, errors.New("injected database needs a getter and a lister")
+	}
+
+	_, err := Register(&Database{
+		Name:        name,
+		Description: description,
+		StorageType: "injected",
+		PrimaryAPI:  "",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db := &InjectedDatabase{
+		getter: getter,
+		setter: setter,
+		lister: lister,
+	}
+	db.controller, err = InjectDatabase(name, db)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// PushUpdate informs subscribers about a changed object. It must be called whenever an object changes, except when changed by the setter. It may be called before the database is injected.
+func (db *InjectedDatabase) PushUpdate(r record.Record) {
+	if db != nil {
+		db.controller.PushUpdate(r)
+	}
+}
+
+// Get returns a database record.
+func (db *InjectedDatabase) Get(key string) (record.Record, error) {
+	r, err := db.getter(key)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Put stores a record in the database.
+func (db *InjectedDatabase) Put(r record.Record) error {
+	if db.setter == nil {
+		return ErrReadOnly
+	}
+	return db.setter(r)
+}
+
+// Query returns a an iterator for the supplied query.
+func (db *InjectedDatabase) Query(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
+	_, err := q.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := db.lister(q.DatabaseKeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].DatabaseKey() < records[j].DatabaseKey()
+	})
+
+	it := iterator.New()
+	go db.queryExecutor(it, q, records, local, internal)
+	return it, nil
+}
+
+func (db *InjectedDatabase) queryExecutor(it *iterator.Iterator, q *query.Query, records []record.Record, local, internal bool) {
+	for _, r := range records {
+		// listers may return more than asked for
+		if !strings.HasPrefix(r.DatabaseKey(), q.DatabaseKeyPrefix()) {
+			continue
+		}
+		it.CountScanned()
+
+		r.Lock()
+		valid := r.Meta().CheckValidity()
+		permitted := r.Meta().CheckPermission(local, internal)
+		matches := q.MatchesRecord(r)
+		r.Unlock()
+
+		// check validity / access
+		if !valid {
+			it.CountSkippedValidity()
+			continue
+		}
+		if !permitted {
+			it.CountSkippedPermission()
+			continue
+		}
+
+		// check if matches & send
+		if matches {
+			it.CountMatched()
+			select {
+			case <-it.Done:
+				it.Finish(nil)
+				return
+			case it.Next <- r:
+			case <-time.After(1 * time.Second):
+				it.Finish(errors.New("query timeout"))
+				return
+			}
+		}
+	}
+	it.Finish(nil)
+}
+
+// ReadOnly returns whether the database is read only.
+func (db *InjectedDatabase) ReadOnly() bool {
+	return db.setter == nil
+}
@@ -6,6 +6,7 @@
 	"time"
 
 	"github.com/bluele/gcache"
+	"github.com/tevino/abool"
 
 	"github.com/safing/portbase/database/accessor"
 	"github.com/safing/portbase/database/iterator"
@@ -84,9 +85,21 @@ func (i *Interface) updateCache(r record.Record) {
 	}
 }
 
+// retryUnloaded calls fn once more, if it failed because the database was unloaded in the meantime. The second call gets the controller of the database again, which starts it again.
+func retryUnloaded(fn func() error) error {
+	err := fn()
+	if err == ErrDatabaseUnloaded {
+		err = fn()
+	}
+	return err
+}
+
 // Exists return whether a record with the given key exists.
-func (i *Interface) Exists(key string) (bool, error) {
-	_, _, err := i.getRecord(getDBFromKey, key, false, false)
+func (i *Interface) Exists(key string) (exists bool, err error) {
+	err = retryUnloaded(func() error {
+		_, _, err := i.getRecord(getDBFromKey, key, false, false)
+		return err
+	})
 	if err != nil {
 		if err == ErrNotFound {
 			return false, nil
@@ -97,7 +110,7 @@ func (i *Interface) Exists(key string) (bool, error) {
 }
 
 // Get return the record with the given key.
-func (i *Interface) Get(key string) (record.Record, error) {
+func (i *Interface) Get(key string) (r record.Record, err error) {
 	r, ok := i.checkCache(key)
 	if ok {
 		if !r.Meta().CheckPermission(i.options.Local, i.options.Internal) {
@@ -106,7 +119,10 @@ func (i *Interface) Get(key string) (record.Record, error) {
 		return r, nil
 	}
 
-	r, _, err := i.getRecord(getDBFromKey, key, true, false)
+	err = retryUnloaded(func() (err error) {
+		r, _, err = i.getRecord(getDBFromKey, key, true, false)
+		return err
+	})
 	return r, err
 }
 
@@ -141,6 +157,12 @@ func (i *Interface) getRecord(dbName string, dbKey string, check bool, mustBeWri
 
 // InsertValue inserts a value into a record.
 func (i *Interface) InsertValue(key string, attribute string, value interface{}) error {
+	return retryUnloaded(func() error {
+		return i.insertValue(key, attribute, value)
+	})
+}
+
+func (i *Interface) insertValue(key string, attribute string, value interface{}) error {
 	r, db, err := i.getRecord(getDBFromKey, key, true, true)
 	if err != nil {
 		return err
@@ -171,22 +193,19 @@ func (i *Interface) InsertValue(key string, attribute string, value interface{})
 
 // Put saves a record to the database.
 func (i *Interface) Put(r record.Record) error {
-	_, db, err := i.getRecord(r.DatabaseName(), r.DatabaseKey(), true, true)
-	if err != nil && err != ErrNotFound {
-		return err
-	}
-
-	r.Lock()
-	defer r.Unlock()
-
-	i.options.Apply(r)
-
-	i.updateCache(r)
-	return db.Put(r)
+	return retryUnloaded(func() error {
+		return i.put(r, false)
+	})
 }
 
 // PutNew saves a record to the database as a new record (ie. with new timestamps).
 func (i *Interface) PutNew(r record.Record) error {
+	return retryUnloaded(func() error {
+		return i.put(r, true)
+	})
+}
+
+func (i *Interface) put(r record.Record, asNew bool) error {
 	_, db, err := i.getRecord(r.DatabaseName(), r.DatabaseKey(), true, true)
 	if err != nil && err != ErrNotFound {
 		return err
@@ -195,100 +214,134 @@ func (i *Interface) PutNew(r record.Record) error {
 	r.Lock()
 	defer r.Unlock()
 
-	if r.Meta() == nil {
-		r.CreateMeta()
+	if asNew {
+		if r.Meta() == nil {
+			r.CreateMeta()
+		}
+		r.Meta().Reset()
 	}
-	r.Meta().Reset()
 	i.options.Apply(r)
 	i.updateCache(r)
 	return db.Put(r)
 }
 
-// SetAbsoluteExpiry sets an absolute record expiry.
-func (i *Interface) SetAbsoluteExpiry(key string, time int64) error {
-	r, db, err := i.getRecord(getDBFromKey, key, true, true)
-	if err != nil {
+// PutIf saves a record to the database with its metadata as is, if cond returns true for the currently stored version of the record. The stored version is nil if there is none, and it may be deleted or expired. This is meant for importing records from other sources, such as other nodes.
+func (i *Interface) PutIf(r record.Record, cond func(stored record.Record) bool) (saved bool, err error) {
+	err = retryUnloaded(func() (err error) {
+		saved, err = i.putIf(r, cond)
 		return err
+	})
+	return saved, err
+}
+
+func (i *Interface) putIf(r record.Record, cond func(stored record.Record) bool) (saved bool, err error) {
+	db, err := getController(r.DatabaseName())
+	if err != nil {
+		return false, err
+	}
+	if db.ReadOnly() {
+		return false, ErrReadOnly
 	}
 
 	r.Lock()
 	defer r.Unlock()
 
-	i.options.Apply(r)
-	r.Meta().SetAbsoluteExpiry(time)
-	return db.Put(r)
+	saved, err = db.PutIf(r, cond)
+	if saved {
+		i.updateCache(r)
+	}
+	return saved, err
+}
+
+// SetAbsoluteExpiry sets an absolute record expiry.
+func (i *Interface) SetAbsoluteExpiry(key string, time int64) error {
+	return i.updateMeta(key, func(m *record.Meta) {
+		m.SetAbsoluteExpiry(time)
+	})
 }
 
 // SetRelativateExpiry sets a relative (self-updating) record expiry.
 func (i *Interface) SetRelativateExpiry(key string, duration int64) error {
-	r, db, err := i.getRecord(getDBFromKey, key, true, true)
-	if err != nil {
-		return err
-	}
-
-	r.Lock()
-	defer r.Unlock()
-
-	i.options.Apply(r)
-	r.Meta().SetRelativateExpiry(duration)
-	return db.Put(r)
+	return i.updateMeta(key, func(m *record.Meta) {
+		m.SetRelativateExpiry(duration)
+	})
 }
 
 // MakeSecret marks the record as a secret, meaning interfacing processes, such as an UI, are denied access to the record.
 func (i *Interface) MakeSecret(key string) error {
-	r, db, err := i.getRecord(getDBFromKey, key, true, true)
-	if err != nil {
-		return err
-	}
-
-	r.Lock()
-	defer r.Unlock()
-
-	i.options.Apply(r)
-	r.Meta().MakeSecret()
-	return db.Put(r)
+	return i.updateMeta(key, (*record.Meta).MakeSecret)
 }
 
 // MakeCrownJewel marks a record as a crown jewel, meaning it will only be accessible locally.
 func (i *Interface) MakeCrownJewel(key string) error {
-	r, db, err := i.getRecord(getDBFromKey, key, true, true)
-	if err != nil {
-		return err
-	}
+	return i.updateMeta(key, (*record.Meta).MakeCrownJewel)
+}
 
-	r.Lock()
-	defer r.Unlock()
+// updateMeta applies the options and the given change to the metadata of the record and saves it.
+func (i *Interface) updateMeta(key string, change func(m *record.Meta)) error {
+	return retryUnloaded(func() error {
+		r, db, err := i.getRecord(getDBFromKey, key, true, true)
+		if err != nil {
+			return err
+		}
 
-	i.options.Apply(r)
-	r.Meta().MakeCrownJewel()
-	return db.Put(r)
+		r.Lock()
+		defer r.Unlock()
+
+		i.options.Apply(r)
+		change(r.Meta())
+		return db.Put(r)
+	})
 }
 
 // Delete deletes a record from the database.
 func (i *Interface) Delete(key string) error {
-	r, db, err := i.getRecord(getDBFromKey, key, true, true)
-	if err != nil {
-		return err
-	}
+	return retryUnloaded(func() error {
+		r, db, err := i.getRecord(getDBFromKey, key, true, true)
+		if err != nil {
+			return err
+		}
 
-	i.options.Apply(r)
-	r.Meta().Delete()
-	return db.Put(r)
+		i.options.Apply(r)
+		r.Meta().Delete()
+		return db.Put(r)
+	})
 }
 
 // Query executes the given query on the database.
-func (i *Interface) Query(q *query.Query) (*iterator.Iterator, error) {
-	_, err := q.Check()
+func (i *Interface) Query(q *query.Query) (it *iterator.Iterator, err error) {
+	_, err = q.Check()
 	if err != nil {
 		return nil, err
 	}
 
-	db, err := getController(q.DatabaseName())
+	err = retryUnloaded(func() error {
+		db, err := getController(q.DatabaseName())
+		if err != nil {
+			return err
+		}
+		it, err = db.Query(q, i.options.Local, i.options.Internal)
+		return err
+	})
+	return it, err
+}
+
+// Explain returns the execution plan for the given query without running it.
+func (i *Interface) Explain(q *query.Query) (plan *QueryPlan, err error) {
+	_, err = q.Check()
 	if err != nil {
 		return nil, err
 	}
 
-	return db.Query(q, i.options.Local, i.options.Internal)
+	err = retryUnloaded(func() error {
+		db, err := getController(q.DatabaseName())
+		if err != nil {
+			return err
+		}
+		plan, err = db.Explain(q)
+		return err
+	})
+	return plan, err
 }
 
 // Subscribe subscribes to updates matching the given query.
@@ -297,18 +350,26 @@ func (i *Interface) Subscribe(q *query.Query) (*Subscription, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	c, err := getController(q.DatabaseName())
-	if err != nil {
-		return nil, err
+	if q.HasSearch() {
+		return nil, errors.New("full-text search queries cannot be subscribed to")
 	}
 
 	sub := &Subscription{
 		q:        q,
 		local:    i.options.Local,
 		internal: i.options.Internal,
+		dropped:  abool.NewBool(false),
 		Feed:     make(chan record.Record, 1000),
 	}
-	c.addSubscription(sub)
+	err = retryUnloaded(func() error {
+		c, err := getController(q.DatabaseName())
+		if err != nil {
+			return err
+		}
+		return c.addSubscription(sub)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return sub, nil
 }
@@ -2,6 +2,8 @@
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/tevino/abool"
 
@@ -10,12 +12,29 @@
 
 // Iterator defines the iterator structure.
 type Iterator struct {
+	stats Stats // first for 64-bit alignment of atomic counters
+
 	Next chan record.Record
 	Done chan struct{}
 
 	errLock    sync.Mutex
 	err        error
 	doneClosed *abool.AtomicBool
+
+	started time.Time
+	elapsed time.Duration
+
+	// source is the iterator a filtered iterator reads from.
+	source *Iterator
+}
+
+// Stats holds execution statistics of a query.
+type Stats struct {
+	Scanned           uint64
+	Matched           uint64
+	SkippedPermission uint64
+	SkippedValidity   uint64
+	Elapsed           time.Duration
 }
 
 // New creates a new Iterator.
@@ -24,11 +43,16 @@ func New() *Iterator {
 		Next:       make(chan record.Record, 10),
 		Done:       make(chan struct{}),
 		doneClosed: abool.NewBool(false),
+		started:    time.Now(),
 	}
 }
 
 // Finish is called be the storage to signal the end of the query results.
 func (it *Iterator) Finish(err error) {
+	it.errLock.Lock()
+	it.elapsed = time.Since(it.started)
+	it.errLock.Unlock()
+
 	close(it.Next)
 	if it.doneClosed.SetToIf(false, true) {
 		close(it.Done)
@@ -52,3 +76,77 @@ func (it *Iterator) Err() error {
 	defer it.errLock.Unlock()
 	return it.err
 }
+
+// CountScanned is called by the storage for every record it reads.
+func (it *Iterator) CountScanned() {
+	atomic.AddUint64(&it.stats.Scanned, 1)
+}
+
+// CountMatched is called by the storage for every record it returns.
+func (it *Iterator) CountMatched() {
+	atomic.AddUint64(&it.stats.Matched, 1)
+}
+
+// CountSkippedPermission is called by the storage for every record it skips because of missing permissions.
+func (it *Iterator) CountSkippedPermission() {
+	atomic.AddUint64(&it.stats.SkippedPermission, 1)
+}
+
+// CountSkippedValidity is called by the storage for every record it skips because it is deleted or expired.
+func (it *Iterator) CountSkippedValidity() {
+	atomic.AddUint64(&it.stats.SkippedValidity, 1)
+}
+
+// Stats returns the execution statistics of the query. The elapsed time is only set after the query finished.
+func (it *Iterator) Stats() Stats {
+	it.errLock.Lock()
+	elapsed := it.elapsed
+	it.errLock.Unlock()
+
+	stats := Stats{
+		Scanned:           atomic.LoadUint64(&it.stats.Scanned),
+		Matched:           atomic.LoadUint64(&it.stats.Matched),
+		SkippedPermission: atomic.LoadUint64(&it.stats.SkippedPermission),
+		SkippedValidity:   atomic.LoadUint64(&it.stats.SkippedValidity),
+		Elapsed:           elapsed,
+	}
+	if it.source != nil {
+		// records filtered out were matched by the source
+		sourceStats := it.source.Stats()
+		stats.Scanned += sourceStats.Scanned
+		stats.Matched += sourceStats.Matched - stats.SkippedValidity
+		stats.SkippedPermission += sourceStats.SkippedPermission
+		stats.SkippedValidity += sourceStats.SkippedValidity
+	}
+	return stats
+}
+
+// Filter returns an iterator that returns the records of the iterator for which keep returns true. Records that are filtered out are counted as skipped for validity. Canceling the returned iterator cancels the iterator.
+func (it *Iterator) Filter(keep func(record.Record) bool) *Iterator {
+	filtered := New()
+	filtered.started = it.started
+	filtered.source = it
+
+	go func() {
+		for r := range it.Next {
+			if !keep(r) {
+				filtered.CountSkippedValidity()
+				continue
+			}
+
+			select {
+			case filtered.Next <- r:
+			case <-filtered.Done:
+				it.Cancel()
+				// let the source finish
+				for range it.Next {
+				}
+				filtered.Finish(it.Err())
+				return
+			}
+		}
+		filtered.Finish(it.Err())
+	}()
+
+	return filtered
+}
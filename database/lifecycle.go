@@ -0,0 +1,178 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/safing/portbase/database/record"
+)
+
+// Unload shuts down the storage of the given database. The database is started again when it is used the next time. Databases with active hooks or subscriptions and injected databases cannot be unloaded.
+func Unload(name string) error {
+	controllersLock.Lock()
+	defer controllersLock.Unlock()
+
+	if shuttingDown.IsSet() {
+		return ErrShuttingDown
+	}
+
+	c, ok := controllers[name]
+	if !ok {
+		return nil
+	}
+	if c.Injected() {
+		return fmt.Errorf(`database "%s" is injected and cannot be unloaded`, name)
+	}
+	return unloadController(name, c)
+}
+
+// UnloadIdle unloads all databases that were not used within maxIdle. Databases with active hooks or subscriptions and injected databases are kept.
+func UnloadIdle(maxIdle time.Duration) error {
+	controllersLock.Lock()
+	defer controllersLock.Unlock()
+
+	if shuttingDown.IsSet() {
+		return nil
+	}
+
+	var lastErr error
+	threshold := time.Now().Add(-maxIdle)
+	for name, c := range controllers {
+		if c.Injected() || c.LastUsed().After(threshold) {
+			continue
+		}
+
+		err := unloadController(name, c)
+		if err != nil && err != ErrDatabaseInUse {
+			lastErr = fmt.Errorf("failed to unload database %s: %s", name, err)
+		}
+	}
+	return lastErr
+}
+
+// unloadController shuts down the given controller and removes it. If the controller cannot be shut down, it is kept, as its storage may still be open and records may still wait to be flushed. The caller must hold controllersLock.
+func unloadController(name string, c *Controller) error {
+	err := c.unload()
+	if err != nil {
+		return err
+	}
+	delete(controllers, name)
+
+	// record last use
+	registryLock.Lock()
+	registeredDB, ok := registry[name]
+	if ok {
+		registeredDB.LastLoaded = c.LastUsed()
+		writeRegistrySoon.Set()
+	}
+	registryLock.Unlock()
+
+	return nil
+}
+
+// Delete unloads the given database, removes it from the registry and deletes all of its data. Databases with active hooks or subscriptions and injected databases cannot be deleted.
+func Delete(name string) error {
+	controllersLock.Lock()
+	defer controllersLock.Unlock()
+
+	if shuttingDown.IsSet() {
+		return ErrShuttingDown
+	}
+
+	registryLock.Lock()
+	registeredDB, ok := registry[name]
+	registryLock.Unlock()
+	switch {
+	case !ok:
+		return fmt.Errorf(`database "%s" not registered`, name)
+	case registeredDB.StorageType == "injected":
+		return fmt.Errorf(`database "%s" is injected and cannot be deleted`, name)
+	}
+
+	c, ok := controllers[name]
+	if ok {
+		err := unloadController(name, c)
+		if err == ErrDatabaseInUse {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to shut down database %s: %s", name, err)
+		}
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	delete(registry, name)
+	record.SetDatabaseCompression(name, record.AUTO)
+	err := saveRegistry(false)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(databasesStructure.Path, name))
+}
+
+// Rename renames the given database. The database is unloaded for the move and started with its new name when it is used the next time. Databases with active hooks or subscriptions and injected databases cannot be renamed.
+func Rename(oldName, newName string) error {
+	if !nameConstraint.MatchString(newName) {
+		return errors.New("database name must only contain alphanumeric and `_-` characters and must be at least 4 characters long")
+	}
+
+	controllersLock.Lock()
+	defer controllersLock.Unlock()
+
+	if shuttingDown.IsSet() {
+		return ErrShuttingDown
+	}
+
+	registryLock.Lock()
+	registeredDB, ok := registry[oldName]
+	_, exists := registry[newName]
+	registryLock.Unlock()
+	switch {
+	case !ok:
+		return fmt.Errorf(`database "%s" not registered`, oldName)
+	case exists:
+		return fmt.Errorf(`database "%s" already registered`, newName)
+	case registeredDB.StorageType == "injected":
+		return fmt.Errorf(`database "%s" is injected and cannot be renamed`, oldName)
+	}
+
+	c, ok := controllers[oldName]
+	if ok {
+		err := unloadController(oldName, c)
+		if err == ErrDatabaseInUse {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to shut down database %s: %s", oldName, err)
+		}
+	}
+
+	// move data
+	oldPath := filepath.Join(databasesStructure.Path, oldName)
+	newPath := filepath.Join(databasesStructure.Path, newName)
+	_, err := os.Stat(newPath)
+	if err == nil {
+		return fmt.Errorf(`database directory "%s" already exists`, newPath)
+	}
+	err = os.Rename(oldPath, newPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move database directory: %s", err)
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	delete(registry, oldName)
+	registeredDB.Name = newName
+	registeredDB.Updated()
+	registry[newName] = registeredDB
+	record.SetDatabaseCompression(oldName, record.AUTO)
+	record.SetDatabaseCompression(newName, registeredDB.Compression)
+	return saveRegistry(false)
+}
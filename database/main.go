@@ -73,7 +73,7 @@ func Shutdown() (err error) {
 	return
 }
 
-// getLocation returns the storage location for the given name and type.
+// getLocation returns the storage location for the given name and type. The directory is created if it does not exist, which storage.CreateDatabase still treats as holding no database.
 func getLocation(name, storageType string) (string, error) {
 	location := databasesStructure.ChildDir(name, 0700).ChildDir(storageType, 0700)
 	// check location
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/safing/portbase/database/query"
@@ -19,7 +20,7 @@ func Maintain() (err error) {
 	return
 }
 
-// MaintainThorough runs the MaintainThorough method on all storages.
+// MaintainThorough runs the MaintainThorough method on all storages and verifies their integrity afterwards. Databases that use RecoveryFromBackup are backed up once a day.
 func MaintainThorough() (err error) {
 	all := duplicateControllers()
 	for _, c := range all {
@@ -28,7 +29,11 @@ func MaintainThorough() (err error) {
 			return
 		}
 	}
-	return
+	err = CheckStorages()
+	if err != nil {
+		return err
+	}
+	return backupDatabases()
 }
 
 // MaintainRecordStates runs record state lifecycle maintenance on all storages.
@@ -69,7 +74,7 @@ func MaintainRecordStates() error {
 		}
 
 		for _, r := range toDelete {
-			err := c.storage.Delete(r.DatabaseKey())
+			err := c.purgeDeleted(r.DatabaseKey())
 			if err != nil {
 				return err
 			}
@@ -86,6 +91,74 @@ func MaintainRecordStates() error {
 	return nil
 }
 
+// upgradeRecords saves all records that were loaded from an older record format again, which converts them to the current format. Records are written to the storage directly, so that their metadata stays untouched. Invalid records are not returned by storages and are left to be purged by MaintainRecordStates.
+func (c *Controller) upgradeRecords() error {
+	if c.ReadOnly() || c.Injected() {
+		return nil
+	}
+
+	// writes must wait, as the stale version read here would overwrite them
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if c.stopped() {
+		return nil
+	}
+
+	// records waiting to be written are newer than the stored ones
+	err := c.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %s", err)
+	}
+
+	q, err := query.New("").Check()
+	if err != nil {
+		return err
+	}
+
+	it, err := c.storage.Query(q, true, true)
+	if err != nil {
+		return err
+	}
+
+	var toUpgrade []record.Record
+	for r := range it.Next {
+		if wrapper, ok := r.(*record.Wrapper); ok && wrapper.NeedsUpgrade() {
+			toUpgrade = append(toUpgrade, r)
+		}
+	}
+	if it.Err() != nil {
+		return it.Err()
+	}
+
+	// quota sizes are always accounted in the current format and do not change
+	for _, r := range toUpgrade {
+		err := c.upgradeRecord(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeRecord writes the record to the storage in the current format. The caller must hold the write lock.
+func (c *Controller) upgradeRecord(r record.Record) error {
+	r.Lock()
+	defer r.Unlock()
+
+	err := c.storage.Put(r)
+	if err != nil {
+		return err
+	}
+	if c.cache != nil {
+		c.cache.invalidate(r.DatabaseKey())
+	}
+	if c.search != nil {
+		c.search.update(r)
+	}
+	return nil
+}
+
 func duplicateControllers() (all []*Controller) {
 	controllersLock.Lock()
 	defer controllersLock.Unlock()
@@ -96,3 +169,24 @@ func duplicateControllers() (all []*Controller) {
 
 	return
 }
+
+// purgeDeleted removes a deleted record from the storage.
+func (c *Controller) purgeDeleted(dbKey string) error {
+	c.writeLock.RLock()
+	defer c.writeLock.RUnlock()
+
+	err := c.storage.Delete(dbKey)
+	if err != nil {
+		return err
+	}
+	if c.cache != nil {
+		c.cache.invalidate(dbKey)
+	}
+	if c.quota != nil {
+		c.quota.remove(dbKey)
+	}
+	if c.search != nil {
+		c.search.delete(dbKey)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/formats/dsd"
+)
+
+var (
+	baseType   = reflect.TypeOf(record.Base{})
+	lockerType = reflect.TypeOf((*sync.Locker)(nil)).Elem()
+)
+
+// maxPatchAttempts is the number of times a patch is applied again, if the record was changed concurrently.
+const maxPatchAttempts = 10
+
+// Patch applies a patch to the record with the given key. The patch may either be a JSON Patch (RFC 6902), which is an array of operations, or a JSON Merge Patch (RFC 7396), which is an object. The patched record is only saved if the record was not changed in the meantime, otherwise the patch is applied again to the new version, so concurrent writes are never overwritten.
+func (i *Interface) Patch(key string, patch []byte) error {
+	for attempt := 0; attempt < maxPatchAttempts; attempt++ {
+		var saved bool
+		err := retryUnloaded(func() (err error) {
+			saved, err = i.patch(key, patch)
+			return err
+		})
+		if err != nil || saved {
+			return err
+		}
+	}
+	return ErrConcurrentChange
+}
+
+// patch applies the patch to the current version of the record and saves it, if the stored version is still the same.
+func (i *Interface) patch(key string, patch []byte) (saved bool, err error) {
+	r, db, err := i.getRecord(getDBFromKey, key, true, true)
+	if err != nil {
+		return false, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// remember the version the patch is applied to
+	modified, revision := r.Meta().Modified, r.Meta().Revision
+
+	// get current data as JSON
+	data, err := r.Marshal(r, record.JSON)
+	if err != nil {
+		return false, err
+	}
+	if len(data) < 1 {
+		return false, errors.New("record has no data")
+	}
+	// strip format
+	data = data[1:]
+
+	patched, err := ApplyPatch(data, patch)
+	if err != nil {
+		return false, err
+	}
+
+	// apply to a copy, as the record may be shared, eg. by an injected database
+	patchedRecord, err := copyRecord(r)
+	if err != nil {
+		return false, err
+	}
+	err = setPatchedData(patchedRecord, patched)
+	if err != nil {
+		return false, err
+	}
+
+	i.options.Apply(patchedRecord)
+	saved, err = db.PutIf(patchedRecord, func(stored record.Record) bool {
+		return stored != nil && stored.Meta() != nil &&
+			stored.Meta().Modified == modified &&
+			stored.Meta().Revision == revision
+	})
+	if !saved || err != nil {
+		return saved, err
+	}
+
+	// update the original only after it was saved
+	err = setPatchedData(r, patched)
+	if err != nil {
+		return true, err
+	}
+	r.SetMeta(patchedRecord.Meta().Duplicate())
+	i.updateCache(patchedRecord)
+	return true, nil
+}
+
+// setPatchedData replaces the data of the record with the patched JSON data.
+func setPatchedData(r record.Record, patched []byte) error {
+	if r.IsWrapped() {
+		wrapper, ok := r.(*record.Wrapper)
+		if !ok {
+			return errors.New("record is malformed (reports to be wrapped but is not of type *record.Wrapper)")
+		}
+		wrapper.Format = record.JSON
+		wrapper.Data = patched
+		return nil
+	}
+	return replaceStructData(r, patched)
+}
+
+// copyRecord returns a copy of the record that can be patched without affecting the original. Struct records are copied shallowly, except for the metadata and any embedded locks. The caller must hold the record lock.
+func copyRecord(r record.Record) (record.Record, error) {
+	if r.IsWrapped() {
+		wrapper, ok := r.(*record.Wrapper)
+		if !ok {
+			return nil, errors.New("record is malformed (reports to be wrapped but is not of type *record.Wrapper)")
+		}
+		return wrapper.Copy(), nil
+	}
+
+	src := reflect.ValueOf(r)
+	if src.Kind() != reflect.Ptr || src.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot patch record of type %T", r)
+	}
+	dst := reflect.New(src.Elem().Type())
+	dst.Elem().Set(src.Elem())
+
+	// do not copy the lock state
+	t := dst.Elem().Type()
+	for j := 0; j < t.NumField(); j++ {
+		field := t.Field(j)
+		if field.Anonymous && field.PkgPath == "" && reflect.PtrTo(field.Type).Implements(lockerType) {
+			dst.Elem().Field(j).Set(reflect.Zero(field.Type))
+		}
+	}
+
+	copied, ok := dst.Interface().(record.Record)
+	if !ok {
+		return nil, fmt.Errorf("cannot patch record of type %T", r)
+	}
+	if r.Meta() != nil {
+		copied.SetMeta(r.Meta().Duplicate())
+	}
+	return copied, nil
+}
+
+// ApplyPatch applies a JSON Patch (RFC 6902) or JSON Merge Patch (RFC 7396) to the given JSON document and returns the result. The type of the patch is detected by its first character.
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(patch)
+	if len(trimmed) == 0 {
+		return nil, ErrInvalidPatch
+	}
+
+	switch trimmed[0] {
+	case '[':
+		p, err := jsonpatch.DecodePatch(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON Patch: %s", err)
+		}
+		patched, err := p.Apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply JSON Patch: %s", err)
+		}
+		return patched, nil
+	case '{':
+		patched, err := jsonpatch.MergePatch(doc, trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply JSON Merge Patch: %s", err)
+		}
+		return patched, nil
+	default:
+		return nil, ErrInvalidPatch
+	}
+}
+
+// replaceStructData loads the JSON data into a new value of the type of r and copies over the exported fields. The embedded record.Base and any embedded locks are kept, as they hold the record metadata and state.
+func replaceStructData(r record.Record, data []byte) error {
+	dst := reflect.ValueOf(r)
+	if dst.Kind() != reflect.Ptr || dst.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot patch record of type %T", r)
+	}
+	dst = dst.Elem()
+
+	src := reflect.New(dst.Type())
+	_, err := dsd.LoadAsFormat(data, dsd.JSON, src.Interface())
+	if err != nil {
+		return fmt.Errorf("failed to load patched data: %s", err)
+	}
+	src = src.Elem()
+
+	t := dst.Type()
+	for j := 0; j < t.NumField(); j++ {
+		field := t.Field(j)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if field.Anonymous &&
+			(field.Type == baseType || reflect.PtrTo(field.Type).Implements(lockerType)) {
+			continue
+		}
+		dst.Field(j).Set(src.Field(j))
+	}
+
+	return nil
+}
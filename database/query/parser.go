@@ -1,8 +1,10 @@
 package query
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,15 +13,18 @@
 type snippet struct {
 	text           string
 	globalPosition int
+	param          int // placeholder number, 0 if the snippet is not a placeholder
 }
 
 // ParseQuery parses a plaintext query. Special characters (that must be escaped with a '\') are: `\()` and any whitespaces.
-func ParseQuery(query string) (*Query, error) {
+// Condition values and search texts may be given as placeholders ($1, $2, ...), which are replaced by the respective params. Placeholders are never escaped, so values bound this way need no escaping at all.
+func ParseQuery(query string, params ...interface{}) (*Query, error) {
 	snippets, err := extractSnippets(query)
 	if err != nil {
 		return nil, err
 	}
 	snippetsPos := 0
+	paramsUsed := make([]bool, len(params))
 
 	getSnippet := func() (*snippet, error) {
 		// order is important, as parseAndOr will always consume one additional snippet.
@@ -32,6 +37,16 @@ func ParseQuery(query string) (*Query, error) {
 	remainingSnippets := func() int {
 		return len(snippets) - snippetsPos
 	}
+	getValue := func(s *snippet) (interface{}, error) {
+		if s.param == 0 {
+			return s.text, nil
+		}
+		if s.param > len(params) {
+			return nil, fmt.Errorf("missing parameter for placeholder $%d at position %d", s.param, s.globalPosition)
+		}
+		paramsUsed[s.param-1] = true
+		return normalizeParam(params[s.param-1]), nil
+	}
 
 	// check for query word
 	queryWord, err := getSnippet()
@@ -62,7 +77,7 @@ func ParseQuery(query string) (*Query, error) {
 			}
 
 			// parse conditions
-			condition, err := parseAndOr(getSnippet, remainingSnippets, true)
+			condition, err := parseAndOr(getSnippet, remainingSnippets, getValue, true)
 			if err != nil {
 				return nil, err
 			}
@@ -70,6 +85,29 @@ func ParseQuery(query string) (*Query, error) {
 			snippetsPos--
 
 			q.Where(condition)
+		case "search":
+			if q.search != nil {
+				return nil, fmt.Errorf("duplicate \"%s\" clause found at position %d", command.text, command.globalPosition)
+			}
+
+			searchSnippet, err := getSnippet()
+			if err != nil {
+				return nil, err
+			}
+
+			searchValue, err := getValue(searchSnippet)
+			if err != nil {
+				return nil, err
+			}
+			searchText, ok := searchValue.(string)
+			if !ok {
+				return nil, fmt.Errorf("search parameter $%d must be a string", searchSnippet.param)
+			}
+
+			q.Search(searchText)
+			if q.search == nil {
+				return nil, fmt.Errorf("no search terms found at position %d", searchSnippet.globalPosition)
+			}
 		case "orderby":
 			if q.orderBy != "" {
 				return nil, fmt.Errorf("duplicate \"%s\" clause found at position %d", command.text, command.globalPosition)
@@ -116,6 +154,12 @@ func ParseQuery(query string) (*Query, error) {
 		}
 	}
 
+	for i, used := range paramsUsed {
+		if !used {
+			return nil, fmt.Errorf("parameter $%d is not used in query", i+1)
+		}
+	}
+
 	return q.Check()
 }
 
@@ -158,6 +202,7 @@ func extractSnippets(text string) (snippets []*snippet, err error) {
 				snippets = append(snippets, &snippet{
 					text:           prepToken(text[start:pos]),
 					globalPosition: start + 1,
+					param:          parsePlaceholder(text[start:pos]),
 				})
 				start = -1
 			}
@@ -188,6 +233,7 @@ func extractSnippets(text string) (snippets []*snippet, err error) {
 		snippets = append(snippets, &snippet{
 			text:           prepToken(text[start : pos+1]),
 			globalPosition: start + 1,
+			param:          parsePlaceholder(text[start : pos+1]),
 		})
 	}
 
@@ -195,7 +241,7 @@ func extractSnippets(text string) (snippets []*snippet, err error) {
 
 }
 
-func parseAndOr(getSnippet func() (*snippet, error), remainingSnippets func() int, rootCondition bool) (Condition, error) {
+func parseAndOr(getSnippet func() (*snippet, error), remainingSnippets func() int, getValue func(*snippet) (interface{}, error), rootCondition bool) (Condition, error) {
 	var isOr = false
 	var typeSet = false
 	var wrapInNot = false
@@ -222,7 +268,7 @@ func parseAndOr(getSnippet func() (*snippet, error), remainingSnippets func() in
 
 		if !expectingMore && rootCondition {
 			switch firstSnippet.text {
-			case "orderby", "limit", "offset":
+			case "search", "orderby", "limit", "offset":
 				if len(conditions) == 1 {
 					return conditions[0], nil
 				}
@@ -235,7 +281,7 @@ func parseAndOr(getSnippet func() (*snippet, error), remainingSnippets func() in
 
 		switch firstSnippet.text {
 		case "(":
-			condition, err := parseAndOr(getSnippet, remainingSnippets, false)
+			condition, err := parseAndOr(getSnippet, remainingSnippets, getValue, false)
 			if err != nil {
 				return nil, err
 			}
@@ -272,7 +318,7 @@ func parseAndOr(getSnippet func() (*snippet, error), remainingSnippets func() in
 			wrapInNot = true
 			expectingMore = true
 		default:
-			condition, err := parseCondition(firstSnippet, getSnippet)
+			condition, err := parseCondition(firstSnippet, getSnippet, getValue)
 			if err != nil {
 				return nil, err
 			}
@@ -287,7 +333,7 @@ func parseAndOr(getSnippet func() (*snippet, error), remainingSnippets func() in
 	}
 }
 
-func parseCondition(firstSnippet *snippet, getSnippet func() (*snippet, error)) (Condition, error) {
+func parseCondition(firstSnippet *snippet, getSnippet func() (*snippet, error), getValue func(*snippet) (interface{}, error)) (Condition, error) {
 	wrapInNot := false
 
 	// get operator name
@@ -319,14 +365,62 @@ func parseCondition(firstSnippet *snippet, getSnippet func() (*snippet, error))
 	}
 
 	// get value
-	value, err := getSnippet()
+	valueSnippet, err := getSnippet()
+	if err != nil {
+		return nil, err
+	}
+	value, err := getValue(valueSnippet)
 	if err != nil {
 		return nil, err
 	}
 	if wrapInNot {
-		return Not(Where(firstSnippet.text, operator, value.text)), nil
+		return Not(Where(firstSnippet.text, operator, value)), nil
+	}
+	return Where(firstSnippet.text, operator, value), nil
+}
+
+// parsePlaceholder returns the number of the placeholder in the given raw (unescaped) snippet, or 0 if it is not a placeholder.
+func parsePlaceholder(raw string) int {
+	if len(raw) < 2 || raw[0] != '$' {
+		return 0
+	}
+	n, err := strconv.ParseUint(raw[1:], 10, 31)
+	if err != nil || n == 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// normalizeParam converts parameters decoded from JSON to the types expected by conditions.
+func normalizeParam(param interface{}) interface{} {
+	switch v := param.(type) {
+	case float64:
+		// JSON numbers are always decoded as float64
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, el := range v {
+			s, ok := el.(string)
+			if !ok {
+				return param
+			}
+			values = append(values, s)
+		}
+		return values
+	default:
+		return param
 	}
-	return Where(firstSnippet.text, operator, value.text), nil
 }
 
 var (
@@ -340,8 +434,8 @@ func prepToken(text string) string {
 
 // escapeString correctly escapes a snippet for printing
 func escapeString(token string) string {
-	// check if token contains characters that need to be escaped
-	if strings.ContainsAny(token, "()\"\\\t\r\n ") {
+	// check if token contains characters that need to be escaped or would be read as a placeholder
+	if strings.ContainsAny(token, "()\"\\\t\r\n ") || parsePlaceholder(token) > 0 {
 		// put the token in parenthesis and only escape \ and "
 		return fmt.Sprintf("\"%s\"", strings.Replace(token, "\"", "\\\"", -1))
 	}
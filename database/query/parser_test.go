@@ -1,6 +1,7 @@
 package query
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -102,6 +103,8 @@ func TestParseQuery(t *testing.T) {
 	testParsing(t, `query test: orderby name`, New("test:").OrderBy("name"))
 	testParsing(t, `query test: limit 10`, New("test:").Limit(10))
 	testParsing(t, `query test: offset 10`, New("test:").Offset(10))
+	testParsing(t, `query test: search coconut`, New("test:").Search("Coconut"))
+	testParsing(t, `query test: where banana exists search "monkey island" limit 10`, New("test:").Where(Where("banana", Exists, nil)).Search("Monkey, Island!").Limit(10))
 	testParsing(t, `query test: where banana matches ^ban`, New("test:").Where(Where("banana", Matches, "^ban")))
 	testParsing(t, `query test: where banana exists`, New("test:").Where(Where("banana", Exists, nil)))
 	testParsing(t, `query test: where banana not exists`, New("test:").Where(Not(Where("banana", Exists, nil))))
@@ -130,6 +133,68 @@ func TestParseQuery(t *testing.T) {
 	testParsing(t, `query test: where banana not exists`, New("test:").Where(Not(Where("banana", Exists, nil))))
 }
 
+func TestParseQueryParams(t *testing.T) {
+	// values that would otherwise need escaping
+	q, err := ParseQuery(`query test: where (score > $2 or tags in $3) and name sameas $1 search $4`, `King "Julian" (\)`, 10, []string{"a", "b"}, "monkey island")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := New("test:").Where(And(
+		Or(
+			Where("score", GreaterThan, 10),
+			Where("tags", In, []string{"a", "b"}),
+		),
+		Where("name", SameAs, `King "Julian" (\)`),
+	)).Search("monkey island")
+	if q.Print() != expected.Print() {
+		t.Fatalf("unexpected query: %s", q.Print())
+	}
+
+	// params decoded from JSON
+	var params []interface{}
+	err = json.Unmarshal([]byte(`[1.5, 2, true, ["x", "y"]]`), &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = ParseQuery(`query test: where a f> $1 and b == $2 and c is $3 and d in $4`, params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = New("test:").Where(And(
+		Where("a", FloatGreaterThan, 1.5),
+		Where("b", Equals, 2),
+		Where("c", Is, true),
+		Where("d", In, []string{"x", "y"}),
+	))
+	if q.Print() != expected.Print() {
+		t.Fatalf("unexpected query: %s", q.Print())
+	}
+
+	// quoted and escaped placeholders are plain values
+	testParsing(t, `query test: where a sameas "$1" or b sameas $a`, New("test:").Where(Or(Where("a", SameAs, "$1"), Where("b", SameAs, "$a"))))
+	q, err = ParseQuery(`query test: where a sameas \$1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Print() != `query test: where a sameas "$1"` {
+		t.Fatalf("unexpected query: %s", q.Print())
+	}
+
+	// errors
+	_, err = ParseQuery(`query test: where a sameas $2`, "x")
+	if err == nil || err.Error() != "missing parameter for placeholder $2 at position 28" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ParseQuery(`query test: where a sameas $1`, "x", "y")
+	if err == nil || err.Error() != "parameter $2 is not used in query" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ParseQuery(`query test: where a sameas $1`, 1)
+	if err == nil || err.Error() != "incompatible value 1 for string" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func testParseError(t *testing.T, queryText string, expectedErrorString string) {
 	_, err := ParseQuery(queryText)
 	if err == nil {
@@ -157,6 +222,9 @@ func TestParseErrors(t *testing.T) {
 	testParseError(t, `query test: where banana exists and banana is true or`, `you may not mix "and" and "or" (position: 52)`)
 	testParseError(t, `query test: where banana exists or banana is true and`, `you may not mix "and" and "or" (position: 51)`)
 	// testParseError(t, `query test: where banana exists and (`, ``)
+	testParseError(t, `query test: search`, `unexpected end at position 18`)
+	testParseError(t, `query test: search "!?"`, `no search terms found at position 20`)
+	testParseError(t, `query test: search a search b`, `duplicate "search" clause found at position 22`)
 
 	// value parsing error
 	testParseError(t, `query test: where banana == banana`, `could not parse banana to int64: strconv.ParseInt: parsing "banana": invalid syntax (hint: use "sameas" to compare strings)`)
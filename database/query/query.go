@@ -24,6 +24,7 @@ type Query struct {
 	dbName      string
 	dbKeyPrefix string
 	where       Condition
+	search      []string
 	orderBy     string
 	limit       int
 	offset      int
@@ -94,6 +95,11 @@ func (q *Query) IsChecked() bool {
 	return q.checked
 }
 
+// HasWhereCondition returns whether the query filters records with a where condition.
+func (q *Query) HasWhereCondition() bool {
+	return q.where != nil
+}
+
 // MatchesKey checks whether the query matches the supplied database key (key without database prefix).
 func (q *Query) MatchesKey(dbKey string) bool {
 	return strings.HasPrefix(dbKey, q.dbKeyPrefix)
@@ -141,6 +147,11 @@ func (q *Query) Print() string {
 		}
 	}
 
+	var search string
+	if len(q.search) > 0 {
+		search = fmt.Sprintf(" search %s", escapeString(strings.Join(q.search, " ")))
+	}
+
 	var orderBy string
 	if q.orderBy != "" {
 		orderBy = fmt.Sprintf(" orderby %s", q.orderBy)
@@ -156,7 +167,7 @@ func (q *Query) Print() string {
 		offset = fmt.Sprintf(" offset %d", q.offset)
 	}
 
-	return fmt.Sprintf("query %s:%s%s%s%s%s", q.dbName, q.dbKeyPrefix, where, orderBy, limit, offset)
+	return fmt.Sprintf("query %s:%s%s%s%s%s%s", q.dbName, q.dbKeyPrefix, where, search, orderBy, limit, offset)
 }
 
 // DatabaseName returns the name of the database.
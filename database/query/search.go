@@ -0,0 +1,45 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Search adds full-text search terms to the query. The text is tokenized with Tokenize.
+func (q *Query) Search(text string) *Query {
+	q.search = uniqueTokens(Tokenize(text))
+	return q
+}
+
+// HasSearch returns whether the query contains full-text search terms.
+func (q *Query) HasSearch() bool {
+	return len(q.search) > 0
+}
+
+// SearchTerms returns the full-text search terms of the query.
+func (q *Query) SearchTerms() []string {
+	return q.search
+}
+
+// Tokenize splits the given text into lowercase tokens for full-text search. Any character that is neither a letter nor a number separates tokens.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func uniqueTokens(tokens []string) []string {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(tokens))
+	unique := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if _, ok := seen[token]; !ok {
+			seen[token] = struct{}{}
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+	"github.com/safing/portbase/log"
+)
+
+// Quota limits the size of a database. Zero values mean no limit. The size of a record is the size of its serialized form including metadata, deleted records are not counted.
+type Quota struct {
+	Records int64 `json:",omitempty"`
+	Bytes   int64 `json:",omitempty"`
+}
+
+// exceededBy returns whether the given usage exceeds the quota.
+func (q *Quota) exceededBy(records, bytes int64) bool {
+	if q == nil {
+		return false
+	}
+	return (q.Records > 0 && records > q.Records) ||
+		(q.Bytes > 0 && bytes > q.Bytes)
+}
+
+func (q *Quota) valid() bool {
+	return q == nil || (q.Records >= 0 && q.Bytes >= 0)
+}
+
+func quotasEqual(a, b *Quota) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// QuotaReport describes a database that exceeded its soft quota.
+type QuotaReport struct {
+	Database string
+	Records  int64
+	Bytes    int64
+	Quota    Quota
+}
+
+var (
+	quotaHandlers     []func(*QuotaReport)
+	quotaHandlersLock sync.Mutex
+)
+
+// OnSoftQuotaExceeded registers a function that is called when a database exceeds its soft quota. It is called again only after the usage dropped below the quota in between.
+func OnSoftQuotaExceeded(fn func(*QuotaReport)) {
+	quotaHandlersLock.Lock()
+	defer quotaHandlersLock.Unlock()
+
+	quotaHandlers = append(quotaHandlers, fn)
+}
+
+func reportSoftQuota(report *QuotaReport) {
+	log.Warningf("database: database %s exceeded its soft quota (%d/%d records, %d/%d bytes)", report.Database, report.Records, report.Quota.Records, report.Bytes, report.Quota.Bytes)
+
+	quotaHandlersLock.Lock()
+	handlers := make([]func(*QuotaReport), len(quotaHandlers))
+	copy(handlers, quotaHandlers)
+	quotaHandlersLock.Unlock()
+
+	for _, fn := range handlers {
+		fn(report)
+	}
+}
+
+// quotaTracker accounts the records of a database with a quota.
+type quotaTracker struct {
+	lock sync.Mutex
+
+	dbName string
+	soft   *Quota
+	hard   *Quota
+
+	sizes        map[string]int64
+	records      int64
+	bytes        int64
+	softExceeded bool
+}
+
+// enableQuota enables the size accounting of the controller, if a quota is set. It must be called before the controller is used.
+func (c *Controller) enableQuota(dbName string, soft, hard *Quota) error {
+	if soft == nil && hard == nil {
+		return nil
+	}
+
+	qt := &quotaTracker{
+		dbName: dbName,
+		soft:   soft,
+		hard:   hard,
+	}
+	err := qt.count(c.storage)
+	if err != nil {
+		return err
+	}
+	c.quota = qt
+	return nil
+}
+
+// updateQuota applies changed quotas to a loaded controller. Enabling a quota counts the records of the database.
+func (c *Controller) updateQuota(dbName string, soft, hard *Quota) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	switch {
+	case c.stopped():
+		return nil
+	case soft == nil && hard == nil:
+		c.quota = nil
+		return nil
+	case c.quota == nil:
+		return c.enableQuota(dbName, soft, hard)
+	}
+
+	c.quota.lock.Lock()
+	defer c.quota.lock.Unlock()
+
+	c.quota.soft = soft
+	c.quota.hard = hard
+	c.quota.checkSoftQuota()
+	return nil
+}
+
+// count resets the accounting to the records in the given storage. This is expensive, as every record is loaded and serialized in order to get its size, and should only be done when the storage is opened.
+func (qt *quotaTracker) count(storageInt storage.Interface) error {
+	q, err := query.New("").Check()
+	if err != nil {
+		return err
+	}
+	it, err := storageInt.Query(q, true, true)
+	if err != nil {
+		return fmt.Errorf("failed to count records: %s", err)
+	}
+
+	sizes := make(map[string]int64)
+	var bytes int64
+	for r := range it.Next {
+		r.Lock()
+		size, err := recordSize(r)
+		r.Unlock()
+		if err != nil {
+			it.Cancel()
+			return fmt.Errorf("failed to count records: %s", err)
+		}
+		sizes[r.DatabaseKey()] = size
+		bytes += size
+	}
+	if it.Err() != nil {
+		return fmt.Errorf("failed to count records: %s", it.Err())
+	}
+
+	qt.lock.Lock()
+	defer qt.lock.Unlock()
+
+	qt.sizes = sizes
+	qt.records = int64(len(sizes))
+	qt.bytes = bytes
+	qt.checkSoftQuota()
+	return nil
+}
+
+// recordSize returns the size of a record for accounting. The caller must hold the record lock.
+func recordSize(r record.Record) (int64, error) {
+	if r.Meta().IsDeleted() {
+		return 0, nil
+	}
+	data, err := r.MarshalRecord(r)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// reserve accounts a record that is about to be written and returns a function that reverts the accounting if the write fails. Writes that increase the usage beyond the hard quota are rejected with ErrQuotaExceeded. The caller must hold the record lock.
+func (qt *quotaTracker) reserve(r record.Record) (revert func(), err error) {
+	size, err := recordSize(r)
+	if err != nil {
+		return nil, err
+	}
+	dbKey := r.DatabaseKey()
+	deleted := r.Meta().IsDeleted()
+
+	qt.lock.Lock()
+	defer qt.lock.Unlock()
+
+	oldSize, existed := qt.sizes[dbKey]
+	var recordsDelta int64
+	switch {
+	case deleted && existed:
+		recordsDelta = -1
+	case !deleted && !existed:
+		recordsDelta = 1
+	}
+	bytesDelta := size - oldSize
+
+	if (recordsDelta > 0 || bytesDelta > 0) &&
+		qt.hard.exceededBy(qt.records+recordsDelta, qt.bytes+bytesDelta) {
+		return nil, ErrQuotaExceeded
+	}
+
+	qt.set(dbKey, size, !deleted)
+	qt.records += recordsDelta
+	qt.bytes += bytesDelta
+	qt.checkSoftQuota()
+
+	return func() {
+		qt.lock.Lock()
+		defer qt.lock.Unlock()
+
+		qt.set(dbKey, oldSize, existed)
+		qt.records -= recordsDelta
+		qt.bytes -= bytesDelta
+		qt.checkSoftQuota()
+	}, nil
+}
+
+// set sets the size of a record, or removes it if it does not exist. The caller must hold the lock.
+func (qt *quotaTracker) set(dbKey string, size int64, exists bool) {
+	if exists {
+		qt.sizes[dbKey] = size
+	} else {
+		delete(qt.sizes, dbKey)
+	}
+}
+
+// remove removes a record that was deleted from the storage from the accounting.
+func (qt *quotaTracker) remove(dbKey string) {
+	qt.lock.Lock()
+	defer qt.lock.Unlock()
+
+	size, ok := qt.sizes[dbKey]
+	if !ok {
+		return
+	}
+	delete(qt.sizes, dbKey)
+	qt.records--
+	qt.bytes -= size
+	qt.checkSoftQuota()
+}
+
+// checkSoftQuota reports when the usage exceeds the soft quota. The caller must hold the lock.
+func (qt *quotaTracker) checkSoftQuota() {
+	exceeded := qt.soft.exceededBy(qt.records, qt.bytes)
+	if exceeded && !qt.softExceeded {
+		// do not block the write
+		go reportSoftQuota(&QuotaReport{
+			Database: qt.dbName,
+			Records:  qt.records,
+			Bytes:    qt.bytes,
+			Quota:    *qt.soft,
+		})
+	}
+	qt.softExceeded = exceeded
+}
+
+// Usage returns the number of records and bytes stored in the database. It is only available for databases with a quota.
+func (c *Controller) Usage() (records, bytes int64, ok bool) {
+	c.writeLock.RLock()
+	defer c.writeLock.RUnlock()
+
+	if c.quota == nil {
+		return 0, 0, false
+	}
+
+	c.quota.lock.Lock()
+	defer c.quota.lock.Unlock()
+
+	return c.quota.records, c.quota.bytes, true
+}
@@ -7,6 +7,7 @@
 	"github.com/safing/portbase/container"
 	"github.com/safing/portbase/database/accessor"
 	"github.com/safing/portbase/formats/dsd"
+	"github.com/safing/portbase/formats/varint"
 )
 
 // Base provides a quick way to comply with the Model interface.
@@ -94,17 +95,25 @@ func (b *Base) MarshalRecord(self Record) ([]byte, error) {
 	}
 
 	// version
-	c := container.New([]byte{1})
+	c := container.New(varint.Pack8(RecordVersion))
 
 	// meta encoding
-	metaSection, err := dsd.Dump(b.meta, GenCode)
+	c.AppendAsBlock(b.meta.marshalTagged())
+
+	// data
+	format := uint8(JSON)
+	if formatter, ok := self.(StorageFormatter); ok {
+		format = formatter.StorageFormat()
+	}
+	dataSection, err := b.Marshal(self, format)
 	if err != nil {
 		return nil, err
 	}
-	c.AppendAsBlock(metaSection)
-
-	// data
-	dataSection, err := b.Marshal(self, JSON)
+	dataSection, err = signDataSection(self, dataSection)
+	if err != nil {
+		return nil, err
+	}
+	dataSection, err = dsd.Compress(dataSection, storageCompression(self))
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +127,10 @@ func (b *Base) IsWrapped() bool {
 	return false
 }
 
-// GetAccessor returns an accessor for this record, if available.
+// GetAccessor returns an accessor for this record, if available. Accessors generated by accessorgen are preferred over the reflection based StructAccessor.
 func (b *Base) GetAccessor(self Record) accessor.Accessor {
+	if generated, ok := self.(accessor.Generated); ok {
+		return generated.GeneratedAccessor()
+	}
 	return accessor.NewStructAccessor(self)
 }
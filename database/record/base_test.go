@@ -11,3 +11,50 @@ func TestBaseRecord(t *testing.T) {
 	_ = m
 
 }
+
+type TestBinaryRecord struct {
+	TestRecord `dsd:"-"`
+
+	Name  string `dsd:"1"`
+	Score int    `dsd:"2"`
+}
+
+func (tr *TestBinaryRecord) StorageFormat() uint8 {
+	return Binary
+}
+
+func TestBinaryStorageFormat(t *testing.T) {
+	r := &TestBinaryRecord{
+		Name:  "Herbert",
+		Score: 3,
+	}
+	r.SetKey("test:binary")
+	r.UpdateMeta()
+
+	data, err := r.MarshalRecord(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper, err := NewRawWrapper("test", "binary", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapper.Format != Binary {
+		t.Fatalf("unexpected format %c", wrapper.Format)
+	}
+
+	loaded := &TestBinaryRecord{}
+	err = Unwrap(wrapper, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Name != r.Name || loaded.Score != r.Score {
+		t.Errorf("unexpected record %+v", loaded)
+	}
+
+	// binary data cannot be converted without its struct
+	_, err = wrapper.Marshal(wrapper, JSON)
+	if err == nil {
+		t.Error("converting binary data should fail")
+	}
+}
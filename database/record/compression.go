@@ -0,0 +1,38 @@
+package record
+
+import (
+	"sync"
+)
+
+var (
+	dbCompression     = make(map[string]uint8)
+	dbCompressionLock sync.RWMutex
+)
+
+// StorageCompressor may be implemented by records to select the compression of their stored data. It overrides the compression of the database.
+type StorageCompressor interface {
+	StorageCompression() uint8
+}
+
+// SetDatabaseCompression sets the compression (dsd.GZIP, dsd.ZSTD) used for storing records of the given database. AUTO disables compression.
+func SetDatabaseCompression(dbName string, compression uint8) {
+	dbCompressionLock.Lock()
+	defer dbCompressionLock.Unlock()
+
+	if compression == AUTO {
+		delete(dbCompression, dbName)
+		return
+	}
+	dbCompression[dbName] = compression
+}
+
+// storageCompression returns the compression to be used for storing the given record.
+func storageCompression(r Record) uint8 {
+	if compressor, ok := r.(StorageCompressor); ok {
+		return compressor.StorageCompression()
+	}
+
+	dbCompressionLock.RLock()
+	defer dbCompressionLock.RUnlock()
+	return dbCompression[r.DatabaseName()]
+}
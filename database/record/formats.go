@@ -11,5 +11,8 @@
 	BYTES   = dsd.BYTES   // X
 	JSON    = dsd.JSON    // J
 	BSON    = dsd.BSON    // B
+	CBOR    = dsd.CBOR    // C
 	GenCode = dsd.GenCode // G
+	MsgPack = dsd.MsgPack // M
+	Binary  = dsd.Binary  // P
 )
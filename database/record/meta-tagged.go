@@ -0,0 +1,145 @@
+package record
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/formats/varint"
+)
+
+// Meta fields of record format version 2. The meta section is a sequence of fields, each consisting of a varint tag and a value block. Zero values are omitted, unknown tags are skipped, so that new fields can be added without breaking older readers. Tag numbers must never be reused. The generated GenCode layout of version 1 is frozen, new fields, such as the signed flag, are only stored here.
+const (
+	metaTagCreated     = 1
+	metaTagModified    = 2
+	metaTagExpires     = 3
+	metaTagDeleted     = 4
+	metaTagFlags       = 5
+	metaTagRevision    = 6
+	metaTagOrigin      = 7
+	metaTagContentType = 8
+	metaTagTag         = 9 // repeated, value holds a key block and a value block
+)
+
+// meta flags
+const (
+	metaFlagSecret = 1 << iota
+	metaFlagCrownJewel
+	metaFlagSigned
+)
+
+// marshalTagged serializes the metadata into the tagged meta section of record format version 2.
+func (m *Meta) marshalTagged() []byte {
+	c := container.New()
+
+	appendNumber := func(tag uint8, n uint64) {
+		if n != 0 {
+			c.Append(varint.Pack8(tag))
+			c.AppendAsBlock(varint.Pack64(n))
+		}
+	}
+	appendString := func(tag uint8, s string) {
+		if s != "" {
+			c.Append(varint.Pack8(tag))
+			c.AppendAsBlock([]byte(s))
+		}
+	}
+
+	// signed values are stored in two's complement
+	appendNumber(metaTagCreated, uint64(m.Created))
+	appendNumber(metaTagModified, uint64(m.Modified))
+	appendNumber(metaTagExpires, uint64(m.Expires))
+	appendNumber(metaTagDeleted, uint64(m.Deleted))
+
+	var flags uint64
+	if m.secret {
+		flags |= metaFlagSecret
+	}
+	if m.cronjewel {
+		flags |= metaFlagCrownJewel
+	}
+	if m.signed {
+		flags |= metaFlagSigned
+	}
+	appendNumber(metaTagFlags, flags)
+
+	appendNumber(metaTagRevision, m.Revision)
+	appendString(metaTagOrigin, m.Origin)
+	appendString(metaTagContentType, m.ContentType)
+
+	// sort tags, so that equal metadata is always encoded the same
+	keys := make([]string, 0, len(m.Tags))
+	for key := range m.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tag := container.New()
+		tag.AppendAsBlock([]byte(key))
+		tag.AppendAsBlock([]byte(m.Tags[key]))
+		c.Append(varint.Pack8(metaTagTag))
+		c.AppendAsBlock(tag.CompileData())
+	}
+
+	return c.CompileData()
+}
+
+// unmarshalTagged parses the tagged meta section of record format version 2.
+func (m *Meta) unmarshalTagged(data []byte) error {
+	for offset := 0; offset < len(data); {
+		tag, n, err := varint.Unpack8(data[offset:])
+		if err != nil {
+			return fmt.Errorf("failed to get meta tag: %s", err)
+		}
+		offset += n
+		value, n, err := varint.GetNextBlock(data[offset:])
+		if err != nil {
+			return fmt.Errorf("failed to get value of meta tag %d: %s", tag, err)
+		}
+		offset += n
+
+		var number uint64
+		switch tag {
+		case metaTagCreated, metaTagModified, metaTagExpires, metaTagDeleted, metaTagFlags, metaTagRevision:
+			number, _, err = varint.Unpack64(value)
+			if err != nil {
+				return fmt.Errorf("failed to get value of meta tag %d: %s", tag, err)
+			}
+		}
+
+		switch tag {
+		case metaTagCreated:
+			m.Created = int64(number)
+		case metaTagModified:
+			m.Modified = int64(number)
+		case metaTagExpires:
+			m.Expires = int64(number)
+		case metaTagDeleted:
+			m.Deleted = int64(number)
+		case metaTagFlags:
+			m.secret = number&metaFlagSecret != 0
+			m.cronjewel = number&metaFlagCrownJewel != 0
+			m.signed = number&metaFlagSigned != 0
+		case metaTagRevision:
+			m.Revision = number
+		case metaTagOrigin:
+			m.Origin = string(value)
+		case metaTagContentType:
+			m.ContentType = string(value)
+		case metaTagTag:
+			key, n, err := varint.GetNextBlock(value)
+			if err != nil {
+				return fmt.Errorf("failed to get key of custom tag: %s", err)
+			}
+			tagValue, _, err := varint.GetNextBlock(value[n:])
+			if err != nil {
+				return fmt.Errorf("failed to get value of custom tag %s: %s", key, err)
+			}
+			m.SetTag(string(key), string(tagValue))
+		default:
+			// skip unknown fields written by newer versions
+		}
+	}
+
+	return nil
+}
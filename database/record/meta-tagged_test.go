@@ -0,0 +1,69 @@
+package record
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/safing/portbase/formats/varint"
+)
+
+func TestTaggedMeta(t *testing.T) {
+	m := &Meta{
+		Created:     time.Now().Unix(),
+		Modified:    time.Now().Unix(),
+		Expires:     time.Now().Unix(),
+		Deleted:     -3600,
+		Revision:    7,
+		Origin:      "node-a",
+		ContentType: "application/json",
+		secret:      true,
+		signed:      true,
+	}
+	m.SetTag("a", "b")
+	m.SetTag("empty", "")
+
+	encoded := m.marshalTagged()
+
+	// unknown fields are skipped
+	encoded = append(encoded, varint.Pack8(100)...)
+	encoded = append(encoded, varint.PrependLength([]byte("future"))...)
+
+	new := &Meta{}
+	err := new.unmarshalTagged(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, new) {
+		t.Errorf("objects are not equal, got: %+v", new)
+	}
+
+	// tags are encoded in a stable order
+	tagged := &Meta{}
+	for _, key := range []string{"c", "a", "e", "b", "d", "f", "h", "g"} {
+		tagged.SetTag(key, key)
+	}
+	first := tagged.marshalTagged()
+	for i := 0; i < 10; i++ {
+		if !bytes.Equal(first, tagged.marshalTagged()) {
+			t.Fatal("encoding of tags is not deterministic")
+		}
+	}
+
+	// empty meta
+	new = &Meta{}
+	err = new.unmarshalTagged((&Meta{}).marshalTagged())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&Meta{}, new) {
+		t.Errorf("objects are not equal, got: %+v", new)
+	}
+
+	// truncated data
+	err = new.unmarshalTagged(m.marshalTagged()[:5])
+	if err == nil {
+		t.Error("should fail on truncated data")
+	}
+}
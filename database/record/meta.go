@@ -1,15 +1,41 @@
 package record
 
-import "time"
+import (
+	"sync"
+	"time"
+)
+
+var (
+	localOrigin     string
+	localOriginLock sync.RWMutex
+)
+
+// SetLocalOrigin sets the ID of this node, which is recorded as the origin of records created here.
+func SetLocalOrigin(nodeID string) {
+	localOriginLock.Lock()
+	defer localOriginLock.Unlock()
+	localOrigin = nodeID
+}
+
+func getLocalOrigin() string {
+	localOriginLock.RLock()
+	defer localOriginLock.RUnlock()
+	return localOrigin
+}
 
 // Meta holds
 type Meta struct {
-	Created   int64
-	Modified  int64
-	Expires   int64
-	Deleted   int64
-	secret    bool // secrets must not be sent to the UI, only synced between nodes
-	cronjewel bool // crownjewels must never leave the instance, but may be read by the UI
+	Created     int64
+	Modified    int64
+	Expires     int64
+	Deleted     int64
+	Revision    uint64            // incremented on every update
+	Origin      string            // ID of the node the record was created on
+	ContentType string            // optional MIME type of the record data
+	Tags        map[string]string // custom tags
+	secret      bool              // secrets must not be sent to the UI, only synced between nodes
+	cronjewel   bool              // crownjewels must never leave the instance, but may be read by the UI
+	signed      bool              // signed records are stored in a signing envelope and must pass verification when loaded
 }
 
 // SetAbsoluteExpiry sets an absolute expiry time (in seconds), that is not affected when the record is updated.
@@ -48,29 +74,74 @@ func (m *Meta) MakeCrownJewel() {
 	m.cronjewel = true
 }
 
+// IsCrownJewel returns whether the database record is a crownjewel.
+func (m *Meta) IsCrownJewel() bool {
+	return m.cronjewel
+}
+
 // MakeSecret sets the database record as secret, meaning that it may only be used internally, and not by interfacing processes, such as the UI.
 func (m *Meta) MakeSecret() {
 	m.secret = true
 }
 
+// IsSecret returns whether the database record is secret.
+func (m *Meta) IsSecret() bool {
+	return m.secret
+}
+
+// MakeSigned marks the database record as signed, meaning that its data is stored in a signing envelope and verified when it is loaded. The record must implement StorageSigner.
+func (m *Meta) MakeSigned() {
+	m.signed = true
+}
+
+// IsSigned returns whether the database record is signed.
+func (m *Meta) IsSigned() bool {
+	return m.signed
+}
+
+// SetTag sets a custom tag.
+func (m *Meta) SetTag(key, value string) {
+	if m.Tags == nil {
+		m.Tags = make(map[string]string)
+	}
+	m.Tags[key] = value
+}
+
+// GetTag returns the custom tag with the given key.
+func (m *Meta) GetTag(key string) (value string, ok bool) {
+	value, ok = m.Tags[key]
+	return
+}
+
+// DeleteTag deletes the custom tag with the given key.
+func (m *Meta) DeleteTag(key string) {
+	delete(m.Tags, key)
+}
+
 // Update updates the internal meta states and should be called before writing the record to the database.
 func (m *Meta) Update() {
 	now := time.Now().Unix()
 	m.Modified = now
+	m.Revision++
 	if m.Created == 0 {
 		m.Created = now
 	}
+	if m.Origin == "" {
+		m.Origin = getLocalOrigin()
+	}
 	if m.Deleted < 0 {
 		m.Expires = now - m.Deleted
 	}
 }
 
-// Reset resets all metadata, except for the secret and crownjewel status.
+// Reset resets all metadata, except for the secret, crownjewel and signed status, the content type and the custom tags.
 func (m *Meta) Reset() {
 	m.Created = 0
 	m.Modified = 0
 	m.Expires = 0
 	m.Deleted = 0
+	m.Revision = 0
+	m.Origin = ""
 }
 
 // Delete marks the record as deleted.
@@ -117,12 +188,25 @@ func (m *Meta) CheckPermission(local, internal bool) (permitted bool) {
 
 // Duplicate returns a new copy of Meta.
 func (m *Meta) Duplicate() *Meta {
+	var tags map[string]string
+	if m.Tags != nil {
+		tags = make(map[string]string, len(m.Tags))
+		for key, value := range m.Tags {
+			tags[key] = value
+		}
+	}
+
 	return &Meta{
-		Created:   m.Created,
-		Modified:  m.Modified,
-		Expires:   m.Expires,
-		Deleted:   m.Deleted,
-		secret:    m.secret,
-		cronjewel: m.cronjewel,
+		Created:     m.Created,
+		Modified:    m.Modified,
+		Expires:     m.Expires,
+		Deleted:     m.Deleted,
+		Revision:    m.Revision,
+		Origin:      m.Origin,
+		ContentType: m.ContentType,
+		Tags:        tags,
+		secret:      m.secret,
+		cronjewel:   m.cronjewel,
+		signed:      m.signed,
 	}
 }
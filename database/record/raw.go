@@ -0,0 +1,32 @@
+package record
+
+import (
+	"fmt"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/formats/varint"
+)
+
+// SplitRawRecord splits raw record data, as handed to storage backends by MarshalRecord, into the record format version, the meta section and the data section. The data section holds the dsd formatted data, which may be compressed or signed. This is normally only used by storage backends that store these parts separately.
+func SplitRawRecord(data []byte) (version uint8, metaSection, dataSection []byte, err error) {
+	version, offset, err := varint.Unpack8(data)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	metaSection, n, err := varint.GetNextBlock(data[offset:])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("could not get meta section: %s", err)
+	}
+	offset += n
+
+	return version, metaSection, data[offset:], nil
+}
+
+// JoinRawRecord joins the parts returned by SplitRawRecord back into raw record data.
+func JoinRawRecord(version uint8, metaSection, dataSection []byte) []byte {
+	c := container.New(varint.Pack8(version))
+	c.AppendAsBlock(metaSection)
+	c.Append(dataSection)
+	return c.CompileData()
+}
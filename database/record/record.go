@@ -4,6 +4,9 @@
 	"github.com/safing/portbase/database/accessor"
 )
 
+// RecordVersion is the current version of the record format used for storing records. Version 1 records are still read and are upgraded by the database maintenance.
+const RecordVersion = 2
+
 // Record provides an interface for uniformally handling database records.
 type Record interface {
 	Key() string // test:config
@@ -27,3 +30,8 @@ type Record interface {
 
 	IsWrapped() bool
 }
+
+// StorageFormatter may be implemented by records to select the format their data is stored in. Records without it are stored as JSON. Data in the GenCode and Binary formats can only be loaded into its struct and cannot be converted, eg. for queries on the wrapped record.
+type StorageFormatter interface {
+	StorageFormat() uint8
+}
@@ -0,0 +1,87 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/formats/dsd"
+	"github.com/safing/portbase/formats/varint"
+)
+
+// StorageSigner must be implemented by signed records to select the key their data is signed with.
+type StorageSigner interface {
+	SigningKeyID() string
+}
+
+// signDataSection wraps the data section of a signed record in a signing envelope. Deleted records are signed too, so that a record cannot be deleted by changing its metadata.
+func signDataSection(r Record, dataSection []byte) ([]byte, error) {
+	if !r.Meta().IsSigned() {
+		return dataSection, nil
+	}
+
+	signer, ok := r.(StorageSigner)
+	if !ok {
+		return nil, fmt.Errorf("record %s is marked as signed, but does not implement StorageSigner", r.Key())
+	}
+	return dsd.SignBound(dataSection, signatureBinding(r), signer.SigningKeyID())
+}
+
+// signatureBinding returns the parts of a record outside of its data that are covered by its signature: the database key and the metadata that affects validity and access. The database name is not included, as it may differ between nodes.
+func signatureBinding(r Record) []byte {
+	m := r.Meta()
+
+	var flags uint8
+	if m.secret {
+		flags |= 1
+	}
+	if m.cronjewel {
+		flags |= 2
+	}
+	if m.signed {
+		flags |= 4
+	}
+
+	c := container.New()
+	c.AppendAsBlock([]byte(r.DatabaseKey()))
+	c.Append(varint.Pack64(uint64(m.Expires)))
+	c.Append(varint.Pack64(uint64(m.Deleted)))
+	c.Append(varint.Pack8(flags))
+	return c.CompileData()
+}
+
+// VerifySignature verifies the signature of a record that was loaded from storage. Records that are not wrapped have not passed through storage and are accepted as is.
+func VerifySignature(r Record) error {
+	w, ok := r.(*Wrapper)
+	if !ok {
+		return nil
+	}
+	return w.Verify()
+}
+
+// Verify verifies the signing envelope the wrapped data was loaded with, including the signed metadata. It fails if the data was not signed or was changed since.
+func (w *Wrapper) Verify() error {
+	if w.envelope == nil {
+		return dsd.ErrNotSigned
+	}
+	if !w.envelopeMatches() {
+		return errors.New("wrapped data was changed after loading")
+	}
+	return w.envelope.VerifyBound(signatureBinding(w))
+}
+
+// envelopeMatches returns whether the signing envelope still holds the wrapped data and whether the signed metadata is unchanged.
+func (w *Wrapper) envelopeMatches() bool {
+	if !bytes.Equal(w.envelopeBinding, signatureBinding(w)) {
+		return false
+	}
+
+	payload := w.envelope.Payload
+	if len(payload) == 0 {
+		// deleted records have no data
+		return len(w.Data) == 0
+	}
+	return payload[0] == w.Format &&
+		bytes.Equal(payload[1:], w.Data)
+}
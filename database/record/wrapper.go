@@ -18,57 +18,92 @@ type Wrapper struct {
 
 	Format uint8
 	Data   []byte
+
+	// envelope holds the signing envelope of signed data loaded from storage.
+	envelope *dsd.Envelope
+	// envelopeBinding holds the signed metadata the wrapper was loaded with.
+	envelopeBinding []byte
+	// version is the record format version the wrapper was loaded from.
+	version uint8
 }
 
 // NewRawWrapper returns a record wrapper for the given data, including metadata. This is normally only used by storage backends when loading records.
 func NewRawWrapper(database, key string, data []byte) (*Wrapper, error) {
-	version, offset, err := varint.Unpack8(data)
+	version, metaSection, dataSection, err := SplitRawRecord(data)
 	if err != nil {
 		return nil, err
 	}
-	if version != 1 {
+
+	newMeta := &Meta{}
+	switch version {
+	case 1:
+		err = unmarshalMetaV1(newMeta, metaSection)
+	case 2:
+		err = newMeta.unmarshalTagged(metaSection)
+	default:
 		return nil, fmt.Errorf("incompatible record version: %d", version)
 	}
-
-	metaSection, n, err := varint.GetNextBlock(data[offset:])
 	if err != nil {
-		return nil, fmt.Errorf("could not get meta section: %s", err)
+		return nil, fmt.Errorf("could not unmarshal meta section: %s", err)
 	}
-	offset += n
 
-	newMeta := &Meta{}
-	if len(metaSection) == 34 && metaSection[4] == 0 {
-		// TODO: remove in 2020
-		// backward compatibility:
-		// format would byte shift and populate metaSection[4] with value > 0 (would naturally populate >0 at 07.02.2106 07:28:15)
-		// this must be gencode without format
-		_, err = newMeta.GenCodeUnmarshal(metaSection)
+	if dsd.IsCompressed(dataSection) {
+		dataSection, err = dsd.Decompress(dataSection)
 		if err != nil {
-			return nil, fmt.Errorf("could not unmarshal meta section: %s", err)
+			return nil, fmt.Errorf("could not decompress data section: %s", err)
 		}
-	} else {
-		_, err = dsd.Load(metaSection, newMeta)
+	}
+
+	// signed data is verified on access, see Verify
+	var envelope *dsd.Envelope
+	if dsd.IsSigned(dataSection) {
+		envelope, err = dsd.ParseEnvelope(dataSection)
 		if err != nil {
-			return nil, fmt.Errorf("could not unmarshal meta section: %s", err)
+			return nil, fmt.Errorf("could not parse signing envelope: %s", err)
 		}
+		dataSection = envelope.Payload
 	}
 
-	format, n, err := varint.Unpack8(data[offset:])
-	if err != nil {
-		return nil, fmt.Errorf("could not get dsd format: %s", err)
+	// deleted records have no data
+	var format uint8
+	if len(dataSection) > 0 {
+		var n int
+		format, n, err = varint.Unpack8(dataSection)
+		if err != nil {
+			return nil, fmt.Errorf("could not get dsd format: %s", err)
+		}
+		dataSection = dataSection[n:]
 	}
-	offset += n
 
-	return &Wrapper{
-		Base{
-			database,
-			key,
-			newMeta,
+	w := &Wrapper{
+		Base: Base{
+			dbName: database,
+			dbKey:  key,
+			meta:   newMeta,
 		},
-		sync.Mutex{},
-		format,
-		data[offset:],
-	}, nil
+		Format:   format,
+		Data:     dataSection,
+		envelope: envelope,
+		version:  version,
+	}
+	if envelope != nil {
+		w.envelopeBinding = signatureBinding(w)
+	}
+	return w, nil
+}
+
+// unmarshalMetaV1 parses the GenCode meta section of record format version 1.
+func unmarshalMetaV1(m *Meta, metaSection []byte) error {
+	if len(metaSection) == 34 && metaSection[4] == 0 {
+		// TODO: remove in 2020
+		// backward compatibility:
+		// format would byte shift and populate metaSection[4] with value > 0 (would naturally populate >0 at 07.02.2106 07:28:15)
+		// this must be gencode without format
+		_, err := m.GenCodeUnmarshal(metaSection)
+		return err
+	}
+	_, err := dsd.Load(metaSection, m)
+	return err
 }
 
 // NewWrapper returns a new record wrapper for the given data.
@@ -76,14 +111,14 @@ func NewWrapper(key string, meta *Meta, format uint8, data []byte) (*Wrapper, er
 	dbName, dbKey := ParseKey(key)
 
 	return &Wrapper{
-		Base{
+		Base: Base{
 			dbName: dbName,
 			dbKey:  dbKey,
 			meta:   meta,
 		},
-		sync.Mutex{},
-		format,
-		data,
+		Format:  format,
+		Data:    data,
+		version: RecordVersion,
 	}, nil
 }
 
@@ -97,13 +132,20 @@ func (w *Wrapper) Marshal(r Record, format uint8) ([]byte, error) {
 		return nil, nil
 	}
 
-	if format != AUTO && format != w.Format {
-		return nil, errors.New("could not dump model, wrapped object format mismatch")
+	wrappedData := w.Data
+	if format == AUTO {
+		format = w.Format
+	} else if format != w.Format {
+		var err error
+		wrappedData, err = dsd.Convert(w.Data, w.Format, format)
+		if err != nil {
+			return nil, fmt.Errorf("could not dump model, failed to convert wrapped object: %s", err)
+		}
 	}
 
-	data := make([]byte, len(w.Data)+1)
-	data[0] = w.Format
-	copy(data[1:], w.Data)
+	data := make([]byte, len(wrappedData)+1)
+	data[0] = format
+	copy(data[1:], wrappedData)
 
 	return data, nil
 }
@@ -117,17 +159,26 @@ func (w *Wrapper) MarshalRecord(r Record) ([]byte, error) {
 	}
 
 	// version
-	c := container.New([]byte{1})
+	c := container.New(varint.Pack8(RecordVersion))
 
 	// meta
-	metaSection, err := dsd.Dump(w.meta, GenCode)
+	c.AppendAsBlock(w.meta.marshalTagged())
+
+	// data, in the wrapped format
+	dataSection, err := w.Marshal(r, AUTO)
 	if err != nil {
 		return nil, err
 	}
-	c.AppendAsBlock(metaSection)
-
-	// data
-	dataSection, err := w.Marshal(r, JSON)
+	if w.Meta().IsSigned() && w.envelope != nil && w.envelopeMatches() {
+		// keep the original signature, eg. of a record synced from another node
+		dataSection = w.envelope.Pack()
+	} else {
+		dataSection, err = signDataSection(r, dataSection)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dataSection, err = dsd.Compress(dataSection, storageCompression(r))
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +187,42 @@ func (w *Wrapper) MarshalRecord(r Record) ([]byte, error) {
 	return c.CompileData(), nil
 }
 
+// SigningKeyID returns the key ID of the signing envelope the data was loaded with, in order to re-sign changed data with the same key.
+func (w *Wrapper) SigningKeyID() string {
+	if w.envelope == nil {
+		return ""
+	}
+	return w.envelope.KeyID
+}
+
+// Copy returns a copy of the wrapper that can be changed without affecting the original. The caller must hold the lock.
+func (w *Wrapper) Copy() *Wrapper {
+	var meta *Meta
+	if w.meta != nil {
+		meta = w.meta.Duplicate()
+	}
+	data := make([]byte, len(w.Data))
+	copy(data, w.Data)
+
+	return &Wrapper{
+		Base: Base{
+			dbName: w.dbName,
+			dbKey:  w.dbKey,
+			meta:   meta,
+		},
+		Format:          w.Format,
+		Data:            data,
+		envelope:        w.envelope,
+		envelopeBinding: w.envelopeBinding,
+		version:         w.version,
+	}
+}
+
+// NeedsUpgrade returns whether the wrapped record was loaded from an older record format version and should be saved again.
+func (w *Wrapper) NeedsUpgrade() bool {
+	return w.version < RecordVersion
+}
+
 // IsWrapped returns whether the record is a Wrapper.
 func (w *Wrapper) IsWrapped() bool {
 	return true
@@ -159,10 +246,32 @@ func Unwrap(wrapped, new Record) error {
 	return nil
 }
 
-// GetAccessor returns an accessor for this record, if available.
+// GetAccessor returns an accessor for this record, if available. Data in other formats than JSON is converted to JSON for reading, the accessor then cannot set values, as the wrapper is never changed by reading.
 func (w *Wrapper) GetAccessor(self Record) accessor.Accessor {
-	if w.Format == JSON && len(w.Data) > 0 {
+	if len(w.Data) == 0 {
+		return nil
+	}
+	if w.Format == JSON {
 		return accessor.NewJSONBytesAccessor(&w.Data)
 	}
-	return nil
+
+	data, err := dsd.Convert(w.Data, w.Format, JSON)
+	if err != nil {
+		return nil
+	}
+	return &convertedAccessor{
+		Accessor: accessor.NewJSONBytesAccessor(&data),
+		format:   w.Format,
+	}
+}
+
+// convertedAccessor provides read access to a JSON copy of data in another format.
+type convertedAccessor struct {
+	accessor.Accessor
+	format uint8
+}
+
+// Set always fails, as changes would only be applied to the converted copy.
+func (ca *convertedAccessor) Set(key string, value interface{}) error {
+	return fmt.Errorf("cannot set %s: record data in format %d is read-only, save it as JSON first", key, ca.format)
 }
@@ -2,10 +2,13 @@
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/formats/dsd"
 )
 
 func TestWrapper(t *testing.T) {
@@ -68,6 +71,203 @@ func TestWrapper(t *testing.T) {
 	if !bytes.Equal(testData, wrapper3.Data) {
 		t.Error("marshal mismatch")
 	}
+	if wrapper2.NeedsUpgrade() || !wrapper3.NeedsUpgrade() {
+		t.Error("only old record should need an upgrade")
+	}
+}
+
+func TestWrapperFormats(t *testing.T) {
+	cborData, err := dsd.Dump(map[string]string{"a": "b"}, CBOR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapper, err := NewWrapper("test:a", &Meta{}, CBOR, cborData[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stored in the wrapped format
+	raw, err := wrapper.MarshalRecord(wrapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper2, err := NewRawWrapper("test", "a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapper2.Format != CBOR || !bytes.Equal(cborData[1:], wrapper2.Data) {
+		t.Error("marshal mismatch")
+	}
+
+	// converted on request
+	encoded, err := wrapper2.Marshal(wrapper2, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `J{"a":"b"}` {
+		t.Errorf("unexpected conversion: %s", encoded)
+	}
+
+	// accessible
+	acc := wrapper2.GetAccessor(wrapper2)
+	if acc == nil {
+		t.Fatal("no accessor for converted wrapper")
+	}
+	if value, ok := acc.GetString("a"); !ok || value != "b" {
+		t.Errorf("unexpected value: %s", value)
+	}
+	// but not changed
+	if wrapper2.Format != CBOR || !bytes.Equal(cborData[1:], wrapper2.Data) {
+		t.Error("wrapper was changed by getting an accessor")
+	}
+	if err := acc.Set("a", "c"); err == nil {
+		t.Error("setting values of converted data should fail")
+	}
+}
+
+func TestWrapperCompression(t *testing.T) {
+	testData := []byte(`{"a": "` + strings.Repeat("b", 1000) + `"}`)
+	wrapper, err := NewWrapper("test-compression:a", &Meta{}, JSON, testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetDatabaseCompression("test-compression", dsd.ZSTD)
+	defer SetDatabaseCompression("test-compression", AUTO)
+
+	raw, err := wrapper.MarshalRecord(wrapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) >= len(testData) {
+		t.Errorf("record was not compressed (%d >= %d)", len(raw), len(testData))
+	}
+
+	wrapper2, err := NewRawWrapper("test-compression", "a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapper2.Format != JSON || !bytes.Equal(testData, wrapper2.Data) {
+		t.Error("decompressed data mismatch")
+	}
+}
+
+func TestWrapperSigning(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := dsd.NewMemoryKeyStore()
+	ks.AddSigningKey("test", key)
+	dsd.SetKeyStore(ks)
+	defer dsd.SetKeyStore(nil)
+
+	testData := []byte(`{"a": "b"}`)
+	meta := &Meta{}
+	meta.MakeSigned()
+	wrapper, err := NewWrapper("test:a", meta, JSON, testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new wrapper has no key to sign with
+	_, err = wrapper.MarshalRecord(wrapper)
+	if err == nil {
+		t.Fatal("should fail to sign without a signing key")
+	}
+
+	// sign
+	signedData, err := dsd.SignBound(append([]byte{JSON}, testData...), signatureBinding(wrapper), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper.envelope, err = dsd.ParseEnvelope(signedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper.envelopeBinding = signatureBinding(wrapper)
+	raw, err := wrapper.MarshalRecord(wrapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper2, err := NewRawWrapper("test", "a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wrapper2.Meta().IsSigned() || !bytes.Equal(testData, wrapper2.Data) {
+		t.Fatal("signed record mismatch")
+	}
+	err = VerifySignature(wrapper2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changed data is re-signed with the same key
+	wrapper2.Data = []byte(`{"a": "c"}`)
+	err = VerifySignature(wrapper2)
+	if err == nil {
+		t.Fatal("changed data should fail verification")
+	}
+	raw, err = wrapper2.MarshalRecord(wrapper2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper3, err := NewRawWrapper("test", "a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignature(wrapper3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tampered data
+	idx := bytes.LastIndex(raw, []byte(`"c"`))
+	raw[idx+1] = 'd'
+	wrapper4, err := NewRawWrapper("test", "a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignature(wrapper4)
+	if err != dsd.ErrInvalidSignature {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	// tampered metadata
+	for _, tamper := range []func(m *Meta){
+		func(m *Meta) { m.Expires = 1 },
+		func(m *Meta) { m.Deleted = 1 },
+		func(m *Meta) { m.MakeSecret() },
+	} {
+		tampered := wrapper3.Meta().Duplicate()
+		tamper(tampered)
+		w, err := NewWrapper("test:a", tampered, wrapper3.Format, wrapper3.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.envelope = wrapper3.envelope
+		w.envelopeBinding = signatureBinding(w)
+		err = VerifySignature(w)
+		if err != dsd.ErrInvalidSignature {
+			t.Fatalf("expected ErrInvalidSignature for tampered meta, got %v", err)
+		}
+	}
+
+	// signed deleted records
+	wrapper3.Meta().Delete()
+	raw, err = wrapper3.MarshalRecord(wrapper3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper5, err := NewRawWrapper("test", "a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignature(wrapper5)
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
 func oldWrapperMarshalRecord(w *Wrapper, r Record) ([]byte, error) {
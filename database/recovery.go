@@ -0,0 +1,459 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/safing/portbase/database/storage"
+	"github.com/safing/portbase/log"
+)
+
+// Recovery modes for damaged storages, see Database.Recovery.
+const (
+	// RecoveryDisabled leaves a damaged storage untouched, the database cannot be used until it is repaired manually.
+	RecoveryDisabled uint8 = iota
+	// RecoveryStartFresh moves a damaged storage aside and starts with an empty one. A storage is considered damaged if it reports storage.ErrCorrupted when starting or in its integrity check. Other errors, eg. missing permissions or a storage locked by another process, are returned as is.
+	RecoveryStartFresh
+	// RecoveryFromBackup moves a damaged storage aside and restores the latest usable backup. Backups are created with Backup and by MaintainThorough, which backs up loaded databases with this recovery mode once a day. If there is no usable backup, an empty storage is started.
+	RecoveryFromBackup
+)
+
+const (
+	backupsDirName    = "backups"
+	damagedDirName    = "damaged"
+	backupTimeFormat  = "20060102-150405"
+	damagedTimeFormat = "20060102-150405.000000000"
+
+	// backupInterval is the minimum age of the latest backup before MaintainThorough creates a new one.
+	backupInterval = 24 * time.Hour
+	// keptBackups is the number of backups kept per database, older ones are deleted.
+	keptBackups = 3
+)
+
+// RecoveryReport describes the recovery of a damaged storage.
+type RecoveryReport struct {
+	Database     string
+	StorageType  string
+	Cause        error
+	MovedTo      string // location the damaged storage was moved to
+	RestoredFrom string // location of the restored backup, empty if started fresh
+}
+
+var (
+	recoveryHandlers     []func(*RecoveryReport)
+	recoveryHandlersLock sync.Mutex
+)
+
+// OnRecovery registers a function that is called after a damaged storage was recovered. Handlers are called in a separate goroutine.
+func OnRecovery(fn func(*RecoveryReport)) {
+	recoveryHandlersLock.Lock()
+	defer recoveryHandlersLock.Unlock()
+
+	recoveryHandlers = append(recoveryHandlers, fn)
+}
+
+func reportRecovery(report *RecoveryReport) {
+	if report.RestoredFrom != "" {
+		log.Warningf("database: recovered damaged database %s from backup %s, damaged storage was moved to %s: %s", report.Database, report.RestoredFrom, report.MovedTo, report.Cause)
+	} else {
+		log.Warningf("database: recovered damaged database %s with empty storage, damaged storage was moved to %s: %s", report.Database, report.MovedTo, report.Cause)
+	}
+
+	recoveryHandlersLock.Lock()
+	handlers := make([]func(*RecoveryReport), len(recoveryHandlers))
+	copy(handlers, recoveryHandlers)
+	recoveryHandlersLock.Unlock()
+
+	for _, fn := range handlers {
+		fn(report)
+	}
+}
+
+// openStorage starts the storage at location, creating it if it does not exist yet, and verifies its integrity.
+func openStorage(name, storageType, location string) (storage.Interface, error) {
+	storageInt, err := storage.CreateDatabase(name, storageType, location)
+	if err == storage.ErrDatabaseExists {
+		storageInt, err = storage.StartDatabase(name, storageType, location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkStorage(storageInt)
+	if err != nil {
+		_ = storageInt.Shutdown()
+		return nil, err
+	}
+	return storageInt, nil
+}
+
+// checkStorage verifies the integrity of storages that support it.
+func checkStorage(storageInt storage.Interface) error {
+	checker, ok := storageInt.(storage.Checker)
+	if !ok {
+		return nil
+	}
+
+	// only errors wrapping storage.ErrCorrupted report damage, others are failures of the check itself
+	err := checker.Check()
+	if err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	return nil
+}
+
+// isDamaged returns whether the error reports a damaged storage, which may be recovered.
+func isDamaged(err error) bool {
+	return errors.Is(err, storage.ErrCorrupted)
+}
+
+// recoverStorage moves the damaged storage at location aside and starts a restored or empty storage in its place, according to the recovery mode of the database.
+func recoverStorage(name string, registeredDB *Database, location string, cause error) (storage.Interface, error) {
+	// a missing storage type is a configuration error, not a damaged storage
+	if !storage.IsRegistered(registeredDB.StorageType) {
+		return nil, cause
+	}
+
+	dbDir := filepath.Dir(location)
+	report := &RecoveryReport{
+		Database:    name,
+		StorageType: registeredDB.StorageType,
+		Cause:       cause,
+	}
+
+	// move damaged storage aside
+	damagedDir := filepath.Join(dbDir, damagedDirName)
+	err := os.MkdirAll(damagedDir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("could not recover database %s: %s", name, err)
+	}
+	report.MovedTo = filepath.Join(
+		damagedDir,
+		fmt.Sprintf("%s-%s", registeredDB.StorageType, time.Now().UTC().Format(damagedTimeFormat)),
+	)
+	err = os.Rename(location, report.MovedTo)
+	if err != nil {
+		return nil, fmt.Errorf("could not recover database %s: failed to move damaged storage aside: %s", name, err)
+	}
+
+	if registeredDB.Recovery == RecoveryFromBackup {
+		for _, backup := range listBackups(filepath.Join(dbDir, backupsDirName), registeredDB.StorageType) {
+			err = copyDir(backup, location)
+			if err == nil {
+				var storageInt storage.Interface
+				storageInt, err = openStorage(name, registeredDB.StorageType, location)
+				if err == nil {
+					report.RestoredFrom = backup
+					// handlers may use the database, which is being started
+					go reportRecovery(report)
+					return storageInt, nil
+				}
+			}
+
+			log.Warningf("database: failed to restore backup %s of database %s: %s", backup, name, err)
+			err = os.RemoveAll(location)
+			if err != nil {
+				return nil, fmt.Errorf("could not recover database %s: %s", name, err)
+			}
+		}
+	}
+
+	// start fresh
+	err = os.MkdirAll(location, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("could not recover database %s: %s", name, err)
+	}
+	storageInt, err := openStorage(name, registeredDB.StorageType, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not recover database %s: failed to start empty storage: %s", name, err)
+	}
+	// handlers may use the database, which is being started
+	go reportRecovery(report)
+	return storageInt, nil
+}
+
+// Backup copies the storage of the given database to its backups directory, from where it is restored when the database uses RecoveryFromBackup. Backups are copies of the storage directory, kept in the "backups" directory of the database and named "<storage type>-<timestamp>", with the timestamp formatted as "20060102-150405" in UTC. Only the latest backups are kept.
+// The storage is verified first and shut down while it is copied. Injected databases cannot be backed up.
+func Backup(name string) error {
+	controllersLock.Lock()
+	defer controllersLock.Unlock()
+
+	if shuttingDown.IsSet() {
+		return ErrShuttingDown
+	}
+
+	registryLock.Lock()
+	registeredDB, ok := registry[name]
+	registryLock.Unlock()
+	switch {
+	case !ok:
+		return fmt.Errorf(`database "%s" not registered`, name)
+	case registeredDB.StorageType == "injected":
+		return fmt.Errorf(`database "%s" is injected and cannot be backed up`, name)
+	}
+
+	location, err := getLocation(name, registeredDB.StorageType)
+	if err != nil {
+		return err
+	}
+	backupsDir := filepath.Join(filepath.Dir(location), backupsDirName)
+	backupLocation := filepath.Join(
+		backupsDir,
+		fmt.Sprintf("%s-%s", registeredDB.StorageType, time.Now().UTC().Format(backupTimeFormat)),
+	)
+	open := func() (storage.Interface, error) {
+		return openStorage(name, registeredDB.StorageType, location)
+	}
+
+	c, ok := controllers[name]
+	if ok {
+		err = c.backup(location, backupLocation, open)
+		if c.stopped() {
+			// the storage could not be started again, start over on next use
+			delete(controllers, name)
+		}
+	} else {
+		// verify storage before copying it
+		var storageInt storage.Interface
+		storageInt, err = open()
+		if err == nil {
+			err = storageInt.Shutdown()
+		}
+		if err == nil {
+			err = copyBackup(location, backupLocation)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up database %s: %s", name, err)
+	}
+
+	pruneBackups(backupsDir, registeredDB.StorageType)
+	return nil
+}
+
+// backup writes records waiting to be written, verifies the storage and copies it to backupLocation while it is shut down. The storage is started again with open afterwards, if that fails, the controller is marked as unloaded.
+func (c *Controller) backup(location, backupLocation string, open func() (storage.Interface, error)) error {
+	// acquire full locks
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if err := c.stoppedErr(); err != nil {
+		return err
+	}
+
+	err := c.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %s", err)
+	}
+	err = checkStorage(c.storage)
+	if err != nil {
+		return err
+	}
+
+	// the controller stays usable if the storage cannot be shut down
+	err = c.storage.Shutdown()
+	if err != nil {
+		return err
+	}
+	copyErr := copyBackup(location, backupLocation)
+
+	storageInt, err := open()
+	if err != nil {
+		c.unloaded.Set()
+		return fmt.Errorf("failed to start storage again: %s", err)
+	}
+	c.storage = storageInt
+	return copyErr
+}
+
+// backupDatabases backs up the loaded databases that use RecoveryFromBackup and were not backed up within backupInterval.
+func backupDatabases() error {
+	controllersLock.RLock()
+	loaded := make([]string, 0, len(controllers))
+	for name, c := range controllers {
+		if !c.Injected() {
+			loaded = append(loaded, name)
+		}
+	}
+	controllersLock.RUnlock()
+
+	var lastErr error
+	for _, name := range loaded {
+		registryLock.Lock()
+		registeredDB, ok := registry[name]
+		registryLock.Unlock()
+		if !ok || registeredDB.Recovery != RecoveryFromBackup {
+			continue
+		}
+
+		backups := listBackups(filepath.Join(databasesStructure.Path, name, backupsDirName), registeredDB.StorageType)
+		if len(backups) > 0 {
+			latest, err := time.Parse(backupTimeFormat, strings.TrimPrefix(filepath.Base(backups[0]), registeredDB.StorageType+"-"))
+			if err == nil && time.Since(latest) < backupInterval {
+				continue
+			}
+		}
+
+		err := Backup(name)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// copyBackup copies the storage at location to backupLocation and removes incomplete copies.
+func copyBackup(location, backupLocation string) error {
+	err := os.MkdirAll(filepath.Dir(backupLocation), 0700)
+	if err != nil {
+		return err
+	}
+	err = copyDir(location, backupLocation)
+	if err != nil {
+		_ = os.RemoveAll(backupLocation)
+		return err
+	}
+	return nil
+}
+
+// pruneBackups deletes all but the latest keptBackups backups of the given storage type in backupsDir.
+func pruneBackups(backupsDir, storageType string) {
+	backups := listBackups(backupsDir, storageType)
+	if len(backups) <= keptBackups {
+		return
+	}
+	for _, backup := range backups[keptBackups:] {
+		err := os.RemoveAll(backup)
+		if err != nil {
+			log.Warningf("database: failed to delete old backup %s: %s", backup, err)
+		}
+	}
+}
+
+// listBackups returns the backups of the given storage type in backupsDir, latest first.
+func listBackups(backupsDir, storageType string) []string {
+	entries, err := ioutil.ReadDir(backupsDir)
+	if err != nil {
+		return nil
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), storageType+"-") {
+			continue
+		}
+		_, err := time.Parse(backupTimeFormat, strings.TrimPrefix(entry.Name(), storageType+"-"))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(backupsDir, entry.Name()))
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// copyDir copies the directory src with all its files to dst.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// CheckStorages verifies the integrity of all loaded storages that support it. Damaged storages are recovered according to the recovery mode of their database.
+func CheckStorages() error {
+	controllersLock.RLock()
+	all := make(map[string]*Controller, len(controllers))
+	for name, c := range controllers {
+		all[name] = c
+	}
+	controllersLock.RUnlock()
+
+	var lastErr error
+	for name, c := range all {
+		if c.Injected() {
+			continue
+		}
+
+		checkErr := c.check()
+		if checkErr == nil {
+			continue
+		}
+		if !isDamaged(checkErr) {
+			lastErr = fmt.Errorf("failed to check database %s: %s", name, checkErr)
+			continue
+		}
+
+		registryLock.Lock()
+		registeredDB, ok := registry[name]
+		registryLock.Unlock()
+		if !ok {
+			lastErr = fmt.Errorf(`database "%s" not registered`, name)
+			continue
+		}
+		if registeredDB.Recovery == RecoveryDisabled {
+			log.Errorf("database: database %s is damaged: %s", name, checkErr)
+			lastErr = fmt.Errorf("database %s is damaged: %s", name, checkErr)
+			continue
+		}
+
+		err := c.replaceStorage(func() (storage.Interface, error) {
+			location, err := getLocation(name, registeredDB.StorageType)
+			if err != nil {
+				return nil, err
+			}
+			return recoverStorage(name, registeredDB, location, checkErr)
+		})
+		if err != nil {
+			// the controller is unusable, start over on next use
+			controllersLock.Lock()
+			if controllers[name] == c {
+				delete(controllers, name)
+			}
+			controllersLock.Unlock()
+			lastErr = err
+		}
+	}
+	return lastErr
+}
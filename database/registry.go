@@ -12,6 +12,10 @@
 	"time"
 
 	"github.com/tevino/abool"
+
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/formats/dsd"
+	"github.com/safing/portbase/utils"
 )
 
 const (
@@ -29,13 +33,38 @@
 
 // Register registers a new database.
 // If the database is already registered, only
-// the description and the primary API will be
-// updated and the effective object will be returned.
+// the description, the primary API, the search fields, the
+// compression, the recovery mode, the cache settings, the signing requirement and the quotas will be updated and the effective object will be returned.
+// The signing requirement and the quotas are also applied to the database if it is loaded.
 func Register(new *Database) (*Database, error) {
 	if !initialized.IsSet() {
 		return nil, errors.New("database not initialized")
 	}
 
+	if new.Compression != 0 && !dsd.HasCompression(new.Compression) {
+		return nil, fmt.Errorf("unknown compression %d", new.Compression)
+	}
+	if new.Recovery > RecoveryFromBackup {
+		return nil, fmt.Errorf("unknown recovery mode %d", new.Recovery)
+	}
+	if new.CacheSize < 0 || new.WriteBehindInterval < 0 {
+		return nil, errors.New("cache size and write-behind interval must not be negative")
+	}
+	if !new.SoftQuota.valid() || !new.HardQuota.valid() {
+		return nil, errors.New("quotas must not be negative")
+	}
+
+	registeredDB, settings, err := register(new)
+	if err != nil {
+		return nil, err
+	}
+	// the controllers lock must not be acquired while holding the registry lock
+	updateController(settings)
+	return registeredDB, nil
+}
+
+// register adds or updates the database in the registry and returns the effective object, if it was already registered, and a copy of the effective settings.
+func register(new *Database) (*Database, *Database, error) {
 	registryLock.Lock()
 	defer registryLock.Unlock()
 
@@ -52,10 +81,42 @@ func Register(new *Database) (*Database, error) {
 			registeredDB.PrimaryAPI = new.PrimaryAPI
 			save = true
 		}
+		if !utils.StringSliceEqual(registeredDB.SearchFields, new.SearchFields) {
+			registeredDB.SearchFields = new.SearchFields
+			save = true
+		}
+		if registeredDB.Compression != new.Compression {
+			registeredDB.Compression = new.Compression
+			save = true
+		}
+		if registeredDB.Recovery != new.Recovery {
+			registeredDB.Recovery = new.Recovery
+			save = true
+		}
+		if registeredDB.CacheSize != new.CacheSize {
+			registeredDB.CacheSize = new.CacheSize
+			save = true
+		}
+		if registeredDB.WriteBehindInterval != new.WriteBehindInterval {
+			registeredDB.WriteBehindInterval = new.WriteBehindInterval
+			save = true
+		}
+		if !quotasEqual(registeredDB.SoftQuota, new.SoftQuota) {
+			registeredDB.SoftQuota = new.SoftQuota
+			save = true
+		}
+		if !quotasEqual(registeredDB.HardQuota, new.HardQuota) {
+			registeredDB.HardQuota = new.HardQuota
+			save = true
+		}
+		if registeredDB.Signed != new.Signed {
+			registeredDB.Signed = new.Signed
+			save = true
+		}
 	} else {
 		// register new database
 		if !nameConstraint.MatchString(new.Name) {
-			return nil, errors.New("database name must only contain alphanumeric and `_-` characters and must be at least 4 characters long")
+			return nil, nil, errors.New("database name must only contain alphanumeric and `_-` characters and must be at least 4 characters long")
 		}
 
 		now := time.Now().Round(time.Second)
@@ -66,6 +127,7 @@ func Register(new *Database) (*Database, error) {
 		registry[new.Name] = new
 		save = true
 	}
+	record.SetDatabaseCompression(new.Name, new.Compression)
 
 	if save {
 		if ok {
@@ -73,14 +135,16 @@ func Register(new *Database) (*Database, error) {
 		}
 		err := saveRegistry(false)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	if ok {
-		return registeredDB, nil
+		settings := *registeredDB
+		return registeredDB, &settings, nil
 	}
-	return nil, nil
+	settings := *new
+	return nil, &settings, nil
 }
 
 func getDatabase(name string) (*Database, error) {
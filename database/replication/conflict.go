@@ -0,0 +1,28 @@
+package replication
+
+import (
+	"bytes"
+
+	"github.com/safing/portbase/database/record"
+)
+
+// Newer returns whether record a wins against record b. The later modification wins. If both were modified in the same second, a deletion wins over a change, and otherwise the record with the greater data wins, so that all nodes make the same decision.
+func Newer(a, b record.Record) bool {
+	aMeta, bMeta := a.Meta(), b.Meta()
+	switch {
+	case aMeta.Modified != bMeta.Modified:
+		return aMeta.Modified > bMeta.Modified
+	case aMeta.IsDeleted() != bMeta.IsDeleted():
+		return aMeta.IsDeleted()
+	}
+	return bytes.Compare(comparableData(a), comparableData(b)) > 0
+}
+
+// comparableData returns the record data in a format that is independent of how it is stored.
+func comparableData(r record.Record) []byte {
+	data, err := r.Marshal(r, record.JSON)
+	if err != nil {
+		data, _ = r.Marshal(r, record.AUTO)
+	}
+	return data
+}
@@ -0,0 +1,282 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/database"
+	"github.com/safing/portbase/database/record"
+	_ "github.com/safing/portbase/database/storage/hashmap"
+)
+
+type TestRecord struct {
+	record.Base
+	sync.Mutex
+	S string
+}
+
+var db = database.NewInterface(nil)
+
+func TestMain(m *testing.M) {
+	testDir, err := ioutil.TempDir("", "testing-")
+	if err != nil {
+		panic(err)
+	}
+
+	err = database.Initialize(testDir, nil)
+	if err != nil {
+		panic(err)
+	}
+	// node b uses the prefix "b-" for its databases
+	for _, name := range []string{"repl", "b-repl"} {
+		_, err = database.Register(&database.Database{
+			Name:        name,
+			Description: "Unit Test Database for Replication",
+			StorageType: "hashmap",
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	exitCode := m.Run()
+
+	_ = database.Shutdown()
+	_ = os.RemoveAll(testDir)
+	os.Exit(exitCode)
+}
+
+func put(t *testing.T, key, value string) *TestRecord {
+	r := &TestRecord{S: value}
+	r.SetKey(key)
+	err := db.Put(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// waitFor waits until the record at key has the given value, or is deleted if the value is empty.
+func waitFor(t *testing.T, key, value string) {
+	var current string
+	for i := 0; i < 100; i++ {
+		r, err := db.Get(key)
+		switch {
+		case err == database.ErrNotFound:
+			current = ""
+		case err != nil:
+			t.Fatal(err)
+		default:
+			tr := &TestRecord{}
+			err = record.Unwrap(r, tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current = tr.S
+		}
+		if current == value {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%s: expected %q, got %q", key, value, current)
+}
+
+// connect runs a session between the two replicators and returns a function to end it.
+func connect(t *testing.T, a, b *Replicator) (disconnect func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	connA, connB := net.Pipe()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, side := range []struct {
+		r    *Replicator
+		conn net.Conn
+	}{{a, connA}, {b, connB}} {
+		side := side
+		go func() {
+			defer wg.Done()
+			err := side.r.Serve(ctx, side.conn)
+			if err != nil && err != context.Canceled {
+				t.Logf("session of %s ended: %s", side.r.nodeID, err)
+			}
+		}()
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func TestReplication(t *testing.T) {
+	a := New("a", "repl")
+	b := New("b", "b-repl")
+	b.localPrefix = "b-"
+	for _, r := range []*Replicator{a, b} {
+		err := r.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Stop() //nolint:errcheck
+	}
+
+	// existing records
+	put(t, "repl:one", "1")
+	put(t, "b-repl:two", "2")
+	jewel := &TestRecord{S: "jewel"}
+	jewel.SetKey("repl:jewel")
+	jewel.SetMeta(&record.Meta{})
+	jewel.Meta().MakeCrownJewel()
+	err := db.Put(jewel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disconnect := connect(t, a, b)
+	waitFor(t, "b-repl:one", "1")
+	waitFor(t, "repl:two", "2")
+
+	// live changes and deletions
+	put(t, "repl:three", "3")
+	waitFor(t, "b-repl:three", "3")
+	err = db.Delete("b-repl:one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "repl:one", "")
+
+	// crown jewels stay local
+	_, err = db.Get("b-repl:jewel")
+	if err != database.ErrNotFound {
+		t.Errorf("crown jewel should not be replicated, got %v", err)
+	}
+
+	// resume after disconnect
+	disconnect()
+	put(t, "repl:four", "4")
+	put(t, "b-repl:five", "5")
+	disconnect = connect(t, a, b)
+	waitFor(t, "b-repl:four", "4")
+	waitFor(t, "repl:five", "5")
+	disconnect()
+
+	// a second connection of the same peer is refused
+	_, err = a.connectPeer("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.connectPeer("b")
+	if err == nil {
+		t.Error("duplicate connection of peer should fail")
+	}
+}
+
+func TestNewer(t *testing.T) {
+	newRecord := func(value string, modified, deleted int64) *TestRecord {
+		r := &TestRecord{S: value}
+		r.SetKey("repl:conflict")
+		r.SetMeta(&record.Meta{
+			Modified: modified,
+			Deleted:  deleted,
+		})
+		return r
+	}
+
+	older := newRecord("z", 100, 0)
+	newer := newRecord("a", 200, 0)
+	if !Newer(newer, older) || Newer(older, newer) {
+		t.Error("later modification should win")
+	}
+
+	deleted := newRecord("a", 100, 100)
+	if !Newer(deleted, older) || Newer(older, deleted) {
+		t.Error("deletion should win on same modification time")
+	}
+
+	x := newRecord("x", 100, 0)
+	y := newRecord("y", 100, 0)
+	if Newer(x, y) == Newer(y, x) {
+		t.Error("tie must be broken deterministically")
+	}
+	if Newer(x, x) {
+		t.Error("equal records must not win against each other")
+	}
+}
+
+func TestRequestBeforeHello(t *testing.T) {
+	r := New("c")
+	conn, peerConn := net.Pipe()
+	// drain the hello of r
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, peerConn)
+	}()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- r.Serve(context.Background(), conn)
+	}()
+
+	request, err := json.Marshal(&message{Type: msgRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if container.WriteBlock(peerConn, request) != nil {
+			break
+		}
+	}
+
+	select {
+	case err = <-errs:
+		if err == nil {
+			t.Error("request before hello should fail the session")
+		}
+	case <-time.After(time.Second):
+		t.Error("session did not end")
+	}
+	_ = peerConn.Close()
+}
+
+func TestResyncAfterDrop(t *testing.T) {
+	r := New("resync", "repl")
+	err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop() //nolint:errcheck
+
+	p, err := r.connectPeer("peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.lock.Lock()
+	p.requested["repl"] = struct{}{}
+	r.lock.Unlock()
+
+	// a record that did not reach the dispatcher
+	missed := put(t, "repl:missed", "missed")
+	time.Sleep(20 * time.Millisecond)
+	r.lock.Lock()
+	delete(p.pending, missed.Key())
+	r.lock.Unlock()
+
+	err = r.resync("repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.lock.Lock()
+	_, ok := p.pending[missed.Key()]
+	r.lock.Unlock()
+	if !ok {
+		t.Error("resync should queue all records")
+	}
+}
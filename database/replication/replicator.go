@@ -0,0 +1,390 @@
+/*
+Package replication replicates databases between portbase instances.
+
+Both instances create a Replicator for the databases they want to share and run a session on their end of a connection, using Serve or Connect. Each side first sends all records that were modified since the peer last received records from it, and then streams changes as they happen. Crown jewels are never replicated, secrets are.
+
+Conflicts are resolved per record: the version with the later Meta.Modified wins. If both versions were modified in the same second, a deletion wins over a change and otherwise the version with the greater data wins, so that all nodes choose the same version.
+
+Replicators remember per peer up to which modification time records were received, and queue local changes while a peer is disconnected, so that sessions resume where they left off. Changes made while the replicator is not running are picked up by the next session, except for deletions. If the replicator falls behind and its subscription drops changes, all records of the database are queued again.
+*/
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/safing/portbase/database"
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/log"
+)
+
+// Errors
+var (
+	ErrStopped    = errors.New("replicator stopped")
+	ErrNotStarted = errors.New("replicator not started")
+)
+
+var (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 1 * time.Minute
+
+	// resyncCheckInterval is the interval in which the subscriptions are checked for dropped changes, if there are no new changes.
+	resyncCheckInterval = 10 * time.Second
+)
+
+// Replicator replicates a set of databases with peers.
+type Replicator struct {
+	nodeID    string
+	databases []string
+	db        *database.Interface
+
+	// localPrefix is prepended to database names received from peers, which allows running two nodes within one process in tests.
+	localPrefix string
+
+	lock          sync.Mutex
+	peers         map[string]*peer
+	subscriptions []*database.Subscription
+	started       bool
+	stop          chan struct{}
+	dispatchers   sync.WaitGroup
+}
+
+// peer holds the replication state of a peer. All fields are guarded by the lock of the replicator.
+type peer struct {
+	id        string
+	connected bool
+
+	// since holds the modification time of the latest record received per database, in order to resume from there.
+	since map[string]int64
+
+	// requested holds the databases the peer requested in its last session, local changes of these are queued.
+	requested map[string]struct{}
+	pending   map[string]*change
+	notify    chan struct{}
+
+	// received holds the records received from the peer, so that they are not sent back.
+	received map[string][]byte
+}
+
+// change is a local change of a record, ready to be sent.
+type change struct {
+	dbName   string
+	dbKey    string
+	modified int64
+	data     []byte
+}
+
+// New returns a replicator for the given databases. The node ID identifies the replicator to its peers and must be unique.
+func New(nodeID string, databases ...string) *Replicator {
+	return &Replicator{
+		nodeID:    nodeID,
+		databases: databases,
+		db: database.NewInterface(&database.Options{
+			Local:    true,
+			Internal: true,
+		}),
+		peers: make(map[string]*peer),
+		stop:  make(chan struct{}),
+	}
+}
+
+// Start subscribes to the changes of the replicated databases. It must be called before any session is served.
+func (r *Replicator) Start() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.started {
+		return errors.New("replicator already started")
+	}
+
+	for _, dbName := range r.databases {
+		sub, err := r.db.Subscribe(query.New(dbName + ":"))
+		if err != nil {
+			r.cancelSubscriptions()
+			return fmt.Errorf("failed to subscribe to database %s: %s", dbName, err)
+		}
+		r.subscriptions = append(r.subscriptions, sub)
+
+		r.dispatchers.Add(1)
+		go r.dispatcher(dbName, sub)
+	}
+
+	r.started = true
+	return nil
+}
+
+// Stop ends all sessions and stops watching for changes. A stopped replicator cannot be started again.
+func (r *Replicator) Stop() error {
+	r.lock.Lock()
+	if !r.started {
+		r.lock.Unlock()
+		return ErrNotStarted
+	}
+	r.started = false
+	close(r.stop)
+	err := r.cancelSubscriptions()
+	r.lock.Unlock()
+
+	r.dispatchers.Wait()
+	return err
+}
+
+// cancelSubscriptions cancels all subscriptions. The caller must hold the lock.
+func (r *Replicator) cancelSubscriptions() (err error) {
+	for _, sub := range r.subscriptions {
+		cancelErr := sub.Cancel()
+		if cancelErr != nil {
+			err = cancelErr
+		}
+	}
+	r.subscriptions = nil
+	return err
+}
+
+// ForgetPeer drops the state of a disconnected peer, including its queued changes. The next session with the peer starts from scratch.
+func (r *Replicator) ForgetPeer(id string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	p, ok := r.peers[id]
+	if !ok {
+		return nil
+	}
+	if p.connected {
+		return fmt.Errorf("peer %s is connected", id)
+	}
+	delete(r.peers, id)
+	return nil
+}
+
+// Connect keeps a session with a peer running, dialing again with increasing delays after disconnects, until the context is canceled or the replicator is stopped.
+func (r *Replicator) Connect(ctx context.Context, dial func() (io.ReadWriteCloser, error)) {
+	delay := minReconnectDelay
+	for {
+		conn, err := dial()
+		if err == nil {
+			started := time.Now()
+			err = r.Serve(ctx, conn)
+			if time.Since(started) > maxReconnectDelay {
+				delay = minReconnectDelay
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stop:
+			return
+		default:
+		}
+		log.Debugf("replication: session ended (%s), reconnecting in %s", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		case <-r.stop:
+			return
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (r *Replicator) dispatcher(dbName string, sub *database.Subscription) {
+	defer r.dispatchers.Done()
+
+	ticker := time.NewTicker(resyncCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case rec, ok := <-sub.Feed:
+			if !ok {
+				return
+			}
+			r.dispatch(rec)
+		case <-ticker.C:
+		}
+
+		// the feed drops changes when it is full, queue the whole database again to not miss any
+		if sub.Dropped() {
+			err := r.resync(dbName)
+			if err != nil {
+				log.Warningf("replication: failed to resync database %s after dropped changes: %s", dbName, err)
+			}
+		}
+	}
+}
+
+// dispatch queues a changed record.
+func (r *Replicator) dispatch(rec record.Record) {
+	rec.Lock()
+	ch, err := newChange(rec)
+	rec.Unlock()
+	if err != nil {
+		log.Warningf("replication: failed to prepare change of %s: %s", rec.Key(), err)
+		return
+	}
+	if ch != nil {
+		r.queue(ch)
+	}
+}
+
+// resync queues all records of the database, for when changes were missed. Missed deletions cannot be recovered.
+func (r *Replicator) resync(dbName string) error {
+	it, err := r.db.Query(query.New(dbName + ":"))
+	if err != nil {
+		return err
+	}
+	for rec := range it.Next {
+		r.dispatch(rec)
+	}
+	return it.Err()
+}
+
+// newChange prepares a record for sending. It returns nil for records that must not be replicated. The caller must hold the record lock.
+func newChange(rec record.Record) (*change, error) {
+	if rec.Meta() == nil || rec.Meta().IsCrownJewel() {
+		return nil, nil
+	}
+
+	data, err := rec.MarshalRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return &change{
+		dbName:   rec.DatabaseName(),
+		dbKey:    rec.DatabaseKey(),
+		modified: rec.Meta().Modified,
+		data:     data,
+	}, nil
+}
+
+// queue queues a local change for all peers that requested its database.
+func (r *Replicator) queue(ch *change) {
+	key := ch.dbName + ":" + ch.dbKey
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, p := range r.peers {
+		if _, ok := p.requested[ch.dbName]; !ok {
+			continue
+		}
+		// do not send records back to where they came from
+		if data, ok := p.received[key]; ok && bytes.Equal(data, ch.data) {
+			delete(p.received, key)
+			continue
+		}
+
+		p.pending[key] = ch
+		select {
+		case p.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// takePending returns and removes all queued changes of the peer, oldest first.
+func (r *Replicator) takePending(p *peer) []*change {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	changes := make([]*change, 0, len(p.pending))
+	for key, ch := range p.pending {
+		changes = append(changes, ch)
+		delete(p.pending, key)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].modified != changes[j].modified {
+			return changes[i].modified < changes[j].modified
+		}
+		return changes[i].dbKey < changes[j].dbKey
+	})
+	return changes
+}
+
+// requeue queues changes again that could not be sent, unless there is a newer change of the same record.
+func (r *Replicator) requeue(p *peer, changes []*change) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, ch := range changes {
+		key := ch.dbName + ":" + ch.dbKey
+		if _, ok := p.pending[key]; !ok {
+			p.pending[key] = ch
+		}
+	}
+}
+
+// connectPeer returns the state of the peer with the given ID and marks it as connected.
+func (r *Replicator) connectPeer(id string) (*peer, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch {
+	case !r.started:
+		return nil, ErrNotStarted
+	case id == "":
+		return nil, errors.New("peer did not send a node ID")
+	case id == r.nodeID:
+		return nil, errors.New("peer has the same node ID")
+	}
+
+	p, ok := r.peers[id]
+	if !ok {
+		p = &peer{
+			id:        id,
+			since:     make(map[string]int64),
+			requested: make(map[string]struct{}),
+			pending:   make(map[string]*change),
+			notify:    make(chan struct{}, 1),
+			received:  make(map[string][]byte),
+		}
+		r.peers[id] = p
+	}
+	if p.connected {
+		return nil, fmt.Errorf("peer %s is already connected", id)
+	}
+	p.connected = true
+	return p, nil
+}
+
+func (r *Replicator) disconnectPeer(p *peer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	p.connected = false
+}
+
+// wireName returns the name of a local database as sent to peers.
+func (r *Replicator) wireName(dbName string) string {
+	return strings.TrimPrefix(dbName, r.localPrefix)
+}
+
+// localName returns the local name of a database received from a peer.
+func (r *Replicator) localName(dbName string) string {
+	return r.localPrefix + dbName
+}
+
+// replicates returns whether the given database is replicated.
+func (r *Replicator) replicates(dbName string) bool {
+	for _, name := range r.databases {
+		if name == dbName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,340 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/log"
+)
+
+// A session consists of the following messages, sent by both sides:
+// 1. hello, with the node ID.
+// 2. request, with the modification time to resume from per database, after receiving the hello of the peer.
+// 3. all records of the requested databases modified since then, followed by synced for each database.
+// 4. records as they change.
+
+const (
+	msgHello uint8 = iota + 1
+	msgRequest
+	msgRecord
+	msgSynced
+)
+
+// maxMessageSize is the maximum size of a message, and thus of a single record.
+const maxMessageSize = 1 << 26 // 64MB
+
+type message struct {
+	Type     uint8
+	NodeID   string           `json:",omitempty"`
+	Since    map[string]int64 `json:",omitempty"`
+	Database string           `json:",omitempty"`
+	Key      string           `json:",omitempty"`
+	Data     []byte           `json:",omitempty"`
+}
+
+type session struct {
+	r    *Replicator
+	conn io.ReadWriteCloser
+	done chan struct{}
+
+	// handed from the receiver to the sender
+	hello   chan *peer
+	request chan map[string]int64
+
+	// owned by the receiver
+	peer   *peer
+	synced map[string]bool
+	latest map[string]int64
+}
+
+// Serve runs a replication session on the given connection until the connection fails, the context is canceled or the replicator is stopped. The connection is closed when Serve returns.
+func (r *Replicator) Serve(ctx context.Context, conn io.ReadWriteCloser) error {
+	s := &session{
+		r:       r,
+		conn:    conn,
+		done:    make(chan struct{}),
+		hello:   make(chan *peer, 1),
+		request: make(chan map[string]int64, 1),
+		synced:  make(map[string]bool),
+		latest:  make(map[string]int64),
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- s.receive()
+	}()
+	go func() {
+		errs <- s.send()
+	}()
+
+	var err error
+	running := 2
+	select {
+	case err = <-errs:
+		running--
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-r.stop:
+		err = ErrStopped
+	}
+
+	// stop the other side
+	close(s.done)
+	_ = conn.Close()
+	for ; running > 0; running-- {
+		<-errs
+	}
+
+	if s.peer != nil {
+		r.disconnectPeer(s.peer)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+func (s *session) receive() error {
+	decoder := container.NewBlockDecoder(s.conn, maxMessageSize)
+	for {
+		data, err := decoder.NextBlock()
+		if err != nil {
+			return err
+		}
+		msg := &message{}
+		err = json.Unmarshal(data, msg)
+		if err != nil {
+			return fmt.Errorf("failed to parse message: %s", err)
+		}
+
+		switch msg.Type {
+		case msgHello:
+			if s.peer != nil {
+				return fmt.Errorf("duplicate hello from peer %s", s.peer.id)
+			}
+			s.peer, err = s.r.connectPeer(msg.NodeID)
+			if err != nil {
+				return err
+			}
+			s.hello <- s.peer
+		case msgRequest:
+			if s.peer == nil {
+				return fmt.Errorf("received request before hello")
+			}
+			select {
+			case s.request <- msg.Since:
+			default:
+				return fmt.Errorf("received duplicate request")
+			}
+		case msgRecord:
+			if s.peer == nil {
+				return fmt.Errorf("received record before hello")
+			}
+			err = s.apply(msg)
+			if err != nil {
+				return err
+			}
+		case msgSynced:
+			if s.peer == nil {
+				return fmt.Errorf("received synced before hello")
+			}
+			dbName := s.r.localName(msg.Database)
+			s.synced[dbName] = true
+			s.commit(dbName)
+		default:
+			return fmt.Errorf("received unknown message type %d", msg.Type)
+		}
+	}
+}
+
+// apply saves a record received from the peer, if it wins against the local version.
+func (s *session) apply(msg *message) error {
+	dbName := s.r.localName(msg.Database)
+	if !s.r.replicates(dbName) {
+		return nil
+	}
+
+	rec, err := record.NewRawWrapper(dbName, msg.Key, msg.Data)
+	if err != nil {
+		return fmt.Errorf("failed to parse record %s:%s: %s", dbName, msg.Key, err)
+	}
+	if rec.Meta().IsCrownJewel() {
+		return nil
+	}
+	modified := rec.Meta().Modified
+
+	s.r.lock.Lock()
+	s.peer.received[rec.Key()] = msg.Data
+	s.r.lock.Unlock()
+
+	saved, err := s.r.db.PutIf(rec, func(stored record.Record) bool {
+		return stored == nil || Newer(rec, stored)
+	})
+	if err != nil {
+		// a single record must not block the whole replication
+		log.Warningf("replication: failed to save %s from peer %s: %s", rec.Key(), s.peer.id, err)
+	}
+	if !saved {
+		s.r.lock.Lock()
+		delete(s.peer.received, rec.Key())
+		s.r.lock.Unlock()
+	}
+
+	if modified > s.latest[dbName] {
+		s.latest[dbName] = modified
+	}
+	// during the initial sync, records are not ordered
+	if s.synced[dbName] {
+		s.commit(dbName)
+	}
+	return nil
+}
+
+// commit saves the resume point of the given database.
+func (s *session) commit(dbName string) {
+	s.r.lock.Lock()
+	defer s.r.lock.Unlock()
+
+	if s.latest[dbName] > s.peer.since[dbName] {
+		s.peer.since[dbName] = s.latest[dbName]
+	}
+}
+
+func (s *session) send() error {
+	err := s.write(&message{
+		Type:   msgHello,
+		NodeID: s.r.nodeID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// request changes since the last session
+	var p *peer
+	select {
+	case p = <-s.hello:
+	case <-s.done:
+		return nil
+	}
+	since := make(map[string]int64, len(s.r.databases))
+	s.r.lock.Lock()
+	for _, dbName := range s.r.databases {
+		since[s.r.wireName(dbName)] = p.since[dbName]
+	}
+	s.r.lock.Unlock()
+	err = s.write(&message{
+		Type:  msgRequest,
+		Since: since,
+	})
+	if err != nil {
+		return err
+	}
+
+	// wait for the request of the peer
+	var requested map[string]int64
+	select {
+	case requested = <-s.request:
+	case <-s.done:
+		return nil
+	}
+	dbNames := make([]string, 0, len(requested))
+	s.r.lock.Lock()
+	p.requested = make(map[string]struct{})
+	for wireName := range requested {
+		dbName := s.r.localName(wireName)
+		if s.r.replicates(dbName) {
+			p.requested[dbName] = struct{}{}
+			dbNames = append(dbNames, dbName)
+		}
+	}
+	s.r.lock.Unlock()
+	sort.Strings(dbNames)
+
+	// initial sync
+	for _, dbName := range dbNames {
+		err = s.sendDatabase(dbName, requested[s.r.wireName(dbName)])
+		if err != nil {
+			return err
+		}
+		err = s.write(&message{
+			Type:     msgSynced,
+			Database: s.r.wireName(dbName),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// stream changes
+	for {
+		changes := s.r.takePending(p)
+		for i, ch := range changes {
+			err = s.write(&message{
+				Type:     msgRecord,
+				Database: s.r.wireName(ch.dbName),
+				Key:      ch.dbKey,
+				Data:     ch.data,
+			})
+			if err != nil {
+				s.r.requeue(p, changes[i:])
+				return err
+			}
+		}
+
+		if len(changes) == 0 {
+			select {
+			case <-p.notify:
+			case <-s.done:
+				return nil
+			}
+		}
+	}
+}
+
+// sendDatabase sends all records of the given database modified since the given time.
+func (s *session) sendDatabase(dbName string, since int64) error {
+	it, err := s.r.db.Query(query.New(dbName + ":"))
+	if err != nil {
+		return fmt.Errorf("failed to query database %s: %s", dbName, err)
+	}
+
+	for rec := range it.Next {
+		rec.Lock()
+		ch, err := newChange(rec)
+		rec.Unlock()
+		if err != nil {
+			it.Cancel()
+			return fmt.Errorf("failed to prepare %s: %s", rec.Key(), err)
+		}
+		if ch == nil || ch.modified < since {
+			continue
+		}
+
+		err = s.write(&message{
+			Type:     msgRecord,
+			Database: s.r.wireName(ch.dbName),
+			Key:      ch.dbKey,
+			Data:     ch.data,
+		})
+		if err != nil {
+			it.Cancel()
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// write sends a message to the peer. Only the sender may write.
+func (s *session) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return container.WriteBlock(s.conn, data)
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+)
+
+// searchIndex is an in-memory inverted index for full-text search over selected record fields.
+type searchIndex struct {
+	sync.RWMutex
+
+	fields []string
+	ready  bool
+
+	postings map[string]map[string]int // token -> dbKey -> occurrences
+	indexed  map[string][]string       // dbKey -> tokens
+}
+
+type searchHit struct {
+	dbKey   string
+	matched int
+	count   int
+}
+
+func newSearchIndex(fields []string) *searchIndex {
+	return &searchIndex{
+		fields:   fields,
+		postings: make(map[string]map[string]int),
+		indexed:  make(map[string][]string),
+	}
+}
+
+// build fills the index with all records of the given storage, if not yet done.
+func (si *searchIndex) build(storageInt storage.Interface) error {
+	si.Lock()
+	defer si.Unlock()
+
+	if si.ready {
+		return nil
+	}
+
+	q, err := query.New("").Check()
+	if err != nil {
+		return err
+	}
+	it, err := storageInt.Query(q, true, true)
+	if err != nil {
+		return err
+	}
+	for r := range it.Next {
+		r.Lock()
+		si.add(r)
+		r.Unlock()
+	}
+	if it.Err() != nil {
+		return it.Err()
+	}
+
+	si.ready = true
+	return nil
+}
+
+// update (re-)indexes the given record. The record must be locked.
+func (si *searchIndex) update(r record.Record) {
+	si.Lock()
+	defer si.Unlock()
+
+	// records are picked up by build, if the index is not ready yet
+	if !si.ready {
+		return
+	}
+
+	si.remove(r.DatabaseKey())
+	if r.Meta().CheckValidity() {
+		si.add(r)
+	}
+}
+
+// delete removes the record with the given key from the index.
+func (si *searchIndex) delete(dbKey string) {
+	si.Lock()
+	defer si.Unlock()
+
+	si.remove(dbKey)
+}
+
+func (si *searchIndex) add(r record.Record) {
+	acc := r.GetAccessor(r)
+	if acc == nil {
+		return
+	}
+
+	var tokens []string
+	for _, field := range si.fields {
+		if value, ok := acc.GetString(field); ok {
+			tokens = append(tokens, query.Tokenize(value)...)
+		} else if values, ok := acc.GetStringArray(field); ok {
+			tokens = append(tokens, query.Tokenize(strings.Join(values, " "))...)
+		}
+	}
+	if len(tokens) == 0 {
+		return
+	}
+
+	dbKey := r.DatabaseKey()
+	for _, token := range tokens {
+		keys, ok := si.postings[token]
+		if !ok {
+			keys = make(map[string]int)
+			si.postings[token] = keys
+		}
+		keys[dbKey]++
+	}
+	si.indexed[dbKey] = tokens
+}
+
+func (si *searchIndex) remove(dbKey string) {
+	tokens, ok := si.indexed[dbKey]
+	if !ok {
+		return
+	}
+
+	for _, token := range tokens {
+		keys := si.postings[token]
+		delete(keys, dbKey)
+		if len(keys) == 0 {
+			delete(si.postings, token)
+		}
+	}
+	delete(si.indexed, dbKey)
+}
+
+// find returns the keys of all records matching at least one of the terms, ranked by the number of matched terms first and the total number of matches second.
+func (si *searchIndex) find(keyPrefix string, terms []string) []string {
+	si.RLock()
+	defer si.RUnlock()
+
+	hits := make(map[string]*searchHit)
+	for _, term := range terms {
+		for dbKey, count := range si.postings[term] {
+			if !strings.HasPrefix(dbKey, keyPrefix) {
+				continue
+			}
+			hit, ok := hits[dbKey]
+			if !ok {
+				hit = &searchHit{dbKey: dbKey}
+				hits[dbKey] = hit
+			}
+			hit.matched++
+			hit.count += count
+		}
+	}
+
+	ranked := make([]*searchHit, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, hit)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		switch {
+		case ranked[i].matched != ranked[j].matched:
+			return ranked[i].matched > ranked[j].matched
+		case ranked[i].count != ranked[j].count:
+			return ranked[i].count > ranked[j].count
+		default:
+			return ranked[i].dbKey < ranked[j].dbKey
+		}
+	})
+
+	keys := make([]string, len(ranked))
+	for i, hit := range ranked {
+		keys[i] = hit.dbKey
+	}
+	return keys
+}
@@ -3,6 +3,7 @@
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger"
@@ -36,6 +37,9 @@ func NewBadger(name, location string) (storage.Interface, error) {
 		db, err = badger.Open(opts)
 	}
 	if err != nil {
+		if isCorruption(err) {
+			return nil, fmt.Errorf("%w: failed to open badger database: %s", storage.ErrCorrupted, err)
+		}
 		return nil, err
 	}
 
@@ -45,6 +49,30 @@ func NewBadger(name, location string) (storage.Interface, error) {
 	}, nil
 }
 
+// corruptionMessages are parts of the messages of the errors badger returns for a damaged MANIFEST, table or value log. Badger does not export these errors.
+var corruptionMessages = []string{
+	"manifest has bad magic",
+	"manifest has checksum mismatch",
+	"Manifest file might be corrupted",
+	"MANIFEST invalid",
+	"MANIFEST removes non-existing table",
+	"MANIFEST file has invalid manifestChange op",
+	"file does not exist for table",
+	"CHECKSUM_MISMATCH",
+	"checksum mismatch",
+}
+
+// isCorruption returns whether the error reports a damaged database directory.
+func isCorruption(err error) bool {
+	msg := err.Error()
+	for _, part := range corruptionMessages {
+		if strings.Contains(msg, part) {
+			return true
+		}
+	}
+	return false
+}
+
 // Get returns a database record.
 func (b *Badger) Get(key string) (record.Record, error) {
 	var item *badger.Item
@@ -139,11 +167,14 @@ func (b *Badger) queryExecutor(queryIter *iterator.Iterator, q *query.Query, loc
 			if err != nil {
 				return err
 			}
+			queryIter.CountScanned()
 
 			if !r.Meta().CheckValidity() {
+				queryIter.CountSkippedValidity()
 				continue
 			}
 			if !r.Meta().CheckPermission(local, internal) {
+				queryIter.CountSkippedPermission()
 				continue
 			}
 
@@ -156,6 +187,7 @@ func (b *Badger) queryExecutor(queryIter *iterator.Iterator, q *query.Query, loc
 				if err != nil {
 					return err
 				}
+				queryIter.CountMatched()
 				select {
 				case <-queryIter.Done:
 					return nil
@@ -178,6 +210,99 @@ func (b *Badger) queryExecutor(queryIter *iterator.Iterator, q *query.Query, loc
 	queryIter.Finish(err)
 }
 
+// ReadRange returns the next records of the key range.
+func (b *Badger) ReadRange(kr *storage.KeyRange, local, internal bool) (records []record.Record, more bool, err error) {
+	err = b.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = kr.Reverse
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// in reverse, badger seeks to the last key equal or before the seek key
+		seekKey := kr.SeekKey()
+		if seekKey == "" {
+			it.Rewind()
+		} else {
+			it.Seek([]byte(seekKey))
+		}
+
+		for ; it.Valid(); it.Next() {
+			item := it.Item()
+			key := string(item.Key())
+			if kr.Passed(key) {
+				return nil
+			}
+			if !kr.Contains(key) {
+				continue
+			}
+
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			r, err := record.NewRawWrapper(b.name, key, data)
+			if err != nil {
+				return err
+			}
+			if !r.Meta().CheckValidity() || !r.Meta().CheckPermission(local, internal) {
+				continue
+			}
+
+			if kr.Limit > 0 && len(records) == kr.Limit {
+				more = true
+				return nil
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return records, more, nil
+}
+
+// CountKeys returns the number of keys with the given prefix.
+func (b *Badger) CountKeys(prefix string) (n int, err error) {
+	err = b.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		prefixBytes := []byte(prefix)
+		for it.Seek(prefixBytes); it.ValidForPrefix(prefixBytes); it.Next() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Check verifies that all records can be read and parsed.
+func (b *Badger) Check() error {
+	return b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				_, _, _, err := record.SplitRawRecord(val)
+				if err != nil {
+					return fmt.Errorf("%w: record %s is damaged: %s", storage.ErrCorrupted, item.Key(), err)
+				}
+				return nil
+			})
+			if err != nil {
+				if isCorruption(err) {
+					return fmt.Errorf("%w: %s", storage.ErrCorrupted, err)
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // ReadOnly returns whether the database is read only.
 func (b *Badger) ReadOnly() bool {
 	return false
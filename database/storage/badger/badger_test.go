@@ -2,14 +2,18 @@
 package badger
 
 import (
+	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
 
 	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
 )
 
 type TestRecord struct {
@@ -131,3 +135,20 @@ func TestBadger(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCorruptedBadger(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "testing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir) // clean up
+
+	err = ioutil.WriteFile(filepath.Join(testDir, "MANIFEST"), bytes.Repeat([]byte("damaged "), 1024), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewBadger("test", testDir)
+	if !errors.Is(err, storage.ErrCorrupted) {
+		t.Fatalf("expected ErrCorrupted, got %v", err)
+	}
+}
@@ -34,6 +34,9 @@ func NewBBolt(name, location string) (storage.Interface, error) {
 
 	db, err := bbolt.Open(filepath.Join(location, "db.bbolt"), 0600, nil)
 	if err != nil {
+		if isCorruption(err) {
+			return nil, fmt.Errorf("%w: failed to open bbolt database: %s", storage.ErrCorrupted, err)
+		}
 		return nil, err
 	}
 
@@ -55,6 +58,11 @@ func NewBBolt(name, location string) (storage.Interface, error) {
 	}, nil
 }
 
+// isCorruption returns whether the error reports a damaged database file.
+func isCorruption(err error) bool {
+	return err == bbolt.ErrInvalid || err == bbolt.ErrChecksum || err == bbolt.ErrVersionMismatch
+}
+
 // Get returns a database record.
 func (b *BBolt) Get(key string) (record.Record, error) {
 	var r record.Record
@@ -156,12 +164,15 @@ func (b *BBolt) queryExecutor(queryIter *iterator.Iterator, q *query.Query, loca
 			if err != nil {
 				return err
 			}
+			queryIter.CountScanned()
 
 			// check validity / access
 			if !iterWrapper.Meta().CheckValidity() {
+				queryIter.CountSkippedValidity()
 				continue
 			}
 			if !iterWrapper.Meta().CheckPermission(local, internal) {
+				queryIter.CountSkippedPermission()
 				continue
 			}
 
@@ -175,6 +186,7 @@ func (b *BBolt) queryExecutor(queryIter *iterator.Iterator, q *query.Query, loca
 				if err != nil {
 					return err
 				}
+				queryIter.CountMatched()
 				select {
 				case <-queryIter.Done:
 					return nil
@@ -195,6 +207,104 @@ func (b *BBolt) queryExecutor(queryIter *iterator.Iterator, q *query.Query, loca
 	queryIter.Finish(err)
 }
 
+// ReadRange returns the next records of the key range.
+func (b *BBolt) ReadRange(kr *storage.KeyRange, local, internal bool) (records []record.Record, more bool, err error) {
+	err = b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+
+		var key, value []byte
+		next := c.Next
+		seekKey := kr.SeekKey()
+		switch {
+		case !kr.Reverse:
+			key, value = c.Seek([]byte(seekKey))
+		case seekKey == "":
+			next = c.Prev
+			key, value = c.Last()
+		default:
+			// the cursor is placed at the first key equal or after the seek key, which is skipped as it is not in the range
+			next = c.Prev
+			key, value = c.Seek([]byte(seekKey))
+			if key == nil {
+				key, value = c.Last()
+			}
+		}
+
+		for ; key != nil; key, value = next() {
+			if kr.Passed(string(key)) {
+				return nil
+			}
+			if !kr.Contains(string(key)) {
+				continue
+			}
+
+			// copy data
+			duplicate := make([]byte, len(value))
+			copy(duplicate, value)
+
+			r, err := record.NewRawWrapper(b.name, string(key), duplicate)
+			if err != nil {
+				return err
+			}
+			if !r.Meta().CheckValidity() || !r.Meta().CheckPermission(local, internal) {
+				continue
+			}
+
+			if kr.Limit > 0 && len(records) == kr.Limit {
+				more = true
+				return nil
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return records, more, nil
+}
+
+// CountKeys returns the number of keys with the given prefix.
+func (b *BBolt) CountKeys(prefix string) (n int, err error) {
+	prefixBytes := []byte(prefix)
+	err = b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		for key, _ := c.Seek(prefixBytes); key != nil && bytes.HasPrefix(key, prefixBytes); key, _ = c.Next() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Check verifies the consistency of the database file and that all records can be parsed.
+func (b *BBolt) Check() error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		// the channel must be drained completely
+		var checkErr error
+		for err := range tx.Check() {
+			if checkErr == nil {
+				checkErr = err
+			}
+		}
+		if checkErr != nil {
+			return fmt.Errorf("%w: %s", storage.ErrCorrupted, checkErr)
+		}
+
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return fmt.Errorf("%w: records bucket is missing", storage.ErrCorrupted)
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			_, _, _, err := record.SplitRawRecord(value)
+			if err != nil {
+				return fmt.Errorf("%w: record %s is damaged: %s", storage.ErrCorrupted, key, err)
+			}
+			return nil
+		})
+	})
+}
+
 // ReadOnly returns whether the database is read only.
 func (b *BBolt) ReadOnly() bool {
 	return false
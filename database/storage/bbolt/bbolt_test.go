@@ -2,14 +2,18 @@
 package bbolt
 
 import (
+	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
 
 	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
 )
 
 type TestRecord struct {
@@ -152,6 +156,10 @@ func TestBadger(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = db.(storage.Checker).Check()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// shutdown
 	err = db.Shutdown()
@@ -159,3 +167,20 @@ func TestBadger(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCorruptedBBolt(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "testing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir) // clean up
+
+	err = ioutil.WriteFile(filepath.Join(testDir, "db.bbolt"), bytes.Repeat([]byte("damaged "), 1024), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewBBolt("test", testDir)
+	if !errors.Is(err, storage.ErrCorrupted) {
+		t.Fatalf("expected ErrCorrupted, got %v", err)
+	}
+}
@@ -4,5 +4,8 @@
 
 // Errors for storages
 var (
-	ErrNotFound = errors.New("storage entry could not be found")
+	ErrNotFound       = errors.New("storage entry could not be found")
+	ErrDatabaseExists = errors.New("storage location already holds a database")
+	// ErrCorrupted reports that the data of a storage is damaged. Storages wrap it in the errors returned when they detect damage while starting or checking their data. Other errors, eg. a busy or unreadable storage, are not treated as damage.
+	ErrCorrupted = errors.New("storage is corrupted")
 )
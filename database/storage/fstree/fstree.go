@@ -1,10 +1,17 @@
 /*
 Package fstree provides a dead simple file-based database storage backend.
 It is primarily meant for easy testing or storing big files that can easily be accesses directly, without datastore.
+
+The "fstree" storage stores records in the record format, including their metadata.
+The "fstree-raw" storage stores plain files without any record header, so that files can be read and written by other tools.
+Plain files are loaded as wrapped records with the file modification time as creation and modification time. Files holding a JSON object are loaded in the JSON format, so that they can be queried, all other files are loaded as bytes.
+As plain files cannot hold metadata, records are stored without it and deleting a record removes its file. Records that are secret, crown jewels or expire are rejected with ErrMetaNotSupported, as their metadata would be lost.
 */
 package fstree
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -28,18 +35,32 @@
 	onWindows       = runtime.GOOS == "windows"
 )
 
+// ErrMetaNotSupported is returned by the "fstree-raw" storage for records with metadata that plain files cannot hold.
+var ErrMetaNotSupported = errors.New("fstree: raw storage cannot hold secret, crown jewel or expiring records")
+
 // FSTree database storage.
 type FSTree struct {
 	name     string
 	basePath string
+	raw      bool
 }
 
 func init() {
 	_ = storage.Register("fstree", NewFSTree)
+	_ = storage.Register("fstree-raw", NewRawFSTree)
 }
 
 // NewFSTree returns a (new) FSTree database.
 func NewFSTree(name, location string) (storage.Interface, error) {
+	return newFSTree(name, location, false)
+}
+
+// NewRawFSTree returns a (new) FSTree database that stores plain files without the record header.
+func NewRawFSTree(name, location string) (storage.Interface, error) {
+	return newFSTree(name, location, true)
+}
+
+func newFSTree(name, location string, raw bool) (*FSTree, error) {
 	basePath, err := filepath.Abs(location)
 	if err != nil {
 		return nil, fmt.Errorf("fstree: failed to validate path %s: %s", location, err)
@@ -64,6 +85,7 @@ func NewFSTree(name, location string) (storage.Interface, error) {
 	return &FSTree{
 		name:     name,
 		basePath: basePath,
+		raw:      raw,
 	}, nil
 }
 
@@ -88,19 +110,83 @@ func (fst *FSTree) Get(key string) (record.Record, error) {
 		return nil, err
 	}
 
-	data, err := ioutil.ReadFile(dstPath)
+	r, err := fst.loadFile(key, dstPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, storage.ErrNotFound
 		}
-		return nil, fmt.Errorf("fstree: failed to read file %s: %s", dstPath, err)
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadFile loads the record with the given key from the file at path.
+func (fst *FSTree) loadFile(key, path string) (record.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("fstree: failed to open file %s: %s", path, err)
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("fstree: failed to read file %s: %s", path, err)
+	}
+
+	if !fst.raw {
+		r, err := record.NewRawWrapper(fst.name, key, data)
+		if err != nil {
+			return nil, fmt.Errorf("fstree: failed to load file %s: %s", path, err)
+		}
+		return r, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("fstree: failed to stat file %s: %s", path, err)
+	}
+	return wrapPlainFile(fst.name, key, data, info.ModTime())
+}
+
+// wrapPlainFile returns a record wrapper for a plain file, with metadata synthesised from the modification time.
+func wrapPlainFile(dbName, key string, data []byte, modTime time.Time) (*record.Wrapper, error) {
+	meta := &record.Meta{
+		Created:  modTime.Unix(),
+		Modified: modTime.Unix(),
+	}
+
+	format := uint8(record.BYTES)
+	if isJSONObject(data) {
+		format = record.JSON
 	}
 
-	r, err := record.NewRawWrapper(fst.name, key, data)
+	return record.NewWrapper(fmt.Sprintf("%s:%s", dbName, key), meta, format, data)
+}
+
+func isJSONObject(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed)
+}
+
+// marshalPlain returns the data of the record without the record header. Text and bytes are stored as is, everything else as JSON.
+func marshalPlain(r record.Record) ([]byte, error) {
+	format := uint8(record.JSON)
+	if w, ok := r.(*record.Wrapper); ok && (w.Format == record.STRING || w.Format == record.BYTES) {
+		format = w.Format
+	}
+
+	data, err := r.Marshal(r, format)
 	if err != nil {
 		return nil, err
 	}
-	return r, nil
+	// strip format identifier
+	if len(data) > 0 {
+		data = data[1:]
+	}
+	return data, nil
 }
 
 // Put stores a record in the database.
@@ -110,7 +196,24 @@ func (fst *FSTree) Put(r record.Record) error {
 		return err
 	}
 
-	data, err := r.MarshalRecord(r)
+	var data []byte
+	if fst.raw {
+		// plain files cannot hold the deletion mark
+		if r.Meta().IsDeleted() {
+			err = os.Remove(dstPath)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("fstree: could not delete %s: %s", dstPath, err)
+			}
+			return nil
+		}
+		// do not silently drop permissions or expiry
+		if r.Meta().IsSecret() || r.Meta().IsCrownJewel() || r.Meta().GetAbsoluteExpiry() > 0 || r.Meta().GetRelativeExpiry() > 0 {
+			return ErrMetaNotSupported
+		}
+		data, err = marshalPlain(r)
+	} else {
+		data, err = r.MarshalRecord(r)
+	}
 	if err != nil {
 		return err
 	}
@@ -154,22 +257,10 @@ func (fst *FSTree) Query(q *query.Query, local, internal bool) (*iterator.Iterat
 		return nil, fmt.Errorf("invalid query: %s", err)
 	}
 
-	walkPrefix, err := fst.buildFilePath(q.DatabaseKeyPrefix(), false)
+	walkRoot, err := fst.buildWalkRoot(q.DatabaseKeyPrefix())
 	if err != nil {
 		return nil, err
 	}
-	fileInfo, err := os.Stat(walkPrefix)
-	var walkRoot string
-	switch {
-	case err == nil && fileInfo.IsDir():
-		walkRoot = walkPrefix
-	case err == nil:
-		walkRoot = filepath.Dir(walkPrefix)
-	case os.IsNotExist(err):
-		walkRoot = filepath.Dir(walkPrefix)
-	default: // err != nil
-		return nil, fmt.Errorf("fstree: could not stat query root %s: %s", walkPrefix, err)
-	}
 
 	queryIter := iterator.New()
 
@@ -177,62 +268,50 @@ func (fst *FSTree) Query(q *query.Query, local, internal bool) (*iterator.Iterat
 	return queryIter, nil
 }
 
-func (fst *FSTree) queryExecutor(walkRoot string, queryIter *iterator.Iterator, q *query.Query, local, internal bool) {
-	err := filepath.Walk(walkRoot, func(path string, info os.FileInfo, err error) error {
-
-		// check for error
-		if err != nil {
-			return fmt.Errorf("fstree: error in walking fs: %s", err)
-		}
-
-		if info.IsDir() {
-			// skip dir if not in scope
-			if !strings.HasPrefix(path, fst.basePath) {
-				return filepath.SkipDir
-			}
-			// continue
-			return nil
-		}
-
-		// still in scope?
-		if !strings.HasPrefix(path, fst.basePath) {
-			return nil
-		}
+// buildWalkRoot returns the deepest directory that holds all keys with the given prefix.
+func (fst *FSTree) buildWalkRoot(keyPrefix string) (string, error) {
+	walkRoot, err := fst.buildFilePath(keyPrefix, false)
+	if err != nil {
+		return "", err
+	}
+	// a prefix like "a/b" also matches "a/bc", so start in the parent directory
+	if keyPrefix != "" && !strings.HasSuffix(keyPrefix, "/") {
+		walkRoot = filepath.Dir(walkRoot)
+	}
+	return walkRoot, nil
+}
 
-		// read file
-		data, err := ioutil.ReadFile(path)
+func (fst *FSTree) queryExecutor(walkRoot string, queryIter *iterator.Iterator, q *query.Query, local, internal bool) {
+	err := fst.walk(walkRoot, q.DatabaseKeyPrefix(), func(key, path string, info os.FileInfo) error {
+		r, err := fst.loadFile(key, path)
 		if err != nil {
 			if os.IsNotExist(err) {
+				// deleted in the meantime
 				return nil
 			}
-			return fmt.Errorf("fstree: failed to read file %s: %s", path, err)
-		}
-
-		// parse
-		key, err := filepath.Rel(fst.basePath, path)
-		if err != nil {
-			return fmt.Errorf("fstree: failed to extract key from filepath %s: %s", path, err)
-		}
-		r, err := record.NewRawWrapper(fst.name, key, data)
-		if err != nil {
-			return fmt.Errorf("fstree: failed to load file %s: %s", path, err)
+			return err
 		}
+		queryIter.CountScanned()
 
 		if !r.Meta().CheckValidity() {
 			// record is not valid
+			queryIter.CountSkippedValidity()
 			return nil
 		}
 
 		if !r.Meta().CheckPermission(local, internal) {
 			// no permission to access
+			queryIter.CountSkippedPermission()
 			return nil
 		}
 
 		// check if matches, then send
 		if q.MatchesRecord(r) {
+			queryIter.CountMatched()
 			select {
 			case queryIter.Next <- r:
 			case <-queryIter.Done:
+				return errWalkAborted
 			case <-time.After(1 * time.Second):
 				return errors.New("fstree: query buffer full, timeout")
 			}
@@ -240,10 +319,77 @@ func (fst *FSTree) queryExecutor(walkRoot string, queryIter *iterator.Iterator,
 
 		return nil
 	})
+	if err == errWalkAborted {
+		err = nil
+	}
 
 	queryIter.Finish(err)
 }
 
+// CountKeys returns the number of keys with the given prefix.
+func (fst *FSTree) CountKeys(prefix string) (n int, err error) {
+	walkRoot, err := fst.buildWalkRoot(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	err = fst.walk(walkRoot, prefix, func(key, path string, info os.FileInfo) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+var errWalkAborted = errors.New("walk aborted")
+
+// walk calls fn for every file below dir with a key that has the given prefix, in lexical order. Directories that cannot hold keys with the prefix are not entered, symlinked directories are not followed.
+func (fst *FSTree) walk(dir, prefix string, fn func(key, path string, info os.FileInfo) error) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("fstree: error in walking fs: %s", err)
+	}
+
+	for _, info := range entries {
+		path := filepath.Join(dir, info.Name())
+		key, err := fst.keyFromPath(path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			keyDir := key + "/"
+			if strings.HasPrefix(keyDir, prefix) || strings.HasPrefix(prefix, keyDir) {
+				err = fst.walk(path, prefix, fn)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(key, prefix) {
+			err = fn(key, path, info)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// keyFromPath returns the database key of the file at path.
+func (fst *FSTree) keyFromPath(path string) (string, error) {
+	key, err := filepath.Rel(fst.basePath, path)
+	if err != nil {
+		return "", fmt.Errorf("fstree: failed to extract key from filepath %s: %s", path, err)
+	}
+	return filepath.ToSlash(key), nil
+}
+
 // ReadOnly returns whether the database is read only.
 func (fst *FSTree) ReadOnly() bool {
 	return false
@@ -0,0 +1,212 @@
+package fstree
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+)
+
+type TestRecord struct {
+	record.Base
+	sync.Mutex
+	S string
+	I int
+}
+
+func writeTestFile(t *testing.T, basePath, key, data string) {
+	path := filepath.Join(basePath, filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(path), defaultDirMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, []byte(data), defaultFileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func queryKeys(t *testing.T, db storage.Interface, q *query.Query) []string {
+	it, err := db.Query(q.MustBeValid(), true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for r := range it.Next {
+		keys = append(keys, r.DatabaseKey())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFSTreeQuery(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "testing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir) // clean up
+
+	db, err := NewFSTree("test", testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a/b/c", "a/bc/d", "a/x", "ab", "b/c"} {
+		r := &TestRecord{S: key}
+		r.SetKey("test:" + key)
+		r.SetMeta(&record.Meta{})
+		r.Meta().Update()
+		err = db.Put(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := queryKeys(t, db, query.New("test:a/b"))
+	if len(keys) != 2 || keys[0] != "a/b/c" || keys[1] != "a/bc/d" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+	keys = queryKeys(t, db, query.New("test:a"))
+	if len(keys) != 4 {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+	keys = queryKeys(t, db, query.New("test:").Where(query.Where("S", query.SameAs, "b/c")))
+	if len(keys) != 1 || keys[0] != "b/c" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	n, err := db.(storage.KeyCounter).CountKeys("a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 keys, got %d", n)
+	}
+}
+
+func TestRawFSTree(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "testing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir) // clean up
+
+	db, err := NewRawFSTree("test", testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files dropped in by other tools
+	writeTestFile(t, testDir, "config/a.json", ` {"S": "banana", "I": 42}`)
+	writeTestFile(t, testDir, "config/b.json", `{"S": "apple", "I": 1}`)
+	writeTestFile(t, testDir, "notes.txt", "plain text")
+	modTime := time.Now().Add(-time.Hour).Round(time.Second)
+	err = os.Chtimes(filepath.Join(testDir, "notes.txt"), modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// get
+	r, err := db.Get("notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := r.(*record.Wrapper)
+	if w.Format != record.BYTES || string(w.Data) != "plain text" {
+		t.Errorf("unexpected wrapper: %d %q", w.Format, w.Data)
+	}
+	if r.Meta().Modified != modTime.Unix() || r.Meta().Created != modTime.Unix() {
+		t.Errorf("meta should be synthesised from modification time, got %+v", r.Meta())
+	}
+	if r.Key() != "test:notes.txt" {
+		t.Errorf("unexpected key %s", r.Key())
+	}
+
+	// query on file contents
+	keys := queryKeys(t, db, query.New("test:config/").Where(query.Where("I", query.GreaterThan, 10)))
+	if len(keys) != 1 || keys[0] != "config/a.json" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	// put struct record as JSON
+	a := &TestRecord{S: "cherry", I: 3}
+	a.SetKey("test:config/c.json")
+	a.SetMeta(&record.Meta{})
+	a.Meta().Update()
+	err = db.Put(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = db.Get("config/c.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1 := &TestRecord{}
+	err = record.Unwrap(r, a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a1.S != "cherry" || a1.I != 3 {
+		t.Errorf("unexpected record: %+v", a1)
+	}
+
+	// put bytes as is
+	w, err = record.NewWrapper("test:data.bin", &record.Meta{}, record.BYTES, []byte{0, 1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Put(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(testDir, "data.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "\x00\x01\x02" {
+		t.Errorf("raw data should be stored without header, got %v", data)
+	}
+
+	// metadata that would be lost is rejected
+	for _, modify := range []func(m *record.Meta){
+		func(m *record.Meta) { m.MakeSecret() },
+		func(m *record.Meta) { m.MakeCrownJewel() },
+		func(m *record.Meta) { m.SetAbsoluteExpiry(time.Now().Add(time.Hour).Unix()) },
+		func(m *record.Meta) { m.SetRelativateExpiry(60) },
+	} {
+		rejected, err := record.NewWrapper("test:rejected.bin", &record.Meta{}, record.BYTES, []byte{0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		modify(rejected.Meta())
+		err = db.Put(rejected)
+		if err != ErrMetaNotSupported {
+			t.Errorf("expected ErrMetaNotSupported, got %v", err)
+		}
+	}
+	_, err = db.Get("rejected.bin")
+	if err != storage.ErrNotFound {
+		t.Errorf("rejected record should not be stored, got %v", err)
+	}
+
+	// deleting removes the file
+	w.Meta().Delete()
+	err = db.Put(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Get("data.bin")
+	if err != storage.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
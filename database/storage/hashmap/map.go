@@ -0,0 +1,246 @@
+package hashmap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tevino/abool"
+
+	"github.com/safing/portbase/database/iterator"
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+)
+
+// DefaultSnapshotInterval is the interval in which the hashmap-snapshot storage writes snapshots to disk.
+const DefaultSnapshotInterval = 5 * time.Minute
+
+// HashMap storage.
+type HashMap struct {
+	name string
+
+	dbLock  sync.RWMutex
+	records map[string][]byte
+	keys    []string // sorted, for prefix queries
+
+	// snapshots
+	location         string
+	snapshotInterval time.Duration
+	dirty            *abool.AtomicBool
+	snapshotLock     sync.Mutex
+	shutdownSignal   chan struct{}
+	snapshotterDone  chan struct{}
+}
+
+func init() {
+	_ = storage.Register("hashmap", NewHashMap)
+	_ = storage.Register("memory", NewHashMap)
+	_ = storage.Register("hashmap-snapshot", func(name, location string) (storage.Interface, error) {
+		return NewHashMapWithSnapshots(name, location, DefaultSnapshotInterval)
+	})
+}
+
+// NewHashMap creates a hashmap database, which only lives in memory. It is registered as the "hashmap" and "memory" storage types.
+func NewHashMap(name, location string) (storage.Interface, error) {
+	return newHashMap(name), nil
+}
+
+// NewHashMapWithSnapshots creates a hashmap database, which is loaded from a snapshot in location on start, and writes snapshots to location in the given interval and on shutdown.
+func NewHashMapWithSnapshots(name, location string, interval time.Duration) (storage.Interface, error) {
+	if location == "" {
+		return nil, errors.New("hashmap: snapshots require a location")
+	}
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+
+	hm := newHashMap(name)
+	hm.location = location
+	hm.snapshotInterval = interval
+
+	err := hm.loadSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("hashmap: failed to load snapshot: %w", err)
+	}
+
+	hm.shutdownSignal = make(chan struct{})
+	hm.snapshotterDone = make(chan struct{})
+	go hm.snapshotter()
+
+	return hm, nil
+}
+
+func newHashMap(name string) *HashMap {
+	return &HashMap{
+		name:    name,
+		records: make(map[string][]byte),
+		dirty:   abool.New(),
+	}
+}
+
+// Get returns a database record.
+func (hm *HashMap) Get(key string) (record.Record, error) {
+	hm.dbLock.RLock()
+	data, ok := hm.records[key]
+	hm.dbLock.RUnlock()
+
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return record.NewRawWrapper(hm.name, key, data)
+}
+
+// Put stores a record in the database.
+func (hm *HashMap) Put(r record.Record) error {
+	data, err := r.MarshalRecord(r)
+	if err != nil {
+		return err
+	}
+	key := r.DatabaseKey()
+
+	hm.dbLock.Lock()
+	defer hm.dbLock.Unlock()
+
+	if _, ok := hm.records[key]; !ok {
+		// insert key into sorted key list
+		i := sort.SearchStrings(hm.keys, key)
+		hm.keys = append(hm.keys, "")
+		copy(hm.keys[i+1:], hm.keys[i:])
+		hm.keys[i] = key
+	}
+	hm.records[key] = data
+	hm.dirty.Set()
+	return nil
+}
+
+// Delete deletes a record from the database.
+func (hm *HashMap) Delete(key string) error {
+	hm.dbLock.Lock()
+	defer hm.dbLock.Unlock()
+
+	if _, ok := hm.records[key]; !ok {
+		return nil
+	}
+	delete(hm.records, key)
+	i := sort.SearchStrings(hm.keys, key)
+	hm.keys = append(hm.keys[:i], hm.keys[i+1:]...)
+	hm.dirty.Set()
+	return nil
+}
+
+// prefixRange returns the range of hm.keys that have the given prefix. The caller must hold dbLock.
+func (hm *HashMap) prefixRange(prefix string) (start, end int) {
+	start = sort.SearchStrings(hm.keys, prefix)
+	end = start
+	for end < len(hm.keys) && strings.HasPrefix(hm.keys[end], prefix) {
+		end++
+	}
+	return start, end
+}
+
+// Query returns a an iterator for the supplied query.
+func (hm *HashMap) Query(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
+	_, err := q.Check()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+
+	// collect matching keys, so that the lock is not held while the iterator is consumed
+	hm.dbLock.RLock()
+	start, end := hm.prefixRange(q.DatabaseKeyPrefix())
+	keys := make([]string, end-start)
+	copy(keys, hm.keys[start:end])
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = hm.records[key]
+	}
+	hm.dbLock.RUnlock()
+
+	queryIter := iterator.New()
+
+	go hm.queryExecutor(queryIter, q, keys, values, local, internal)
+	return queryIter, nil
+}
+
+func (hm *HashMap) queryExecutor(queryIter *iterator.Iterator, q *query.Query, keys []string, values [][]byte, local, internal bool) {
+	for i, key := range keys {
+		r, err := record.NewRawWrapper(hm.name, key, values[i])
+		if err != nil {
+			queryIter.Finish(err)
+			return
+		}
+		queryIter.CountScanned()
+
+		// check validity / access
+		if !r.Meta().CheckValidity() {
+			queryIter.CountSkippedValidity()
+			continue
+		}
+		if !r.Meta().CheckPermission(local, internal) {
+			queryIter.CountSkippedPermission()
+			continue
+		}
+
+		// check if matches & send
+		if q.MatchesRecord(r) {
+			queryIter.CountMatched()
+			select {
+			case <-queryIter.Done:
+				queryIter.Finish(nil)
+				return
+			case queryIter.Next <- r:
+			case <-time.After(1 * time.Second):
+				queryIter.Finish(errors.New("query timeout"))
+				return
+			}
+		}
+	}
+	queryIter.Finish(nil)
+}
+
+// CountKeys returns the number of keys with the given prefix.
+func (hm *HashMap) CountKeys(prefix string) (int, error) {
+	hm.dbLock.RLock()
+	defer hm.dbLock.RUnlock()
+
+	start, end := hm.prefixRange(prefix)
+	return end - start, nil
+}
+
+// ReadOnly returns whether the database is read only.
+func (hm *HashMap) ReadOnly() bool {
+	return false
+}
+
+// Injected returns whether the database is injected.
+func (hm *HashMap) Injected() bool {
+	return false
+}
+
+// Maintain runs a light maintenance operation on the database.
+func (hm *HashMap) Maintain() error {
+	return nil
+}
+
+// MaintainThorough runs a thorough maintenance operation on the database.
+func (hm *HashMap) MaintainThorough() error {
+	if hm.location != "" {
+		return hm.Snapshot()
+	}
+	return nil
+}
+
+// Shutdown shuts down the database. If snapshots are enabled, a final snapshot is written.
+func (hm *HashMap) Shutdown() error {
+	if hm.shutdownSignal == nil {
+		return nil
+	}
+
+	close(hm.shutdownSignal)
+	<-hm.snapshotterDone
+	return hm.Snapshot()
+}
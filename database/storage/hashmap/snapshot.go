@@ -0,0 +1,184 @@
+package hashmap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/google/renameio"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/database/storage"
+	"github.com/safing/portbase/log"
+)
+
+// Snapshots start with the snapshot version, followed by pairs of blocks holding the key and the raw record.
+
+const (
+	snapshotFileName = "snapshot.db"
+	snapshotVersion  = 1
+
+	// maxSnapshotBlockSize is the maximum size of a single record in a snapshot.
+	maxSnapshotBlockSize = 1 << 26 // 64MB
+)
+
+func (hm *HashMap) snapshotPath() string {
+	return filepath.Join(hm.location, snapshotFileName)
+}
+
+func (hm *HashMap) snapshotter() {
+	defer close(hm.snapshotterDone)
+
+	ticker := time.NewTicker(hm.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hm.shutdownSignal:
+			return
+		case <-ticker.C:
+			err := hm.Snapshot()
+			if err != nil {
+				log.Warningf("hashmap: failed to write snapshot of database %s: %s", hm.name, err)
+			}
+		}
+	}
+}
+
+// Snapshot writes all records to disk, if snapshots are enabled and there were changes since the last snapshot.
+func (hm *HashMap) Snapshot() error {
+	if hm.location == "" {
+		return errors.New("hashmap: snapshots are not enabled")
+	}
+
+	hm.snapshotLock.Lock()
+	defer hm.snapshotLock.Unlock()
+
+	if !hm.dirty.SetToIf(true, false) {
+		return nil
+	}
+
+	// copy references to the current state, records are never modified in place
+	hm.dbLock.RLock()
+	keys := make([]string, len(hm.keys))
+	copy(keys, hm.keys)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = hm.records[key]
+	}
+	hm.dbLock.RUnlock()
+
+	err := hm.writeSnapshot(keys, values)
+	if err != nil {
+		// try again next time
+		hm.dirty.Set()
+		return err
+	}
+	return nil
+}
+
+func (hm *HashMap) writeSnapshot(keys []string, values [][]byte) error {
+	t, err := renameio.TempFile("", hm.snapshotPath())
+	if err != nil {
+		return err
+	}
+	defer t.Cleanup() //nolint:errcheck
+
+	// set permissions before writing data, as records may be sensitive
+	if runtime.GOOS != "windows" {
+		err = t.Chmod(0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(t)
+	_, err = w.Write([]byte{snapshotVersion})
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		err = container.WriteBlock(w, []byte(key))
+		if err != nil {
+			return err
+		}
+		err = container.WriteBlock(w, values[i])
+		if err != nil {
+			return err
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	return t.CloseAtomicallyReplace()
+}
+
+func (hm *HashMap) loadSnapshot() error {
+	err := hm.readSnapshot()
+	if err != nil {
+		if _, ok := err.(*os.PathError); ok {
+			return err
+		}
+		// the file could be read, but its content is damaged
+		return fmt.Errorf("%w: %s", storage.ErrCorrupted, err)
+	}
+	return nil
+}
+
+func (hm *HashMap) readSnapshot() error {
+	f, err := os.Open(hm.snapshotPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	version, err := r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	decoder := container.NewBlockDecoder(r, maxSnapshotBlockSize)
+	for {
+		key, err := decoder.NextBlock()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		value, err := decoder.NextBlock()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		if _, ok := hm.records[string(key)]; !ok {
+			hm.keys = append(hm.keys, string(key))
+		}
+		hm.records[string(key)] = value
+	}
+
+	// snapshots are written in key order, but do not rely on it
+	sort.Strings(hm.keys)
+	return nil
+}
@@ -19,3 +19,13 @@ type Interface interface {
 	MaintainThorough() error
 	Shutdown() error
 }
+
+// Checker is an optional interface for storages that can verify the integrity of their data. Check returns an error wrapping ErrCorrupted if the storage is damaged, other errors report that the check itself failed.
+type Checker interface {
+	Check() error
+}
+
+// KeyCounter is an optional interface for storages that can count the keys with a given prefix without loading the records.
+type KeyCounter interface {
+	CountKeys(prefix string) (int, error)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"github.com/safing/portbase/database/record"
+)
+
+// RangeReader is an optional interface for storages that can read records in key order, starting at any key.
+type RangeReader interface {
+	// ReadRange returns the next records of the key range that are valid and accessible with the given permissions. More reports whether there are further records in the range.
+	ReadRange(kr *KeyRange, local, internal bool) (records []record.Record, more bool, err error)
+}
+
+// KeyRange selects a page of records by database key.
+type KeyRange struct {
+	// Start is the first key of the range, End is the first key after the range. Empty values do not limit the range.
+	Start string
+	End   string
+	// Reverse reads the range from the end to the start.
+	Reverse bool
+	// After continues reading after the given key, in reading direction. Empty to read from the beginning.
+	After string
+	// Limit is the maximum number of records to return.
+	Limit int
+}
+
+// Contains returns whether the key is in the range and was not read yet.
+func (kr *KeyRange) Contains(key string) bool {
+	switch {
+	case kr.Start != "" && key < kr.Start:
+		return false
+	case kr.End != "" && key >= kr.End:
+		return false
+	case kr.After != "" && !kr.Reverse && key <= kr.After:
+		return false
+	case kr.After != "" && kr.Reverse && key >= kr.After:
+		return false
+	}
+	return true
+}
+
+// Passed returns whether the key lies beyond the range in reading direction, ie. whether reading can stop.
+func (kr *KeyRange) Passed(key string) bool {
+	if kr.Reverse {
+		return kr.Start != "" && key < kr.Start
+	}
+	return kr.End != "" && key >= kr.End
+}
+
+// SeekKey returns the key to start reading at: the first key of the range or the key to continue after, whichever comes later in reading direction. It is empty if reading starts at the beginning, in reverse reading at the last key.
+func (kr *KeyRange) SeekKey() string {
+	if kr.Reverse {
+		switch {
+		case kr.After == "":
+			return kr.End
+		case kr.End == "" || kr.After < kr.End:
+			return kr.After
+		default:
+			return kr.End
+		}
+	}
+
+	if kr.After > kr.Start {
+		return kr.After
+	}
+	return kr.Start
+}
@@ -0,0 +1,309 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3" // also registers the sqlite3 driver
+
+	"github.com/safing/portbase/database/iterator"
+	"github.com/safing/portbase/database/query"
+	"github.com/safing/portbase/database/record"
+	"github.com/safing/portbase/database/storage"
+)
+
+// Records are stored with their metadata in separate columns, so that they can be inspected and filtered with standard tools. The payload column holds the dsd formatted data, which may be compressed or signed.
+const schema = `
+CREATE TABLE IF NOT EXISTS records (
+	key        TEXT PRIMARY KEY NOT NULL,
+	created    INTEGER NOT NULL DEFAULT 0,
+	modified   INTEGER NOT NULL DEFAULT 0,
+	expires    INTEGER NOT NULL DEFAULT 0,
+	deleted    INTEGER NOT NULL DEFAULT 0,
+	secret     INTEGER NOT NULL DEFAULT 0,
+	crownjewel INTEGER NOT NULL DEFAULT 0,
+	version    INTEGER NOT NULL,
+	meta       BLOB NOT NULL,
+	payload    BLOB
+);`
+
+// SQLite database made pluggable for portbase.
+type SQLite struct {
+	name string
+	db   *sql.DB
+}
+
+func init() {
+	_ = storage.Register("sqlite", NewSQLite)
+}
+
+// NewSQLite opens/creates a sqlite database.
+func NewSQLite(name, location string) (storage.Interface, error) {
+	dsn := fmt.Sprintf(
+		"file:%s?_journal_mode=WAL&_busy_timeout=5000",
+		filepath.Join(location, "db.sqlite"),
+	)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// incremental vacuum must be enabled before any table is created
+	_, err = db.Exec("PRAGMA auto_vacuum = INCREMENTAL;")
+	if err == nil {
+		_, err = db.Exec(schema)
+	}
+	if err != nil {
+		_ = db.Close()
+		if isCorruption(err) {
+			return nil, fmt.Errorf("%w: failed to set up sqlite database: %s", storage.ErrCorrupted, err)
+		}
+		return nil, fmt.Errorf("failed to set up sqlite database: %s", err)
+	}
+
+	return &SQLite{
+		name: name,
+		db:   db,
+	}, nil
+}
+
+// isCorruption returns whether the error reports a damaged database file.
+func isCorruption(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB)
+}
+
+// Get returns a database record.
+func (s *SQLite) Get(key string) (record.Record, error) {
+	var version uint8
+	var meta, payload []byte
+	err := s.db.QueryRow(
+		"SELECT version, meta, payload FROM records WHERE key = ?;",
+		key,
+	).Scan(&version, &meta, &payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return record.NewRawWrapper(s.name, key, record.JoinRawRecord(version, meta, payload))
+}
+
+// Put stores a record in the database.
+func (s *SQLite) Put(r record.Record) error {
+	data, err := r.MarshalRecord(r)
+	if err != nil {
+		return err
+	}
+	version, metaSection, dataSection, err := record.SplitRawRecord(data)
+	if err != nil {
+		return err
+	}
+
+	m := r.Meta()
+	_, err = s.db.Exec(
+		`INSERT OR REPLACE INTO records
+		(key, created, modified, expires, deleted, secret, crownjewel, version, meta, payload)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		r.DatabaseKey(),
+		m.Created,
+		m.Modified,
+		m.Expires,
+		m.Deleted,
+		m.IsSecret(),
+		m.IsCrownJewel(),
+		version,
+		metaSection,
+		dataSection,
+	)
+	return err
+}
+
+// Delete deletes a record from the database.
+func (s *SQLite) Delete(key string) error {
+	_, err := s.db.Exec("DELETE FROM records WHERE key = ?;", key)
+	return err
+}
+
+// Query returns a an iterator for the supplied query.
+func (s *SQLite) Query(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
+	_, err := q.Check()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+
+	// push prefix and metadata filters into SQL, the where condition is checked on the records
+	stmt := `SELECT key, version, meta, payload FROM records
+		WHERE deleted = 0 AND (expires = 0 OR expires >= ?)`
+	args := []interface{}{time.Now().Unix()}
+	prefixCondition, prefixArgs := prefixFilter(q.DatabaseKeyPrefix())
+	stmt += prefixCondition
+	args = append(args, prefixArgs...)
+	if !local {
+		stmt += " AND crownjewel = 0"
+	}
+	if !internal {
+		stmt += " AND secret = 0"
+	}
+	stmt += " ORDER BY key;"
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	queryIter := iterator.New()
+
+	go s.queryExecutor(queryIter, rows, q, local, internal)
+	return queryIter, nil
+}
+
+func (s *SQLite) queryExecutor(queryIter *iterator.Iterator, rows *sql.Rows, q *query.Query, local, internal bool) {
+	err := s.iterateRows(queryIter, rows, q, local, internal)
+	closeErr := rows.Close()
+	if err == nil {
+		err = closeErr
+	}
+	queryIter.Finish(err)
+}
+
+func (s *SQLite) iterateRows(queryIter *iterator.Iterator, rows *sql.Rows, q *query.Query, local, internal bool) error {
+	for rows.Next() {
+		var key string
+		var version uint8
+		var meta, payload []byte
+		err := rows.Scan(&key, &version, &meta, &payload)
+		if err != nil {
+			return err
+		}
+
+		r, err := record.NewRawWrapper(s.name, key, record.JoinRawRecord(version, meta, payload))
+		if err != nil {
+			return err
+		}
+		queryIter.CountScanned()
+
+		// check validity / access again, as the meta columns might have been modified externally
+		if !r.Meta().CheckValidity() {
+			queryIter.CountSkippedValidity()
+			continue
+		}
+		if !r.Meta().CheckPermission(local, internal) {
+			queryIter.CountSkippedPermission()
+			continue
+		}
+
+		// check if matches & send
+		if q.MatchesRecord(r) {
+			queryIter.CountMatched()
+			select {
+			case <-queryIter.Done:
+				return nil
+			case queryIter.Next <- r:
+			case <-time.After(1 * time.Second):
+				return errors.New("query timeout")
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// CountKeys returns the number of keys with the given prefix.
+func (s *SQLite) CountKeys(prefix string) (n int, err error) {
+	prefixCondition, prefixArgs := prefixFilter(prefix)
+	err = s.db.QueryRow(
+		"SELECT count(*) FROM records WHERE 1"+prefixCondition+";",
+		prefixArgs...,
+	).Scan(&n)
+	return n, err
+}
+
+// prefixFilter returns an SQL condition, and its arguments, that matches all keys with the given prefix. It uses a key range, so that the primary key index is used.
+func prefixFilter(prefix string) (condition string, args []interface{}) {
+	if prefix == "" {
+		return "", nil
+	}
+
+	// the upper bound is the prefix with its last byte incremented
+	upper := []byte(prefix)
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xFF {
+			upper[i]++
+			return " AND key >= ? AND key < ?", []interface{}{prefix, string(upper[:i+1])}
+		}
+	}
+	// prefix consists of 0xFF bytes only
+	return " AND key >= ?", []interface{}{prefix}
+}
+
+// Check runs the sqlite integrity check.
+func (s *SQLite) Check() error {
+	rows, err := s.db.Query("PRAGMA quick_check;")
+	if err != nil {
+		return checkError(err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		err = rows.Scan(&result)
+		if err != nil {
+			return checkError(err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return checkError(err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", storage.ErrCorrupted, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkError wraps errors that report a damaged database file in storage.ErrCorrupted.
+func checkError(err error) error {
+	if isCorruption(err) {
+		return fmt.Errorf("%w: %s", storage.ErrCorrupted, err)
+	}
+	return err
+}
+
+// ReadOnly returns whether the database is read only.
+func (s *SQLite) ReadOnly() bool {
+	return false
+}
+
+// Injected returns whether the database is injected.
+func (s *SQLite) Injected() bool {
+	return false
+}
+
+// Maintain runs a light maintenance operation on the database: it returns free pages to the file system.
+func (s *SQLite) Maintain() error {
+	_, err := s.db.Exec("PRAGMA incremental_vacuum;")
+	return err
+}
+
+// MaintainThorough runs a thorough maintenance operation on the database: it rebuilds the database file.
+func (s *SQLite) MaintainThorough() error {
+	_, err := s.db.Exec("VACUUM;")
+	return err
+}
+
+// Shutdown shuts down the database.
+func (s *SQLite) Shutdown() error {
+	return s.db.Close()
+}
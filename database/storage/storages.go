@@ -3,6 +3,8 @@
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 )
 
@@ -28,12 +30,30 @@ func Register(name string, factory Factory) error {
 	return nil
 }
 
-// CreateDatabase starts a new database with the given name and storageType at location.
+// IsRegistered returns whether a factory for the given storage type is registered.
+func IsRegistered(storageType string) bool {
+	storagesLock.Lock()
+	defer storagesLock.Unlock()
+
+	_, ok := storages[storageType]
+	return ok
+}
+
+// CreateDatabase starts a new database with the given name and storageType at location. It fails with ErrDatabaseExists if location already holds data, an empty or missing directory counts as no data.
+// Apart from this check, it is the same as StartDatabase: storages initialize their data themselves when they are started at a location without data.
 func CreateDatabase(name, storageType, location string) (Interface, error) {
-	return nil, nil
+	empty, err := isEmptyDir(location)
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		return nil, ErrDatabaseExists
+	}
+
+	return StartDatabase(name, storageType, location)
 }
 
-// StartDatabase starts a new database with the given name and storageType at location.
+// StartDatabase starts a new database with the given name and storageType at location. Storages create missing data when they are started, so this also creates new databases.
 func StartDatabase(name, storageType, location string) (Interface, error) {
 	storagesLock.Lock()
 	defer storagesLock.Unlock()
@@ -45,3 +65,21 @@ func StartDatabase(name, storageType, location string) (Interface, error) {
 
 	return factory(name, location)
 }
+
+// isEmptyDir returns whether the given directory is empty or does not exist.
+func isEmptyDir(location string) (bool, error) {
+	dir, err := os.Open(location)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer dir.Close()
+
+	_, err = dir.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
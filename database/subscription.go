@@ -1,21 +1,38 @@
 package database
 
 import (
+	"github.com/tevino/abool"
+
 	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
 )
 
-// Subscription is a database subscription for updates.
+// Subscription is a database subscription for updates. Updates are dropped if the feed is full, which can be checked with Dropped.
 type Subscription struct {
 	q        *query.Query
 	local    bool
 	internal bool
 	canceled bool
+	dropped  *abool.AtomicBool
 
 	Feed chan record.Record
 	Err  error
 }
 
+// push sends an update to the feed, or marks it as dropped if the feed is full. The caller must hold the readLock or the writeLock, so that the feed is not closed in the meantime.
+func (s *Subscription) push(r record.Record) {
+	select {
+	case s.Feed <- r:
+	default:
+		s.dropped.Set()
+	}
+}
+
+// Dropped returns whether updates were dropped since the last call, because the feed was full. Subscribers that must not miss any update should query the database again in that case.
+func (s *Subscription) Dropped() bool {
+	return s.dropped.SetToIf(true, false)
+}
+
 // Cancel cancels the subscription.
 func (s *Subscription) Cancel() error {
 	c, err := getController(s.q.DatabaseName())
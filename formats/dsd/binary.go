@@ -0,0 +1,529 @@
+package dsd
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/formats/varint"
+)
+
+// The Binary format serializes structs into a compact binary layout without the need for generated code.
+//
+// The layout starts with a layout version, followed by the fields of the struct. Every field is prefixed with a key, which holds the field tag and the wire type (tag<<3 | wire type). Fields with a zero value are omitted.
+// The field tag is taken from the `dsd` struct tag, eg. `dsd:"3"`, which every exported field must have, so that reordering fields cannot silently change the layout. Fields tagged with `dsd:"-"` and unexported fields are skipped.
+// Fields that are unknown to the decoding struct are skipped, so adding fields is backward compatible, as long as existing fields keep their tag and type. Renaming fields is always safe.
+//
+// Supported are bools, integers, floats, strings, byte slices, pointers, slices, arrays, maps and nested structs, as well as types implementing encoding.BinaryMarshaler and encoding.BinaryUnmarshaler. As data is not self-describing, it can only be loaded into the struct it was created from (or a compatible version of it) and cannot be converted into other formats.
+
+const binaryLayoutVersion = 1
+
+// wire types
+const (
+	wireVarint  = 0 // bools and unsigned integers
+	wireSigned  = 1 // signed integers, zig-zag encoded
+	wireFixed64 = 2 // float64
+	wireFixed32 = 3 // float32
+	wireBlock   = 4 // length prefixed: strings, bytes, slices, maps, structs
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	byteType              = reflect.TypeOf(byte(0))
+
+	binaryStructs sync.Map // map[reflect.Type]*binaryStruct
+)
+
+type binaryField struct {
+	tag   uint64
+	index int
+	wire  uint64
+}
+
+type binaryStruct struct {
+	fields []*binaryField // sorted by tag
+	byTag  map[uint64]*binaryField
+}
+
+func init() {
+	_ = RegisterFormat(Binary, binaryCodec{})
+}
+
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(t interface{}) ([]byte, error) {
+	v := reflect.ValueOf(t)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("dsd: binary format cannot pack nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dsd: binary format only supports structs, not %s", v.Type())
+	}
+
+	c := container.New(varint.Pack8(binaryLayoutVersion))
+	err := encodeBinaryStruct(c, v)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to pack binary data: %s", err)
+	}
+	return c.CompileData(), nil
+}
+
+func (binaryCodec) Unmarshal(data []byte, t interface{}) error {
+	v := reflect.ValueOf(t)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dsd: binary format can only be loaded into a pointer to a struct, not %T", t)
+	}
+
+	version, n, err := varint.Unpack8(data)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack binary data: %s", err)
+	}
+	if version != binaryLayoutVersion {
+		return fmt.Errorf("dsd: unsupported binary layout version %d", version)
+	}
+
+	err = decodeBinaryStruct(&binaryDecoder{data: data[n:]}, v.Elem())
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack binary data: %s", err)
+	}
+	return nil
+}
+
+// getBinaryStruct returns the (cached) binary layout of the given struct type.
+func getBinaryStruct(t reflect.Type) (*binaryStruct, error) {
+	if cached, ok := binaryStructs.Load(t); ok {
+		return cached.(*binaryStruct), nil
+	}
+
+	bs := &binaryStruct{
+		byTag: make(map[uint64]*binaryField),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		value, ok := sf.Tag.Lookup("dsd")
+		if !ok {
+			return nil, fmt.Errorf("%s.%s has no dsd tag", t, sf.Name)
+		}
+		value = strings.Split(value, ",")[0]
+		if value == "-" {
+			continue
+		}
+		tag, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || tag == 0 || tag > math.MaxUint64>>3 {
+			return nil, fmt.Errorf("invalid dsd tag %q on %s.%s", value, t, sf.Name)
+		}
+		if existing, ok := bs.byTag[tag]; ok {
+			return nil, fmt.Errorf("%s.%s and %s.%s use the same tag %d", t, t.Field(existing.index).Name, t, sf.Name, tag)
+		}
+
+		wire, err := binaryWireType(sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %s", t, sf.Name, err)
+		}
+
+		f := &binaryField{
+			tag:   tag,
+			index: i,
+			wire:  wire,
+		}
+		bs.fields = append(bs.fields, f)
+		bs.byTag[tag] = f
+	}
+	sort.Slice(bs.fields, func(i, j int) bool {
+		return bs.fields[i].tag < bs.fields[j].tag
+	})
+
+	binaryStructs.Store(t, bs)
+	return bs, nil
+}
+
+func implementsBinary(t reflect.Type) bool {
+	return t.Implements(binaryMarshalerType) && reflect.PtrTo(t).Implements(binaryUnmarshalerType)
+}
+
+func binaryWireType(t reflect.Type) (uint64, error) {
+	if implementsBinary(t) {
+		return wireBlock, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return wireVarint, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return wireSigned, nil
+	case reflect.Float64:
+		return wireFixed64, nil
+	case reflect.Float32:
+		return wireFixed32, nil
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return wireBlock, nil
+	case reflect.Ptr:
+		return binaryWireType(t.Elem())
+	default:
+		return 0, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func encodeBinaryStruct(c *container.Container, v reflect.Value) error {
+	bs, err := getBinaryStruct(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range bs.fields {
+		fv := v.Field(f.index)
+		if fv.IsZero() {
+			continue
+		}
+		c.AppendNumber(f.tag<<3 | f.wire)
+		err := encodeBinaryValue(c, fv)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeBinaryValue(c *container.Container, v reflect.Value) error {
+	if implementsBinary(v.Type()) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		c.AppendAsBlock(data)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			c.AppendNumber(1)
+		} else {
+			c.AppendNumber(0)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.AppendNumber(v.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.AppendInt(v.Int())
+	case reflect.Float64:
+		c.AppendFloat(v.Float())
+	case reflect.Float32:
+		c.Append(varint.PackFloat32(float32(v.Float())))
+	case reflect.String:
+		c.AppendString(v.String())
+	case reflect.Ptr:
+		if v.IsNil() {
+			// encode nil pointers within slices and maps as zero values
+			return encodeBinaryValue(c, reflect.Zero(v.Type().Elem()))
+		}
+		return encodeBinaryValue(c, v.Elem())
+	case reflect.Struct:
+		inner := container.New()
+		err := encodeBinaryStruct(inner, v)
+		if err != nil {
+			return err
+		}
+		c.AppendAsBlock(inner.CompileData())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem() == byteType {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			c.AppendAsBlock(data)
+			return nil
+		}
+		inner := container.New()
+		for i := 0; i < v.Len(); i++ {
+			err := encodeBinaryValue(inner, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		c.AppendAsBlock(inner.CompileData())
+	case reflect.Map:
+		// sort entries by their encoded key, so that the encoding is deterministic
+		type entry struct {
+			key   []byte
+			value []byte
+		}
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := container.New()
+			err := encodeBinaryValue(key, iter.Key())
+			if err != nil {
+				return err
+			}
+			value := container.New()
+			err = encodeBinaryValue(value, iter.Value())
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{key.CompileData(), value.CompileData()})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+		inner := container.New()
+		for _, e := range entries {
+			inner.Append(e.key)
+			inner.Append(e.value)
+		}
+		c.AppendAsBlock(inner.CompileData())
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+type binaryDecoder struct {
+	data []byte
+}
+
+func (d *binaryDecoder) next(unpack func([]byte) (uint64, int, error)) (uint64, error) {
+	n, r, err := unpack(d.data)
+	if err != nil {
+		return 0, err
+	}
+	d.data = d.data[r:]
+	return n, nil
+}
+
+func (d *binaryDecoder) varint() (uint64, error) {
+	return d.next(varint.Unpack64)
+}
+
+func (d *binaryDecoder) signed() (int64, error) {
+	n, r, err := varint.UnpackInt64(d.data)
+	if err != nil {
+		return 0, err
+	}
+	d.data = d.data[r:]
+	return n, nil
+}
+
+func (d *binaryDecoder) fixed64() (uint64, error) {
+	return d.next(varint.UnpackFixed64)
+}
+
+func (d *binaryDecoder) fixed32() (uint32, error) {
+	n, r, err := varint.UnpackFixed32(d.data)
+	if err != nil {
+		return 0, err
+	}
+	d.data = d.data[r:]
+	return n, nil
+}
+
+func (d *binaryDecoder) block() ([]byte, error) {
+	block, r, err := varint.GetNextBlock(d.data)
+	if err != nil {
+		return nil, err
+	}
+	d.data = d.data[r:]
+	return block, nil
+}
+
+func (d *binaryDecoder) skip(wire uint64) (err error) {
+	switch wire {
+	case wireVarint:
+		_, err = d.varint()
+	case wireSigned:
+		_, err = d.signed()
+	case wireFixed64:
+		_, err = d.fixed64()
+	case wireFixed32:
+		_, err = d.fixed32()
+	case wireBlock:
+		_, err = d.block()
+	default:
+		err = fmt.Errorf("unknown wire type %d", wire)
+	}
+	return err
+}
+
+func decodeBinaryStruct(d *binaryDecoder, v reflect.Value) error {
+	bs, err := getBinaryStruct(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for len(d.data) > 0 {
+		key, err := d.varint()
+		if err != nil {
+			return err
+		}
+		tag, wire := key>>3, key&7
+
+		f, ok := bs.byTag[tag]
+		if !ok {
+			// field unknown to this version of the struct
+			err = d.skip(wire)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.wire != wire {
+			return fmt.Errorf("wire type %d of field %s.%s does not match encoded wire type %d", f.wire, v.Type(), v.Type().Field(f.index).Name, wire)
+		}
+
+		err = decodeBinaryValue(d, v.Field(f.index))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeBinaryValue(d *binaryDecoder, v reflect.Value) error {
+	if implementsBinary(v.Type()) {
+		data, err := d.block()
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		n, err := d.varint()
+		if err != nil {
+			return err
+		}
+		v.SetBool(n != 0)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := d.varint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("value %d overflows %s", n, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := d.signed()
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := d.fixed64()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(n))
+	case reflect.Float32:
+		n, err := d.fixed32()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(n)))
+	case reflect.String:
+		block, err := d.block()
+		if err != nil {
+			return err
+		}
+		v.SetString(string(block))
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeBinaryValue(d, v.Elem())
+	case reflect.Struct:
+		block, err := d.block()
+		if err != nil {
+			return err
+		}
+		return decodeBinaryStruct(&binaryDecoder{data: block}, v)
+	case reflect.Slice:
+		block, err := d.block()
+		if err != nil {
+			return err
+		}
+		if v.Type().Elem() == byteType {
+			data := reflect.MakeSlice(v.Type(), len(block), len(block))
+			reflect.Copy(data, reflect.ValueOf(block))
+			v.Set(data)
+			return nil
+		}
+		inner := &binaryDecoder{data: block}
+		slice := reflect.MakeSlice(v.Type(), 0, 0)
+		for len(inner.data) > 0 {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err := decodeBinaryValue(inner, elem)
+			if err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		v.Set(slice)
+	case reflect.Array:
+		block, err := d.block()
+		if err != nil {
+			return err
+		}
+		if v.Type().Elem() == byteType {
+			if len(block) > v.Len() {
+				return fmt.Errorf("%d bytes do not fit into %s", len(block), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf(block))
+			return nil
+		}
+		inner := &binaryDecoder{data: block}
+		for i := 0; len(inner.data) > 0; i++ {
+			if i >= v.Len() {
+				return fmt.Errorf("too many elements for %s", v.Type())
+			}
+			err := decodeBinaryValue(inner, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		block, err := d.block()
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		inner := &binaryDecoder{data: block}
+		for len(inner.data) > 0 {
+			key := reflect.New(v.Type().Key()).Elem()
+			err := decodeBinaryValue(inner, key)
+			if err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			err = decodeBinaryValue(inner, value)
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package dsd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type BinaryTestChild struct {
+	Name  string  `dsd:"1"`
+	Score float32 `dsd:"2"`
+}
+
+type MyByte byte
+
+type BinaryTestStruct struct {
+	B       bool                    `dsd:"1"`
+	I       int                     `dsd:"2"`
+	I8      int8                    `dsd:"3"`
+	UI64    uint64                  `dsd:"4"`
+	F       float64                 `dsd:"5"`
+	S       string                  `dsd:"6"`
+	Sp      *string                 `dsd:"7"`
+	Sa      []string                `dsd:"8"`
+	Ba      []byte                  `dsd:"9"`
+	MBa     []MyByte                `dsd:"10"`
+	Arr     [3]int16                `dsd:"11"`
+	BArr    [4]byte                 `dsd:"12"`
+	M       map[string]int          `dsd:"13"`
+	MS      map[int]BinaryTestChild `dsd:"14"`
+	Child   BinaryTestChild         `dsd:"15"`
+	ChildP  *BinaryTestChild        `dsd:"16"`
+	Childs  []*BinaryTestChild      `dsd:"17"`
+	Nested  [][]uint                `dsd:"18"`
+	T       time.Time               `dsd:"19"`
+	Skipped string                  `dsd:"-"`
+	Tagged  string                  `dsd:"100"`
+	private string
+}
+
+// BinaryTestV1 and BinaryTestV2 are two versions of the same struct.
+type BinaryTestV1 struct {
+	Name  string `dsd:"1"`
+	Count int    `dsd:"2"`
+}
+
+type BinaryTestV2 struct {
+	Title    string            `dsd:"1"` // renamed
+	Count    int               `dsd:"2"`
+	Comment  string            `dsd:"3"` // added
+	Children []BinaryTestChild `dsd:"4"` // added
+}
+
+func TestBinary(t *testing.T) {
+	s := "pointer"
+	subject := &BinaryTestStruct{
+		B:      true,
+		I:      -1000,
+		I8:     -128,
+		UI64:   1 << 63,
+		F:      -0.25,
+		S:      "string",
+		Sp:     &s,
+		Sa:     []string{"a", "", "c"},
+		Ba:     []byte{1, 2, 3},
+		MBa:    []MyByte{4, 5},
+		Arr:    [3]int16{-1, 0, 1},
+		BArr:   [4]byte{9, 8, 7, 6},
+		M:      map[string]int{"a": 1, "b": -2},
+		MS:     map[int]BinaryTestChild{-1: {Name: "minus"}, 1: {Name: "plus", Score: 1.5}},
+		Child:  BinaryTestChild{Name: "child"},
+		ChildP: &BinaryTestChild{},
+		Childs: []*BinaryTestChild{{Name: "a"}, {Score: 2}},
+		Nested: [][]uint{{1, 2}, {}, {3}},
+		T:      time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC),
+		Tagged: "tagged",
+	}
+	subject.Skipped = "skipped"
+	subject.private = "private"
+
+	data, err := Dump(subject, Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// must be deterministic
+	data2, err := Dump(subject, Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Error("encoding is not deterministic")
+	}
+
+	loaded := &BinaryTestStruct{}
+	_, err = Load(data, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject.Skipped = ""
+	subject.private = ""
+	if !reflect.DeepEqual(subject, loaded) {
+		t.Errorf("loaded struct does not match:\n%+v\n%+v", subject, loaded)
+	}
+
+	// empty struct
+	data, err = Dump(&BinaryTestStruct{}, Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Errorf("empty struct should only hold format and version, got %v", data)
+	}
+
+	// unsupported
+	_, err = Dump("string", Binary)
+	if err == nil {
+		t.Error("should fail to dump non-struct")
+	}
+	_, err = Dump(&struct {
+		I interface{} `dsd:"1"`
+	}{}, Binary)
+	if err == nil {
+		t.Error("should fail to dump interface field")
+	}
+	_, err = Dump(&struct {
+		A string `dsd:"1"`
+		B string
+	}{}, Binary)
+	if err == nil {
+		t.Error("should fail to dump untagged field")
+	}
+	_, err = Dump(&struct {
+		A string `dsd:"1"`
+		B string `dsd:"1"`
+	}{}, Binary)
+	if err == nil {
+		t.Error("should fail to dump duplicate tags")
+	}
+}
+
+func TestBinaryCompatibility(t *testing.T) {
+	v1 := &BinaryTestV1{Name: "name", Count: 3}
+	v2 := &BinaryTestV2{
+		Title:    "title",
+		Count:    -3,
+		Comment:  "comment",
+		Children: []BinaryTestChild{{Name: "child"}},
+	}
+
+	// old data, new struct
+	data, err := Dump(v1, Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLoaded := &BinaryTestV2{}
+	_, err = Load(data, newLoaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newLoaded.Title != "name" || newLoaded.Count != 3 || newLoaded.Comment != "" {
+		t.Errorf("unexpected result: %+v", newLoaded)
+	}
+
+	// new data, old struct
+	data, err = Dump(v2, Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLoaded := &BinaryTestV1{}
+	_, err = Load(data, oldLoaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldLoaded.Name != "title" || oldLoaded.Count != -3 {
+		t.Errorf("unexpected result: %+v", oldLoaded)
+	}
+
+	// changed type
+	_, err = Load(data, &struct {
+		Count string `dsd:"2"`
+	}{})
+	if err == nil {
+		t.Error("should fail on wire type mismatch")
+	}
+	// overflow
+	_, err = Load(data, &struct {
+		Children []struct {
+			Name uint8 `dsd:"1"`
+		} `dsd:"4"`
+	}{})
+	if err == nil {
+		t.Error("should fail on wire type mismatch in nested struct")
+	}
+}
+
+func FuzzBinaryLoad(f *testing.F) {
+	data, err := Dump(&BinaryTestStruct{
+		S:      "fuzz",
+		M:      map[string]int{"a": 1},
+		Childs: []*BinaryTestChild{{Name: "a"}},
+	}, Binary)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data[1:])
+	f.Add([]byte{binaryLayoutVersion})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// must not panic on arbitrary input
+		_ = binaryCodec{}.Unmarshal(data, &BinaryTestStruct{})
+	})
+}
@@ -0,0 +1,37 @@
+package dsd
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+)
+
+func init() {
+	_ = RegisterFormat(BSON, bsonCodec{})
+}
+
+type bsonCodec struct{}
+
+func (bsonCodec) Marshal(t interface{}) ([]byte, error) {
+	data, err := bson.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to pack bson data: %s", err)
+	}
+	return data, nil
+}
+
+func (bsonCodec) Unmarshal(data []byte, t interface{}) error {
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(data))
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack bson data: %s", err)
+	}
+	// decode nested documents into maps instead of ordered slices
+	decoder.DefaultDocumentM()
+
+	err = decoder.Decode(t)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack bson data: %s", err)
+	}
+	return nil
+}
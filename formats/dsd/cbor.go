@@ -0,0 +1,36 @@
+package dsd
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+var cborHandle = &codec.CborHandle{}
+
+func init() {
+	// decode maps into string keyed maps, so that they can be converted to JSON
+	cborHandle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+
+	_ = RegisterFormat(CBOR, cborCodec{})
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Marshal(t interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, cborHandle).Encode(t)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to pack cbor data: %s", err)
+	}
+	return data, nil
+}
+
+func (cborCodec) Unmarshal(data []byte, t interface{}) error {
+	err := codec.NewDecoderBytes(data, cborHandle).Decode(t)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack cbor data: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package dsd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+func init() {
+	_ = RegisterFormat(JSON, jsonCodec{})
+	_ = RegisterFormat(GenCode, genCodeCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(t interface{}) ([]byte, error) {
+	// TODO: use SetEscapeHTML(false)
+	return json.Marshal(t)
+}
+
+func (jsonCodec) Unmarshal(data []byte, t interface{}) error {
+	err := json.Unmarshal(data, t)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack json data: %s", data)
+	}
+	return nil
+}
+
+type genCodeCodec struct{}
+
+func (genCodeCodec) Marshal(t interface{}) ([]byte, error) {
+	genCodeStruct, ok := t.(GenCodeCompatible)
+	if !ok {
+		return nil, errors.New("dsd: gencode is not supported by the given data structure")
+	}
+	data, err := genCodeStruct.GenCodeMarshal(nil)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to pack gencode struct: %s", err)
+	}
+	return data, nil
+}
+
+func (genCodeCodec) Unmarshal(data []byte, t interface{}) error {
+	genCodeStruct, ok := t.(GenCodeCompatible)
+	if !ok {
+		return errors.New("dsd: gencode is not supported by the given data structure")
+	}
+	_, err := genCodeStruct.GenCodeUnmarshal(data)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack gencode data: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package dsd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/safing/portbase/formats/varint"
+)
+
+// define compression types
+const (
+	GZIP = 90 // Z
+	ZSTD = 68 // D
+)
+
+// CompressionThreshold is the minimum size of dsd data in bytes for it to be compressed. Smaller data stays uncompressed, as the compression overhead would outweigh the gain.
+const CompressionThreshold = 512
+
+// Compressor compresses and decompresses data.
+type Compressor interface {
+	// Compress compresses the given data.
+	Compress(data []byte) ([]byte, error)
+	// Decompress decompresses the given data.
+	Decompress(data []byte) ([]byte, error)
+}
+
+var (
+	compressors     = make(map[uint8]Compressor)
+	compressorsLock sync.RWMutex
+)
+
+func init() {
+	_ = RegisterCompression(GZIP, gzipCompressor{})
+	_ = RegisterCompression(ZSTD, &zstdCompressor{})
+}
+
+// RegisterCompression registers a compressor for the given compression identifier. The identifier must not be used by a format.
+func RegisterCompression(compression uint8, compressor Compressor) error {
+	if _, ok := getCodec(compression); ok {
+		return fmt.Errorf("dsd: identifier %d is already used by a format", compression)
+	}
+	switch compression {
+	case AUTO, STRING, BYTES, SIGNED:
+		return fmt.Errorf("dsd: identifier %d is reserved", compression)
+	}
+
+	compressorsLock.Lock()
+	defer compressorsLock.Unlock()
+
+	_, ok := compressors[compression]
+	if ok {
+		return fmt.Errorf("dsd: compressor for compression %d already registered", compression)
+	}
+
+	compressors[compression] = compressor
+	return nil
+}
+
+func getCompressor(compression uint8) (Compressor, bool) {
+	compressorsLock.RLock()
+	defer compressorsLock.RUnlock()
+
+	compressor, ok := compressors[compression]
+	return compressor, ok
+}
+
+// DumpAndCompress stores the interface as a dsd formatted data structure and compresses it, if it exceeds the CompressionThreshold.
+func DumpAndCompress(t interface{}, format uint8, compression uint8) ([]byte, error) {
+	data, err := Dump(t, format)
+	if err != nil {
+		return nil, err
+	}
+	return Compress(data, compression)
+}
+
+// Compress compresses the given dsd formatted data, if it exceeds the CompressionThreshold. The compression AUTO disables compression.
+func Compress(data []byte, compression uint8) ([]byte, error) {
+	if compression == AUTO || len(data) < CompressionThreshold {
+		return data, nil
+	}
+
+	compressor, ok := getCompressor(compression)
+	if !ok {
+		return nil, fmt.Errorf("dsd: tried to compress with unknown compression %d", compression)
+	}
+
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to compress data: %s", err)
+	}
+	// keep incompressible data as is
+	if len(compressed) >= len(data) {
+		return data, nil
+	}
+
+	return append(varint.Pack8(compression), compressed...), nil
+}
+
+// Decompress decompresses the given dsd formatted data, if it is compressed. Uncompressed data is returned as is.
+func Decompress(data []byte) ([]byte, error) {
+	compression, read, err := varint.Unpack8(data)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, ok := getCompressor(compression)
+	if !ok {
+		// not compressed
+		return data, nil
+	}
+
+	decompressed, err := compressor.Decompress(data[read:])
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to decompress data: %s", err)
+	}
+	return decompressed, nil
+}
+
+// HasCompression returns whether a compressor is registered for the given compression identifier.
+func HasCompression(compression uint8) bool {
+	_, ok := getCompressor(compression)
+	return ok
+}
+
+// IsCompressed returns whether the given dsd formatted data is compressed.
+func IsCompressed(data []byte) bool {
+	compression, _, err := varint.Unpack8(data)
+	if err != nil {
+		return false
+	}
+	return HasCompression(compression)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+type zstdCompressor struct {
+	init    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	err     error
+}
+
+// setup lazily creates the shared encoder and decoder, which are safe for concurrent use with EncodeAll and DecodeAll.
+func (zc *zstdCompressor) setup() error {
+	zc.init.Do(func() {
+		zc.encoder, zc.err = zstd.NewWriter(nil)
+		if zc.err != nil {
+			return
+		}
+		zc.decoder, zc.err = zstd.NewReader(nil)
+	})
+	return zc.err
+}
+
+func (zc *zstdCompressor) Compress(data []byte) ([]byte, error) {
+	if err := zc.setup(); err != nil {
+		return nil, err
+	}
+	return zc.encoder.EncodeAll(data, nil), nil
+}
+
+func (zc *zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	if err := zc.setup(); err != nil {
+		return nil, err
+	}
+	return zc.decoder.DecodeAll(data, nil)
+}
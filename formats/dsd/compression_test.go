@@ -0,0 +1,71 @@
+package dsd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	large := &SimpleTestStruct{
+		S: strings.Repeat("compressible ", 100),
+		B: 0x01,
+	}
+	small := &SimpleTestStruct{
+		S: "a",
+		B: 0x02,
+	}
+
+	for _, compression := range []uint8{GZIP, ZSTD} {
+		// large data is compressed
+		d, err := DumpAndCompress(large, JSON, compression)
+		if err != nil {
+			t.Fatalf("DumpAndCompress error (%c): %s", compression, err)
+		}
+		if d[0] != compression || !IsCompressed(d) {
+			t.Fatalf("DumpAndCompress (%c): data is not compressed", compression)
+		}
+		uncompressed, err := Dump(large, JSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d) >= len(uncompressed) {
+			t.Errorf("DumpAndCompress (%c): compressed data is not smaller (%d >= %d)", compression, len(d), len(uncompressed))
+		}
+
+		// decompression is transparent
+		o, err := Load(d, &SimpleTestStruct{})
+		if err != nil {
+			t.Fatalf("Load error (%c): %s", compression, err)
+		}
+		if !reflect.DeepEqual(large, o) {
+			t.Errorf("Load (%c): subject does not match loaded object", compression)
+		}
+		decompressed, err := Decompress(d)
+		if err != nil {
+			t.Fatalf("Decompress error (%c): %s", compression, err)
+		}
+		if !bytes.Equal(uncompressed, decompressed) {
+			t.Errorf("Decompress (%c): data mismatch", compression)
+		}
+
+		// small data stays uncompressed
+		d, err = DumpAndCompress(small, JSON, compression)
+		if err != nil {
+			t.Fatalf("DumpAndCompress error (%c): %s", compression, err)
+		}
+		if d[0] != JSON || IsCompressed(d) {
+			t.Errorf("DumpAndCompress (%c): small data should not be compressed", compression)
+		}
+	}
+
+	err := RegisterCompression(JSON, gzipCompressor{})
+	if err == nil {
+		t.Error("should fail to register compression with format identifier")
+	}
+	err = RegisterFormat(GZIP, jsonCodec{})
+	if err == nil {
+		t.Error("should fail to register format with compression identifier")
+	}
+}
@@ -4,11 +4,9 @@
 // check here for some benchmarks: https://github.com/alecthomas/go_serialization_benchmarks
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-
-	// "github.com/pkg/bson"
+	"sync"
 
 	"github.com/safing/portbase/formats/varint"
 )
@@ -20,12 +18,57 @@
 	BYTES   = 88 // X
 	JSON    = 74 // J
 	BSON    = 66 // B
+	CBOR    = 67 // C
 	GenCode = 71 // G
+	MsgPack = 77 // M
+	Binary  = 80 // P
 )
 
 // define errors
 var errNoMoreSpace = errors.New("dsd: no more space left after reading dsd type")
-var errNotImplemented = errors.New("dsd: this type is not yet implemented")
+
+// Codec serializes data structures into a format.
+type Codec interface {
+	// Marshal serializes the given data structure.
+	Marshal(t interface{}) ([]byte, error)
+	// Unmarshal deserializes data into the given data structure.
+	Unmarshal(data []byte, t interface{}) error
+}
+
+var (
+	codecs     = make(map[uint8]Codec)
+	codecsLock sync.RWMutex
+)
+
+// RegisterFormat registers a codec for the given format identifier. The identifiers AUTO, STRING, BYTES and SIGNED are reserved.
+func RegisterFormat(format uint8, codec Codec) error {
+	switch format {
+	case AUTO, STRING, BYTES, SIGNED:
+		return fmt.Errorf("dsd: format %d is reserved", format)
+	}
+	if _, ok := getCompressor(format); ok {
+		return fmt.Errorf("dsd: identifier %d is already used by a compression", format)
+	}
+
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+
+	_, ok := codecs[format]
+	if ok {
+		return fmt.Errorf("dsd: codec for format %d already registered", format)
+	}
+
+	codecs[format] = codec
+	return nil
+}
+
+func getCodec(format uint8) (Codec, bool) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+
+	codec, ok := codecs[format]
+	return codec, ok
+}
 
 // Load loads an dsd structured data blob into the given interface.
 func Load(data []byte, t interface{}) (interface{}, error) {
@@ -41,6 +84,18 @@ func Load(data []byte, t interface{}) (interface{}, error) {
 		return nil, errNoMoreSpace
 	}
 
+	// decompress transparently
+	if _, ok := getCompressor(format); ok {
+		decompressed, err := Decompress(data)
+		if err != nil {
+			return nil, err
+		}
+		return Load(decompressed, t)
+	}
+	if format == SIGNED {
+		return nil, errors.New("dsd: data is signed, use LoadVerified")
+	}
+
 	return LoadAsFormat(data[read:], format, t)
 }
 
@@ -51,32 +106,18 @@ func LoadAsFormat(data []byte, format uint8, t interface{}) (interface{}, error)
 		return string(data), nil
 	case BYTES:
 		return data, nil
-	case JSON:
-		err := json.Unmarshal(data, t)
-		if err != nil {
-			return nil, fmt.Errorf("dsd: failed to unpack json data: %s", data)
-		}
-		return t, nil
-	case BSON:
-		return nil, errNotImplemented
-	// 	err := bson.Unmarshal(data[read:], t)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	return t, nil
-	case GenCode:
-		genCodeStruct, ok := t.(GenCodeCompatible)
-		if !ok {
-			return nil, errors.New("dsd: gencode is not supported by the given data structure")
-		}
-		_, err := genCodeStruct.GenCodeUnmarshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("dsd: failed to unpack gencode data: %s", err)
-		}
-		return t, nil
-	default:
+	}
+
+	codec, ok := getCodec(format)
+	if !ok {
 		return nil, fmt.Errorf("dsd: tried to load unknown type %d, data: %v", format, data)
 	}
+
+	err := codec.Unmarshal(data, t)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
 }
 
 // Dump stores the interface as a dsd formatted data structure.
@@ -100,31 +141,45 @@ func Dump(t interface{}, format uint8) ([]byte, error) {
 		data = []byte(t.(string))
 	case BYTES:
 		data = t.([]byte)
-	case JSON:
-		// TODO: use SetEscapeHTML(false)
-		data, err = json.Marshal(t)
-		if err != nil {
-			return nil, err
-		}
-	case BSON:
-		return nil, errNotImplemented
-	// 	data, err = bson.Marshal(t)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	case GenCode:
-		genCodeStruct, ok := t.(GenCodeCompatible)
+	default:
+		codec, ok := getCodec(format)
 		if !ok {
-			return nil, errors.New("dsd: gencode is not supported by the given data structure")
+			return nil, fmt.Errorf("dsd: tried to dump unknown type %d", format)
 		}
-		data, err = genCodeStruct.GenCodeMarshal(nil)
+		data, err = codec.Marshal(t)
 		if err != nil {
-			return nil, fmt.Errorf("dsd: failed to pack gencode struct: %s", err)
+			return nil, err
 		}
-	default:
-		return nil, fmt.Errorf("dsd: tried to dump unknown type %d", format)
 	}
 
 	r := append(f, data...)
 	return r, nil
 }
+
+// Convert converts a data blob from one format into another. The data is decoded into generic maps and slices, so this only works for formats that support that. GenCode and Binary need the struct of the data and cannot be converted.
+func Convert(data []byte, from, to uint8) ([]byte, error) {
+	if from == to {
+		return data, nil
+	}
+	for _, format := range []uint8{from, to} {
+		switch format {
+		case AUTO, STRING, BYTES:
+			return nil, fmt.Errorf("dsd: cannot convert from or to format %d", format)
+		case GenCode, Binary:
+			return nil, fmt.Errorf("dsd: cannot convert from or to format %c, as it is not self-describing", format)
+		}
+	}
+
+	var v interface{}
+	_, err := LoadAsFormat(data, from, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := Dump(v, to)
+	if err != nil {
+		return nil, err
+	}
+	// strip format identifier
+	return converted[len(varint.Pack8(to)):], nil
+}
@@ -11,31 +11,31 @@
 
 // SimpleTestStruct is used for testing.
 type SimpleTestStruct struct {
-	S string
-	B byte
+	S string `dsd:"1"`
+	B byte   `dsd:"2"`
 }
 
 type ComplexTestStruct struct {
-	I    int
-	I8   int8
-	I16  int16
-	I32  int32
-	I64  int64
-	UI   uint
-	UI8  uint8
-	UI16 uint16
-	UI32 uint32
-	UI64 uint64
-	S    string
-	Sp   *string
-	Sa   []string
-	Sap  *[]string
-	B    byte
-	Bp   *byte
-	Ba   []byte
-	Bap  *[]byte
-	M    map[string]string
-	Mp   *map[string]string
+	I    int                `dsd:"1"`
+	I8   int8               `dsd:"2"`
+	I16  int16              `dsd:"3"`
+	I32  int32              `dsd:"4"`
+	I64  int64              `dsd:"5"`
+	UI   uint               `dsd:"6"`
+	UI8  uint8              `dsd:"7"`
+	UI16 uint16             `dsd:"8"`
+	UI32 uint32             `dsd:"9"`
+	UI64 uint64             `dsd:"10"`
+	S    string             `dsd:"11"`
+	Sp   *string            `dsd:"12"`
+	Sa   []string           `dsd:"13"`
+	Sap  *[]string          `dsd:"14"`
+	B    byte               `dsd:"15"`
+	Bp   *byte              `dsd:"16"`
+	Ba   []byte             `dsd:"17"`
+	Bap  *[]byte            `dsd:"18"`
+	M    map[string]string  `dsd:"19"`
+	Mp   *map[string]string `dsd:"20"`
 }
 
 type GenCodeTestStruct struct {
@@ -151,7 +151,7 @@ func TestConversion(t *testing.T) {
 	}
 
 	// test all formats (complex)
-	formats := []uint8{JSON}
+	formats := []uint8{JSON, BSON, CBOR, MsgPack, Binary}
 
 	for _, format := range formats {
 
@@ -249,7 +249,7 @@ func TestConversion(t *testing.T) {
 	}
 
 	// test all formats
-	formats = []uint8{JSON, GenCode}
+	formats = []uint8{JSON, BSON, CBOR, GenCode, MsgPack}
 
 	for _, format := range formats {
 		// simple
@@ -332,3 +332,59 @@ func TestConversion(t *testing.T) {
 		}
 	}
 }
+
+func TestConvert(t *testing.T) {
+	subject := map[string]interface{}{
+		"S": "a",
+		"M": map[string]interface{}{
+			"Sa": []interface{}{"b", "c"},
+		},
+	}
+
+	for _, format := range []uint8{BSON, CBOR, MsgPack} {
+		d, err := Dump(subject, format)
+		if err != nil {
+			t.Fatalf("Dump error (%c): %s", format, err)
+		}
+		if d[0] != format {
+			t.Fatalf("Dump (%c): unexpected format identifier %c", format, d[0])
+		}
+
+		converted, err := Convert(d[1:], format, JSON)
+		if err != nil {
+			t.Fatalf("Convert error (%c): %s", format, err)
+		}
+		if string(converted) != `{"M":{"Sa":["b","c"]},"S":"a"}` {
+			t.Errorf("Convert (%c): unexpected result %s", format, converted)
+		}
+	}
+
+	_, err := Convert([]byte("abc"), STRING, JSON)
+	if err == nil {
+		t.Error("Convert should fail for strings")
+	}
+	_, err = Convert([]byte("abc"), Binary, JSON)
+	if err == nil {
+		t.Error("Convert should fail for the binary format")
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	err := RegisterFormat(JSON, jsonCodec{})
+	if err == nil {
+		t.Error("should fail to register already registered format")
+	}
+	err = RegisterFormat(STRING, jsonCodec{})
+	if err == nil {
+		t.Error("should fail to register reserved format")
+	}
+
+	_, err = Dump("abc", 1)
+	if err == nil {
+		t.Error("should fail to dump unknown format")
+	}
+	_, err = Load([]byte{1, 2, 3}, nil)
+	if err == nil {
+		t.Error("should fail to load unknown format")
+	}
+}
@@ -0,0 +1,39 @@
+package dsd
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+var msgPackHandle = &codec.MsgpackHandle{}
+
+func init() {
+	// use the current spec, which distinguishes between strings and binary data
+	msgPackHandle.WriteExt = true
+	msgPackHandle.RawToString = true
+	// decode maps into string keyed maps, so that they can be converted to JSON
+	msgPackHandle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+
+	_ = RegisterFormat(MsgPack, msgPackCodec{})
+}
+
+type msgPackCodec struct{}
+
+func (msgPackCodec) Marshal(t interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, msgPackHandle).Encode(t)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to pack msgpack data: %s", err)
+	}
+	return data, nil
+}
+
+func (msgPackCodec) Unmarshal(data []byte, t interface{}) error {
+	err := codec.NewDecoderBytes(data, msgPackHandle).Decode(t)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to unpack msgpack data: %s", err)
+	}
+	return nil
+}
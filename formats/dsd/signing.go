@@ -0,0 +1,271 @@
+package dsd
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/safing/portbase/container"
+	"github.com/safing/portbase/formats/varint"
+)
+
+// SIGNED identifies a signing envelope, which wraps dsd formatted data with a signature.
+const SIGNED = 69 // E
+
+// define signature types
+const (
+	SignatureEd25519 = 1
+)
+
+// define signing errors
+var (
+	ErrNoKeyStore       = errors.New("dsd: no key store set")
+	ErrNotSigned        = errors.New("dsd: data is not signed")
+	ErrInvalidSignature = errors.New("dsd: invalid signature")
+)
+
+// KeyStore provides the keys for signing and verifying data.
+type KeyStore interface {
+	// SigningKey returns the private key with the given ID.
+	SigningKey(keyID string) (ed25519.PrivateKey, error)
+	// VerificationKey returns the public key with the given ID.
+	VerificationKey(keyID string) (ed25519.PublicKey, error)
+}
+
+var (
+	keyStore     KeyStore
+	keyStoreLock sync.RWMutex
+)
+
+// SetKeyStore sets the key store used for signing and verifying data.
+func SetKeyStore(ks KeyStore) {
+	keyStoreLock.Lock()
+	defer keyStoreLock.Unlock()
+	keyStore = ks
+}
+
+func getKeyStore() (KeyStore, error) {
+	keyStoreLock.RLock()
+	defer keyStoreLock.RUnlock()
+
+	if keyStore == nil {
+		return nil, ErrNoKeyStore
+	}
+	return keyStore, nil
+}
+
+// Envelope is a parsed signing envelope.
+// Format: SIGNED | signature type | key ID block | signature block | payload
+type Envelope struct {
+	SignatureType uint8
+	KeyID         string
+	Signature     []byte
+	// Payload holds the signed dsd formatted data.
+	Payload []byte
+}
+
+// DumpSigned stores the interface as a dsd formatted data structure and signs it with the given key.
+func DumpSigned(t interface{}, format uint8, keyID string) ([]byte, error) {
+	data, err := Dump(t, format)
+	if err != nil {
+		return nil, err
+	}
+	return Sign(data, keyID)
+}
+
+// LoadVerified verifies the signature of a signing envelope and loads its payload into the given interface. Compressed envelopes are decompressed first.
+func LoadVerified(data []byte, t interface{}) (interface{}, error) {
+	data, err := Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := Verify(data)
+	if err != nil {
+		return nil, err
+	}
+	return Load(payload, t)
+}
+
+// Sign wraps the given dsd formatted data in a signing envelope, signed with the given key.
+func Sign(data []byte, keyID string) ([]byte, error) {
+	return SignBound(data, nil, keyID)
+}
+
+// SignBound is like Sign, but the signature additionally covers the bound data, which is not stored in the envelope. The same bound data must be supplied for verification.
+func SignBound(data, bound []byte, keyID string) ([]byte, error) {
+	ks, err := getKeyStore()
+	if err != nil {
+		return nil, err
+	}
+	key, err := ks.SigningKey(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to get signing key %s: %s", keyID, err)
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("dsd: invalid signing key %s", keyID)
+	}
+
+	e := &Envelope{
+		SignatureType: SignatureEd25519,
+		KeyID:         keyID,
+		Payload:       data,
+	}
+	e.Signature = ed25519.Sign(key, e.signedMessage(bound))
+
+	return e.Pack(), nil
+}
+
+// Verify verifies the signature of a signing envelope and returns its payload.
+func Verify(data []byte) ([]byte, error) {
+	e, err := ParseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	err = e.Verify()
+	if err != nil {
+		return nil, err
+	}
+	return e.Payload, nil
+}
+
+// IsSigned returns whether the given data is a signing envelope.
+func IsSigned(data []byte) bool {
+	return len(data) > 0 && data[0] == SIGNED
+}
+
+// ParseEnvelope parses a signing envelope without verifying it.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	if !IsSigned(data) {
+		return nil, ErrNotSigned
+	}
+	offset := 1
+
+	sigType, n, err := varint.Unpack8(data[offset:])
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to get signature type: %s", err)
+	}
+	offset += n
+
+	keyID, n, err := varint.GetNextBlock(data[offset:])
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to get key ID: %s", err)
+	}
+	offset += n
+
+	signature, n, err := varint.GetNextBlock(data[offset:])
+	if err != nil {
+		return nil, fmt.Errorf("dsd: failed to get signature: %s", err)
+	}
+	offset += n
+
+	return &Envelope{
+		SignatureType: sigType,
+		KeyID:         string(keyID),
+		Signature:     signature,
+		Payload:       data[offset:],
+	}, nil
+}
+
+// Pack serializes the envelope.
+func (e *Envelope) Pack() []byte {
+	c := container.New(varint.Pack8(SIGNED), varint.Pack8(e.SignatureType))
+	c.AppendAsBlock([]byte(e.KeyID))
+	c.AppendAsBlock(e.Signature)
+	c.Append(e.Payload)
+	return c.CompileData()
+}
+
+// Verify verifies the signature of the envelope using the key store.
+func (e *Envelope) Verify() error {
+	return e.VerifyBound(nil)
+}
+
+// VerifyBound verifies the signature of the envelope and the bound data it was signed with, using the key store.
+func (e *Envelope) VerifyBound(bound []byte) error {
+	if e.SignatureType != SignatureEd25519 {
+		return fmt.Errorf("dsd: unknown signature type %d", e.SignatureType)
+	}
+
+	ks, err := getKeyStore()
+	if err != nil {
+		return err
+	}
+	key, err := ks.VerificationKey(e.KeyID)
+	if err != nil {
+		return fmt.Errorf("dsd: failed to get verification key %s: %s", e.KeyID, err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("dsd: invalid verification key %s", e.KeyID)
+	}
+
+	if !ed25519.Verify(key, e.signedMessage(bound), e.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signedMessage returns the message covered by the signature, which binds the signature type, the key ID and the bound data to the payload.
+func (e *Envelope) signedMessage(bound []byte) []byte {
+	c := container.New(varint.Pack8(e.SignatureType))
+	c.AppendAsBlock([]byte(e.KeyID))
+	c.AppendAsBlock(bound)
+	c.Append(e.Payload)
+	return c.CompileData()
+}
+
+// MemoryKeyStore is a simple in-memory KeyStore.
+type MemoryKeyStore struct {
+	lock             sync.RWMutex
+	signingKeys      map[string]ed25519.PrivateKey
+	verificationKeys map[string]ed25519.PublicKey
+}
+
+// NewMemoryKeyStore returns a new, empty MemoryKeyStore.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		signingKeys:      make(map[string]ed25519.PrivateKey),
+		verificationKeys: make(map[string]ed25519.PublicKey),
+	}
+}
+
+// AddSigningKey adds a private key, which is also used for verification.
+func (ks *MemoryKeyStore) AddSigningKey(keyID string, key ed25519.PrivateKey) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	ks.signingKeys[keyID] = key
+	ks.verificationKeys[keyID] = key.Public().(ed25519.PublicKey)
+}
+
+// AddVerificationKey adds a public key, eg. of another node.
+func (ks *MemoryKeyStore) AddVerificationKey(keyID string, key ed25519.PublicKey) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	ks.verificationKeys[keyID] = key
+}
+
+// SigningKey returns the private key with the given ID.
+func (ks *MemoryKeyStore) SigningKey(keyID string) (ed25519.PrivateKey, error) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	key, ok := ks.signingKeys[keyID]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return key, nil
+}
+
+// VerificationKey returns the public key with the given ID.
+func (ks *MemoryKeyStore) VerificationKey(keyID string) (ed25519.PublicKey, error) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	key, ok := ks.verificationKeys[keyID]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return key, nil
+}
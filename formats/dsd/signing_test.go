@@ -0,0 +1,109 @@
+package dsd
+
+import (
+	"crypto/ed25519"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSigning(t *testing.T) {
+	subject := &SimpleTestStruct{
+		S: "signed",
+		B: 0x01,
+	}
+
+	// no key store
+	_, err := DumpSigned(subject, JSON, "test")
+	if err != ErrNoKeyStore {
+		t.Fatalf("expected ErrNoKeyStore, got %v", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewMemoryKeyStore()
+	ks.AddSigningKey("test", key)
+	ks.AddVerificationKey("other", otherPub)
+	SetKeyStore(ks)
+	defer SetKeyStore(nil)
+
+	// sign and verify
+	signed, err := DumpSigned(subject, JSON, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSigned(signed) {
+		t.Fatal("data should be signed")
+	}
+	o, err := LoadVerified(signed, &SimpleTestStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subject, o) {
+		t.Errorf("LoadVerified: subject does not match loaded object")
+	}
+	e, err := ParseEnvelope(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.KeyID != "test" || e.SignatureType != SignatureEd25519 {
+		t.Errorf("unexpected envelope: %+v", e)
+	}
+
+	// plain load must not skip verification
+	_, err = Load(signed, &SimpleTestStruct{})
+	if err == nil {
+		t.Error("Load should fail on signed data")
+	}
+
+	// tampered payload
+	tampered := make([]byte, len(signed))
+	copy(tampered, signed)
+	tampered[len(tampered)-3]++
+	_, err = LoadVerified(tampered, &SimpleTestStruct{})
+	if err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for tampered data, got %v", err)
+	}
+
+	// key ID is covered by the signature
+	e.KeyID = "other"
+	err = e.Verify()
+	if err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for swapped key ID, got %v", err)
+	}
+
+	// unknown key
+	_, err = DumpSigned(subject, JSON, "unknown")
+	if err == nil {
+		t.Error("should fail to sign with unknown key")
+	}
+
+	// compressed envelope
+	large := &SimpleTestStruct{
+		S: strings.Repeat("compressible ", 100),
+	}
+	signed, err = DumpSigned(large, JSON, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := Compress(signed, GZIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsCompressed(compressed) {
+		t.Fatal("signed data should be compressed")
+	}
+	o, err = LoadVerified(compressed, &SimpleTestStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(large, o) {
+		t.Errorf("LoadVerified: subject does not match loaded compressed object")
+	}
+}
@@ -0,0 +1,96 @@
+package varint
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Fixed-width integers and floats are encoded in big endian (network byte order).
+
+// PackFixed16 packs a uint16 into 2 bytes.
+func PackFixed16(n uint16) []byte {
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, n)
+	return buf
+}
+
+// PackFixed32 packs a uint32 into 4 bytes.
+func PackFixed32(n uint32) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, n)
+	return buf
+}
+
+// PackFixed64 packs a uint64 into 8 bytes.
+func PackFixed64(n uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, n)
+	return buf
+}
+
+// UnpackFixed16 unpacks a uint16 from 2 bytes. It returns the extracted int, how many bytes were used and an error.
+func UnpackFixed16(blob []byte) (uint16, int, error) {
+	if len(blob) < 2 {
+		return 0, 0, errors.New("varint: buf too small")
+	}
+	return binary.BigEndian.Uint16(blob), 2, nil
+}
+
+// UnpackFixed32 unpacks a uint32 from 4 bytes. It returns the extracted int, how many bytes were used and an error.
+func UnpackFixed32(blob []byte) (uint32, int, error) {
+	if len(blob) < 4 {
+		return 0, 0, errors.New("varint: buf too small")
+	}
+	return binary.BigEndian.Uint32(blob), 4, nil
+}
+
+// UnpackFixed64 unpacks a uint64 from 8 bytes. It returns the extracted int, how many bytes were used and an error.
+func UnpackFixed64(blob []byte) (uint64, int, error) {
+	if len(blob) < 8 {
+		return 0, 0, errors.New("varint: buf too small")
+	}
+	return binary.BigEndian.Uint64(blob), 8, nil
+}
+
+// PackFloat32 packs a float32 into 4 bytes.
+func PackFloat32(f float32) []byte {
+	return PackFixed32(math.Float32bits(f))
+}
+
+// PackFloat64 packs a float64 into 8 bytes.
+func PackFloat64(f float64) []byte {
+	return PackFixed64(math.Float64bits(f))
+}
+
+// UnpackFloat32 unpacks a float32 from 4 bytes. It returns the extracted float, how many bytes were used and an error.
+func UnpackFloat32(blob []byte) (float32, int, error) {
+	n, r, err := UnpackFixed32(blob)
+	if err != nil {
+		return 0, 0, err
+	}
+	return math.Float32frombits(n), r, nil
+}
+
+// UnpackFloat64 unpacks a float64 from 8 bytes. It returns the extracted float, how many bytes were used and an error.
+func UnpackFloat64(blob []byte) (float64, int, error) {
+	n, r, err := UnpackFixed64(blob)
+	if err != nil {
+		return 0, 0, err
+	}
+	return math.Float64frombits(n), r, nil
+}
+
+// PackString packs a string prefixed with its VarInt encoded length.
+func PackString(s string) []byte {
+	return PrependLength([]byte(s))
+}
+
+// UnpackString unpacks a string prefixed with its VarInt encoded length. It returns the extracted string, how many bytes were used and an error.
+func UnpackString(blob []byte) (string, int, error) {
+	data, n, err := GetNextBlock(blob)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(data), n, nil
+}
@@ -0,0 +1,42 @@
+package varint
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// PackInt64 packs an int64 into a zig-zag encoded VarInt, so that numbers with a small absolute value, including negative ones, are encoded with few bytes.
+func PackInt64(n int64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	w := binary.PutVarint(buf, n)
+	return buf[:w]
+}
+
+// PackInt32 packs an int32 into a zig-zag encoded VarInt.
+func PackInt32(n int32) []byte {
+	return PackInt64(int64(n))
+}
+
+// UnpackInt64 unpacks a zig-zag encoded VarInt into an int64. It returns the extracted int, how many bytes were used and an error.
+func UnpackInt64(blob []byte) (int64, int, error) {
+	n, r := binary.Varint(blob)
+	if r == 0 {
+		return 0, 0, errors.New("varint: buf too small")
+	}
+	if r < 0 {
+		return 0, 0, errors.New("varint: encoded integer out of range (int64)")
+	}
+	return n, r, nil
+}
+
+// UnpackInt32 unpacks a zig-zag encoded VarInt into an int32. It returns the extracted int, how many bytes were used and an error.
+func UnpackInt32(blob []byte) (int32, int, error) {
+	n, r, err := UnpackInt64(blob)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < -2147483648 || n > 2147483647 {
+		return 0, 0, errors.New("varint: encoded integer out of range (int32)")
+	}
+	return int32(n), r, nil
+}
@@ -0,0 +1,131 @@
+package varint
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestSigned(t *testing.T) {
+	var subjects = []struct {
+		bytes   []byte
+		integer int64
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x01}, -1},
+		{[]byte{0x02}, 1},
+		{[]byte{0x7F}, -64},
+		{[]byte{0x80, 0x01}, 64},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}, math.MinInt64},
+		{[]byte{0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}, math.MaxInt64},
+	}
+
+	for _, subject := range subjects {
+		packed := PackInt64(subject.integer)
+		if !bytes.Equal(packed, subject.bytes) {
+			t.Errorf("PackInt64(%d) = %v, expected %v", subject.integer, packed, subject.bytes)
+		}
+		n, r, err := UnpackInt64(subject.bytes)
+		if err != nil || n != subject.integer || r != len(subject.bytes) {
+			t.Errorf("UnpackInt64(%v) = %d, %d, %s, expected %d", subject.bytes, n, r, err, subject.integer)
+		}
+	}
+
+	_, _, err := UnpackInt32(PackInt64(math.MaxInt32 + 1))
+	if err == nil {
+		t.Error("UnpackInt32 should fail on overflow")
+	}
+	n, _, err := UnpackInt32(PackInt32(math.MinInt32))
+	if err != nil || n != math.MinInt32 {
+		t.Errorf("UnpackInt32 failed: %d, %s", n, err)
+	}
+}
+
+func TestFixedAndFloat(t *testing.T) {
+	if !bytes.Equal(PackFixed32(1), []byte{0, 0, 0, 1}) {
+		t.Error("fixed width integers must be big endian")
+	}
+	n16, r, err := UnpackFixed16(PackFixed16(65535))
+	if err != nil || n16 != 65535 || r != 2 {
+		t.Errorf("UnpackFixed16 failed: %d, %d, %s", n16, r, err)
+	}
+	n64, r, err := UnpackFixed64(PackFixed64(math.MaxUint64))
+	if err != nil || n64 != math.MaxUint64 || r != 8 {
+		t.Errorf("UnpackFixed64 failed: %d, %d, %s", n64, r, err)
+	}
+	_, _, err = UnpackFixed64(make([]byte, 7))
+	if err == nil {
+		t.Error("UnpackFixed64 should fail on short data")
+	}
+
+	f32, _, err := UnpackFloat32(PackFloat32(-1.5))
+	if err != nil || f32 != -1.5 {
+		t.Errorf("UnpackFloat32 failed: %f, %s", f32, err)
+	}
+	f64, _, err := UnpackFloat64(PackFloat64(math.Pi))
+	if err != nil || f64 != math.Pi {
+		t.Errorf("UnpackFloat64 failed: %f, %s", f64, err)
+	}
+
+	s, r, err := UnpackString(PackString("hello"))
+	if err != nil || s != "hello" || r != 6 {
+		t.Errorf("UnpackString failed: %s, %d, %s", s, r, err)
+	}
+	_, _, err = UnpackString([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})
+	if err == nil {
+		t.Error("UnpackString should fail on impossible length")
+	}
+}
+
+func FuzzInt64(f *testing.F) {
+	f.Add(int64(0))
+	f.Add(int64(-1))
+	f.Add(int64(math.MinInt64))
+	f.Add(int64(math.MaxInt64))
+	f.Fuzz(func(t *testing.T, n int64) {
+		unpacked, r, err := UnpackInt64(PackInt64(n))
+		if err != nil || unpacked != n || r != len(PackInt64(n)) {
+			t.Errorf("round trip of %d failed: %d, %s", n, unpacked, err)
+		}
+	})
+}
+
+func FuzzFloat64(f *testing.F) {
+	f.Add(0.0)
+	f.Add(-1.5)
+	f.Add(math.Inf(1))
+	f.Fuzz(func(t *testing.T, v float64) {
+		unpacked, _, err := UnpackFloat64(PackFloat64(v))
+		if err != nil || math.Float64bits(unpacked) != math.Float64bits(v) {
+			t.Errorf("round trip of %f failed: %f, %s", v, unpacked, err)
+		}
+	})
+}
+
+func FuzzString(f *testing.F) {
+	f.Add("")
+	f.Add("The quick brown fox jumps over the lazy dog")
+	f.Fuzz(func(t *testing.T, s string) {
+		unpacked, _, err := UnpackString(PackString(s))
+		if err != nil || unpacked != s {
+			t.Errorf("round trip of %q failed: %q, %s", s, unpacked, err)
+		}
+	})
+}
+
+func FuzzUnpack(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x80})
+	f.Add(PackInt64(math.MinInt64))
+	f.Add(PackString("test"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// must not panic on arbitrary input
+		_, _, _ = Unpack64(data)
+		_, _, _ = UnpackInt64(data)
+		_, _, _ = UnpackInt32(data)
+		_, _, _ = UnpackFixed64(data)
+		_, _, _ = UnpackFloat32(data)
+		_, _, _ = UnpackString(data)
+		_, _, _ = GetNextBlock(data)
+	})
+}
@@ -7,10 +7,7 @@
 	"sync"
 
 	"github.com/safing/portbase/database"
-	"github.com/safing/portbase/database/iterator"
-	"github.com/safing/portbase/database/query"
 	"github.com/safing/portbase/database/record"
-	"github.com/safing/portbase/database/storage"
 	"github.com/safing/portbase/log"
 )
 
@@ -18,8 +15,8 @@
 	nots     = make(map[string]*Notification)
 	notsLock sync.RWMutex
 
-	dbController *database.Controller
-	dbInterface  *database.Interface
+	notsDB      *database.InjectedDatabase
+	dbInterface *database.Interface
 
 	persistentBasePath string
 )
@@ -38,33 +35,13 @@ func SetPersistenceBasePath(dbBasePath string) {
 	}
 }
 
-// StorageInterface provices a storage.Interface to the configuration manager.
-type StorageInterface struct {
-	storage.InjectBase
+func registerAsDatabase() (err error) {
+	notsDB, err = database.NewInjectedDatabase("notifications", "Notifications", getNotificationRecord, setNotificationRecord, listNotificationRecords)
+	return err
 }
 
-func registerAsDatabase() error {
-	_, err := database.Register(&database.Database{
-		Name:        "notifications",
-		Description: "Notifications",
-		StorageType: "injected",
-		PrimaryAPI:  "",
-	})
-	if err != nil {
-		return err
-	}
-
-	controller, err := database.InjectDatabase("notifications", &StorageInterface{})
-	if err != nil {
-		return err
-	}
-
-	dbController = controller
-	return nil
-}
-
-// Get returns a database record.
-func (s *StorageInterface) Get(key string) (record.Record, error) {
+// getNotificationRecord returns a notification.
+func getNotificationRecord(key string) (record.Record, error) {
 	notsLock.RLock()
 	defer notsLock.RUnlock()
 
@@ -72,7 +49,7 @@ func (s *StorageInterface) Get(key string) (record.Record, error) {
 	if strings.HasPrefix(key, "all/") {
 		key = strings.TrimPrefix(key, "all/")
 	} else {
-		return nil, storage.ErrNotFound
+		return nil, database.ErrNotFound
 	}
 
 	// get notification
@@ -80,38 +57,23 @@ func (s *StorageInterface) Get(key string) (record.Record, error) {
 	if ok {
 		return not, nil
 	}
-	return nil, storage.ErrNotFound
+	return nil, database.ErrNotFound
 }
 
-// Query returns a an iterator for the supplied query.
-func (s *StorageInterface) Query(q *query.Query, local, internal bool) (*iterator.Iterator, error) {
-	it := iterator.New()
-	go s.processQuery(q, it)
-	// TODO: check local and internal
-
-	return it, nil
-}
-
-func (s *StorageInterface) processQuery(q *query.Query, it *iterator.Iterator) {
+// listNotificationRecords returns all notifications.
+func listNotificationRecords(prefix string) ([]record.Record, error) {
 	notsLock.RLock()
 	defer notsLock.RUnlock()
 
-	// send all notifications
+	records := make([]record.Record, 0, len(nots))
 	for _, n := range nots {
-		if n.Meta().IsDeleted() {
-			continue
-		}
-
-		if q.MatchesKey(n.DatabaseKey()) && q.MatchesRecord(n) {
-			it.Next <- n
-		}
+		records = append(records, n)
 	}
-
-	it.Finish(nil)
+	return records, nil
 }
 
-// Put stores a record in the database.
-func (s *StorageInterface) Put(r record.Record) error {
+// setNotificationRecord applies a notification written to the database.
+func setNotificationRecord(r record.Record) error {
 	// record is already locked!
 	key := r.DatabaseKey()
 	n, err := EnsureNotification(r)
@@ -179,31 +141,6 @@ func UpdateNotification(n *Notification, key string) {
 	}
 }
 
-// Delete deletes a record from the database.
-func (s *StorageInterface) Delete(key string) error {
-	// transform key
-	if strings.HasPrefix(key, "all/") {
-		key = strings.TrimPrefix(key, "all/")
-	} else {
-		return storage.ErrNotFound
-	}
-
-	// get notification
-	notsLock.Lock()
-	n, ok := nots[key]
-	notsLock.Unlock()
-	if !ok {
-		return storage.ErrNotFound
-	}
-	// delete
-	return n.Delete()
-}
-
-// ReadOnly returns whether the database is read only.
-func (s *StorageInterface) ReadOnly() bool {
-	return false
-}
-
 // EnsureNotification ensures that the given record is a Notification and returns it.
 func EnsureNotification(r record.Record) (*Notification, error) {
 	// unwrap
@@ -224,3 +161,32 @@ func EnsureNotification(r record.Record) (*Notification, error) {
 	}
 	return new, nil
 }
+
+// notifyDatabaseRecovery informs the user that a damaged database was recovered.
+func notifyDatabaseRecovery(report *database.RecoveryReport) {
+	var msg string
+	if report.RestoredFrom != "" {
+		msg = fmt.Sprintf("The database %s was damaged and has been restored from the backup %s. The damaged data was moved to %s.", report.Database, report.RestoredFrom, report.MovedTo)
+	} else {
+		msg = fmt.Sprintf("The database %s was damaged and has been reset. The damaged data was moved to %s.", report.Database, report.MovedTo)
+	}
+
+	n := &Notification{
+		ID:      fmt.Sprintf("database:recovered-%s", report.Database),
+		Message: msg,
+	}
+	n.MakeAck()
+	n.Type = Warning
+	n.Save()
+}
+
+// notifyQuotaExceeded warns the user that a database exceeded its soft quota.
+func notifyQuotaExceeded(report *database.QuotaReport) {
+	n := &Notification{
+		ID:      fmt.Sprintf("database:quota-exceeded-%s", report.Database),
+		Message: fmt.Sprintf("The database %s has grown unusually large: it holds %d records with %d bytes.", report.Database, report.Records, report.Bytes),
+	}
+	n.MakeAck()
+	n.Type = Warning
+	n.Save()
+}
@@ -3,6 +3,7 @@
 import (
 	"time"
 
+	"github.com/safing/portbase/database"
 	"github.com/safing/portbase/modules"
 )
 
@@ -19,6 +20,8 @@ func start() error {
 	if err != nil {
 		return err
 	}
+	database.OnRecovery(notifyDatabaseRecovery)
+	database.OnSoftQuotaExceeded(notifyQuotaExceeded)
 
 	go module.StartServiceWorker("cleaner", 1*time.Second, cleaner)
 	return nil
@@ -93,7 +93,7 @@ func (n *Notification) Save() *Notification {
 	nots[n.ID] = n
 
 	// push update
-	dbController.PushUpdate(n)
+	notsDB.PushUpdate(n)
 
 	// persist
 	if n.Persistent && persistentBasePath != "" {
@@ -196,7 +196,7 @@ func (n *Notification) Delete() error {
 	}
 
 	// push update
-	dbController.PushUpdate(n)
+	notsDB.PushUpdate(n)
 
 	// delete from persistent storage
 	if n.Persistent && persistentBasePath != "" {